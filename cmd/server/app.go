@@ -13,5 +13,5 @@ type Application struct {
 
 // handlers initializers
 func (app *Application) simHandler() handlers.SimHandler {
-	return handlers.SimHandler{}
+	return handlers.SimHandler{Config: app.Config}
 }
@@ -1,13 +1,29 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"log"
+	"net"
+	"net/http"
+	"os/signal"
 	"sim-server/config"
 	"sim-server/database"
+	"sim-server/internal/mockbackend"
+	"sim-server/internal/simulation/actors"
+	"sim-server/internal/simulation/checkpoint"
+	"sim-server/internal/simulation/cluster"
+	"sim-server/internal/simulation/control"
+	"sim-server/internal/simulation/recorder"
+	"syscall"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
+// serverShutdownTimeout is how long the HTTP servers get to answer the requests in flight
+const serverShutdownTimeout = 10 * time.Second
+
 func main() {
 	cfg, err := config.LoadConfig()
 	if err != nil {
@@ -22,6 +38,53 @@ func main() {
 		Redis:  redisDb,
 	}
 
+	// Record the websocket traffic of every actor
+	if cfg.RecordDir != "" {
+		if err := recorder.Enable(cfg.RecordDir, int64(cfg.RecordMaxFileSizeMb)<<20, cfg.RecordMaxFiles); err != nil {
+			log.Fatalf("Error enabling recording: %v", err)
+		}
+	}
+
+	// Actors run in process, per actor gRPC listeners are only needed by outside tools
+	actors.EnableListeners(cfg.ActorListeners)
+	actors.StartHeartbeat()
+
+	// Share scenarios with the other nodes of the registry, reachable on the advertised host
+	if cfg.AdvertiseHost != "" {
+		actors.SetAdvertiseHost(cfg.AdvertiseHost)
+		if err := cluster.Join(node(cfg)); err != nil {
+			log.Fatalf("Error joining the cluster: %v", err)
+		}
+	}
+
+	// Serve every actor over gRPC on one well-known address
+	var controlServer *control.Server
+	if cfg.ControlAddress != "" {
+		controlServer = control.NewServer()
+		go func() {
+			if err := controlServer.ListenAndServe(cfg.ControlAddress); err != nil {
+				log.Printf("Error starting control server: %v", err)
+			}
+		}()
+	}
+
+	// Start the in-process fake backend, scenarios reach it through a target profile
+	var backend *mockbackend.Server
+	if cfg.MockBackendAddress != "" {
+		backend = mockbackend.NewServer()
+		go func() {
+			if err := backend.ListenAndServe(cfg.MockBackendAddress); err != nil {
+				log.Printf("Error starting mock backend: %v", err)
+			}
+		}()
+	}
+
+	// Save every actor to the registry, and pick up the scenarios interrupted by the last restart
+	if cfg.RecoverScenarios {
+		checkpoint.Start(cfg.NodeId)
+		app.simHandler().RecoverScenarios()
+	}
+
 	// Create a new Gin router
 	router := gin.Default()
 
@@ -31,9 +94,57 @@ func main() {
 	app.registerRoutes(router)
 
 	// Start the server
-	if err := router.Run(cfg.ServerAddress); err != nil {
-		log.Fatalf("Error starting server: %v", err)
+	server := &http.Server{Addr: cfg.ServerAddress, Handler: router}
+	go func() {
+		if err := server.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("Error starting server: %v", err)
+		}
+	}()
+
+	// Shut down on SIGINT or SIGTERM, a second signal kills the process
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	<-ctx.Done()
+	stop()
+	shutdown(app, server, controlServer, backend)
+}
+
+// shutdown drains the actors while the HTTP API still reports on them, then stops the servers. The
+// mock backend goes last, the actors talk to it until they are stopped.
+func shutdown(app *Application, server *http.Server, controlServer *control.Server, backend *mockbackend.Server) {
+	timeout := time.Duration(app.Config.ShutdownTimeoutSeconds) * time.Second
+	log.Printf("Shutting down, trips in progress get %s to finish", timeout)
+	drainCtx, cancel := context.WithTimeout(context.Background(), timeout)
+	app.simHandler().Shutdown(drainCtx)
+	cancel()
+
+	if controlServer != nil {
+		controlServer.Stop()
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), serverShutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		log.Printf("Error shutting down server: %v", err)
+	}
+	if backend != nil {
+		if err := backend.Shutdown(ctx); err != nil {
+			log.Printf("Error shutting down mock backend: %v", err)
+		}
+	}
+	log.Print("Shut down")
+}
+
+// node describes this process to the cluster, it is named after its address unless NODE_ID is set
+func node(cfg config.Config) cluster.Node {
+	_, port, err := net.SplitHostPort(cfg.ServerAddress)
+	if err != nil || port == "" {
+		port = "8080"
+	}
+	address := net.JoinHostPort(cfg.AdvertiseHost, port)
+	id := cfg.NodeId
+	if id == "" {
+		id = address
 	}
+	return cluster.Node{Id: id, Address: "http://" + address, Capacity: cfg.NodeCapacity}
 }
 
 func corsMiddleware() gin.HandlerFunc {
@@ -10,6 +10,24 @@ func (app *Application) registerRoutes(router *gin.Engine) {
 	simulation := router.Group("/simulation")
 	{
 		simulation.POST("/scenario", simHandler.SimulateScenario)
+		simulation.GET("/scenarios/:id", simHandler.ScenarioStatus)
+		simulation.POST("/scenarios/:id/share", simHandler.ScenarioShare)
+		simulation.POST("/scenarios/:id/pause", simHandler.PauseScenario)
+		simulation.POST("/scenarios/:id/resume", simHandler.ResumeScenario)
+		simulation.POST("/scenarios/:id/stop", simHandler.StopScenario)
+		simulation.GET("/nodes", simHandler.ListNodes)
+		simulation.GET("/acks", simHandler.AckStats)
+		simulation.GET("/logins", simHandler.LoginStats)
+		simulation.GET("/transcripts/actors/:id", simHandler.ActorTranscript)
+		simulation.GET("/transcripts/trips/:id", simHandler.TripTranscript)
+		simulation.POST("/replay", simHandler.Replay)
+		simulation.GET("/replays/:id", simHandler.ReplayStatus)
+		simulation.POST("/capacity", simHandler.CapacityStudy)
+		simulation.GET("/actors", simHandler.ListActors)
+		simulation.POST("/actors/:id/call/:action", simHandler.CallActor)
+		simulation.POST("/actors/:id/cast/:action", simHandler.CastActor)
+		simulation.POST("/drivers/:id/:action", simHandler.DriverAction)
+		simulation.POST("/customers/:id/rate", simHandler.RateDriver)
 	}
 
 }
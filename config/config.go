@@ -1,6 +1,7 @@
 package config
 
 import (
+	"errors"
 	validation "github.com/go-ozzo/ozzo-validation"
 	"github.com/go-ozzo/ozzo-validation/is"
 	"github.com/spf13/viper"
@@ -9,37 +10,58 @@ import (
 )
 
 type Config struct {
-	DBHost                     string `mapstructure:"DB_HOST"`
-	DBPort                     string `mapstructure:"DB_PORT"`
-	DBUser                     string `mapstructure:"DB_USER"`
-	DBPassword                 string `mapstructure:"DB_PASSWORD"`
-	DBName                     string `mapstructure:"DB_NAME"`
-	DBSslMode                  string `mapstructure:"DB_SSL_MODE"`
-	ServerAddress              string `mapstructure:"SERVER_ADDRESS"`
-	BackdoorOtp                string `mapstructure:"BACKDOOR_OTP"`
-	GoogleMapsKey              string `mapstructure:"GOOGLE_MAPS_KEY"`
-	ServerPort                 string `mapstructure:"SERVER_PORT"`
-	ServerHost                 string `mapstructure:"SERVER_HOST"`
-	UseRedis                   bool   `mapstructure:"USE_REDIS"`
-	RedisUri                   string `mapstructure:"REDIS_URI"`
-	JWTSecretKey               string `mapstructure:"JWT_SECRET"`
-	JWTAccessExpirationMinutes int    `mapstructure:"JWT_ACCESS_EXPIRATION_MINUTES"`
-	JWTRefreshExpirationDays   int    `mapstructure:"JWT_REFRESH_EXPIRATION_DAYS"`
-	Mode                       string `mapstructure:"GIN_MODE"`
-	AwsAccessKeyId             string `mapstructure:"AWS_ACCESS_KEY_ID"`
-	AwsSecretAccessKey         string `mapstructure:"AWS_SECRET_ACCESS_KEY"`
-	SqsRegion                  string `mapstructure:"SQS_REGION"`
-	SqsLocationQueue           string `mapstructure:"SQS_LOCATION_QUEUE"`
-	H3MinResolution            int    `mapstructure:"MINIMUM_RESOLUTION"`
-	H3MaxResolution            int    `mapstructure:"MAXIMUM_RESOLUTION"`
-	H3DefaultResolution        int    `mapstructure:"DEFAULT_RESOLUTION"`
-	AwsBucket                  string `mapstructure:"AWS_BUCKET"`
-	AwsBucketRegion            string `mapstructure:"AWS_BUCKET_REGION"`
+	DBHost                     string            `mapstructure:"DB_HOST"`
+	DBPort                     string            `mapstructure:"DB_PORT"`
+	DBUser                     string            `mapstructure:"DB_USER"`
+	DBPassword                 string            `mapstructure:"DB_PASSWORD"`
+	DBName                     string            `mapstructure:"DB_NAME"`
+	DBSslMode                  string            `mapstructure:"DB_SSL_MODE"`
+	ServerAddress              string            `mapstructure:"SERVER_ADDRESS"`
+	BackdoorOtp                string            `mapstructure:"BACKDOOR_OTP"`
+	GoogleMapsKey              string            `mapstructure:"GOOGLE_MAPS_KEY"`
+	ServerPort                 string            `mapstructure:"SERVER_PORT"`
+	ServerHost                 string            `mapstructure:"SERVER_HOST"`
+	UseRedis                   bool              `mapstructure:"USE_REDIS"`
+	RedisUri                   string            `mapstructure:"REDIS_URI"`
+	JWTSecretKey               string            `mapstructure:"JWT_SECRET"`
+	JWTAccessExpirationMinutes int               `mapstructure:"JWT_ACCESS_EXPIRATION_MINUTES"`
+	JWTRefreshExpirationDays   int               `mapstructure:"JWT_REFRESH_EXPIRATION_DAYS"`
+	Mode                       string            `mapstructure:"GIN_MODE"`
+	AwsAccessKeyId             string            `mapstructure:"AWS_ACCESS_KEY_ID"`
+	AwsSecretAccessKey         string            `mapstructure:"AWS_SECRET_ACCESS_KEY"`
+	SqsRegion                  string            `mapstructure:"SQS_REGION"`
+	SqsLocationQueue           string            `mapstructure:"SQS_LOCATION_QUEUE"`
+	H3MinResolution            int               `mapstructure:"MINIMUM_RESOLUTION"`
+	H3MaxResolution            int               `mapstructure:"MAXIMUM_RESOLUTION"`
+	H3DefaultResolution        int               `mapstructure:"DEFAULT_RESOLUTION"`
+	AwsBucket                  string            `mapstructure:"AWS_BUCKET"`
+	AwsBucketRegion            string            `mapstructure:"AWS_BUCKET_REGION"`
+	TargetsFile                string            `mapstructure:"TARGETS_FILE"`
+	DefaultTarget              string            `mapstructure:"DEFAULT_TARGET"`
+	MockBackendAddress         string            `mapstructure:"MOCK_BACKEND_ADDRESS"`
+	RecordDir                  string            `mapstructure:"RECORD_DIR"`
+	RecordMaxFileSizeMb        int               `mapstructure:"RECORD_MAX_FILE_SIZE_MB"`
+	RecordMaxFiles             int               `mapstructure:"RECORD_MAX_FILES"`
+	ActorListeners             bool              `mapstructure:"ACTOR_LISTENERS"`
+	ControlAddress             string            `mapstructure:"CONTROL_ADDRESS"`
+	AdvertiseHost              string            `mapstructure:"ADVERTISE_HOST"`
+	NodeId                     string            `mapstructure:"NODE_ID"`
+	NodeCapacity               int               `mapstructure:"NODE_CAPACITY"`
+	RecoverScenarios           bool              `mapstructure:"RECOVER_SCENARIOS"`
+	ShutdownTimeoutSeconds     int               `mapstructure:"SHUTDOWN_TIMEOUT_SECONDS"`
+	Targets                    map[string]Target `mapstructure:"-"`
 }
 
 func LoadConfig() (Config, error) {
 	v := viper.New()
 
+	v.SetDefault("TARGETS_FILE", "targets.yaml")
+	v.SetDefault("DEFAULT_TARGET", defaultTargetName)
+	v.SetDefault("RECORD_MAX_FILE_SIZE_MB", 10)
+	v.SetDefault("RECORD_MAX_FILES", 5)
+	v.SetDefault("NODE_CAPACITY", 1000)
+	v.SetDefault("SHUTDOWN_TIMEOUT_SECONDS", 60)
+
 	env := os.Getenv("APP_ENV")
 	envsWithEnvVars := []string{"preview", "staging", "prod"}
 	if slices.Contains(envsWithEnvVars, env) {
@@ -70,6 +92,19 @@ func LoadConfig() (Config, error) {
 		v.BindEnv("DEFAULT_RESOLUTION")
 		v.BindEnv("AWS_BUCKET")
 		v.BindEnv("AWS_BUCKET_REGION")
+		v.BindEnv("TARGETS_FILE")
+		v.BindEnv("DEFAULT_TARGET")
+		v.BindEnv("MOCK_BACKEND_ADDRESS")
+		v.BindEnv("RECORD_DIR")
+		v.BindEnv("RECORD_MAX_FILE_SIZE_MB")
+		v.BindEnv("RECORD_MAX_FILES")
+		v.BindEnv("ACTOR_LISTENERS")
+		v.BindEnv("CONTROL_ADDRESS")
+		v.BindEnv("ADVERTISE_HOST")
+		v.BindEnv("NODE_ID")
+		v.BindEnv("NODE_CAPACITY")
+		v.BindEnv("RECOVER_SCENARIOS")
+		v.BindEnv("SHUTDOWN_TIMEOUT_SECONDS")
 		v.AutomaticEnv()
 	} else {
 		v.SetDefault("SERVER_PORT", "8080")
@@ -90,6 +125,18 @@ func LoadConfig() (Config, error) {
 		return Config{}, err
 	}
 
+	targets, err := loadTargets(cfg.TargetsFile)
+	if err != nil {
+		return Config{}, err
+	}
+	for name, target := range targets {
+		if target.Otp == "" {
+			target.Otp = cfg.BackdoorOtp
+			targets[name] = target
+		}
+	}
+	cfg.Targets = targets
+
 	if err := cfg.Validate(); err != nil {
 		panic(err)
 	}
@@ -110,5 +157,26 @@ func (config *Config) Validate() error {
 		//validation.Field(&config.JWTRefreshExpirationDays, validation.Required),
 
 		validation.Field(&config.Mode, validation.In("debug", "release")),
+		validation.Field(&config.DefaultTarget, validation.Required, validation.By(config.hasTarget)),
+		validation.Field(&config.RecordMaxFileSizeMb, validation.Min(1)),
+		validation.Field(&config.RecordMaxFiles, validation.Min(0)),
+		validation.Field(&config.NodeId, config.nodeIdRules()...),
+		validation.Field(&config.ShutdownTimeoutSeconds, validation.Min(1)),
+		validation.Field(&config.NodeCapacity, validation.Min(1)),
 	)
 }
+
+// nodeIdRules require a node id to recover scenarios, the checkpoints of the node are found by it after a restart
+func (config *Config) nodeIdRules() []validation.Rule {
+	if !config.RecoverScenarios {
+		return nil
+	}
+	return []validation.Rule{validation.Required.Error("is required to recover scenarios")}
+}
+
+func (config *Config) hasTarget(value interface{}) error {
+	if _, ok := config.Targets[value.(string)]; !ok {
+		return errors.New("no target profile with this name")
+	}
+	return nil
+}
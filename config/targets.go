@@ -0,0 +1,134 @@
+package config
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+	"github.com/go-ozzo/ozzo-validation/is"
+	"github.com/spf13/viper"
+)
+
+const defaultTargetName = "rh-core"
+
+// How actors log in to a target
+const (
+	AuthModeSimulate = "simulate" // admin simulate endpoints
+	AuthModeOtp      = "otp"      // consumer login, an OTP verified with the backdoor code
+)
+
+// Target is a backend the simulated actors can be pointed at.
+type Target struct {
+	Name         string            `mapstructure:"-" json:"name"`
+	BaseURL      string            `mapstructure:"base_url" json:"base_url"`
+	WebsocketURL string            `mapstructure:"websocket_url" json:"websocket_url"`
+	Headers      map[string]string `mapstructure:"headers" json:"headers"`
+	TLS          TLSPolicy         `mapstructure:"tls" json:"tls"`
+	AuthMode     string            `mapstructure:"auth_mode" json:"auth_mode"`
+	Otp          string            `mapstructure:"otp" json:"-"`
+	// DedupMessageIds is set when the backend acknowledges a websocket message id it already
+	// handled without handling it again, only then are unacknowledged commands resent
+	DedupMessageIds bool `mapstructure:"dedup_message_ids" json:"dedup_message_ids"`
+	// WallClock is set when the backend times offers and scheduled trips on the wall clock whatever
+	// the scenario speed, such as the mock backend, scenarios against it run at speed 1
+	WallClock bool `mapstructure:"wall_clock" json:"wall_clock"`
+}
+
+// TLSPolicy controls how the certificates of a target are verified.
+type TLSPolicy struct {
+	InsecureSkipVerify bool   `mapstructure:"insecure_skip_verify" json:"insecure_skip_verify"`
+	CAFile             string `mapstructure:"ca_file" json:"ca_file,omitempty"`
+	ServerName         string `mapstructure:"server_name" json:"server_name,omitempty"`
+}
+
+// defaultTarget is used when no targets file exists
+var defaultTarget = Target{
+	Name:         defaultTargetName,
+	BaseURL:      "https://rh-core.advantium.in",
+	WebsocketURL: "wss://rh-core.advantium.in",
+	Headers:      map[string]string{"MRSOOL-CLIENT": "Simulation"},
+	AuthMode:     AuthModeSimulate,
+}
+
+func (target Target) Validate() error {
+	return validation.ValidateStruct(&target,
+		validation.Field(&target.BaseURL, validation.Required, is.URL),
+		validation.Field(&target.WebsocketURL, validation.Required, is.URL),
+		validation.Field(&target.AuthMode, validation.Required, validation.In(AuthModeSimulate, AuthModeOtp)),
+	)
+}
+
+// TLSConfig builds the client TLS configuration of the target, nil means the defaults.
+func (target Target) TLSConfig() (*tls.Config, error) {
+	policy := target.TLS
+	if !policy.InsecureSkipVerify && policy.CAFile == "" && policy.ServerName == "" {
+		return nil, nil
+	}
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: policy.InsecureSkipVerify,
+		ServerName:         policy.ServerName,
+	}
+	if policy.CAFile != "" {
+		pem, err := os.ReadFile(policy.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", policy.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	return tlsConfig, nil
+}
+
+// Target returns the named target profile, the default one when name is empty.
+func (config *Config) Target(name string) (Target, error) {
+	if name == "" {
+		name = config.DefaultTarget
+	}
+	target, ok := config.Targets[name]
+	if !ok {
+		return Target{}, fmt.Errorf("unknown target %q", name)
+	}
+	return target, nil
+}
+
+// loadTargets reads the target profiles from the targets file, keyed by profile name
+func loadTargets(path string) (map[string]Target, error) {
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		return map[string]Target{defaultTargetName: defaultTarget}, nil
+	}
+
+	v := viper.New()
+	v.SetConfigFile(path)
+	if err := v.ReadInConfig(); err != nil {
+		return nil, err
+	}
+
+	var file struct {
+		Targets map[string]Target `mapstructure:"targets"`
+	}
+	if err := v.Unmarshal(&file); err != nil {
+		return nil, err
+	}
+
+	targets := make(map[string]Target, len(file.Targets))
+	for name, target := range file.Targets {
+		target.Name = name
+		if target.Headers == nil {
+			target.Headers = defaultTarget.Headers
+		}
+		if target.AuthMode == "" {
+			target.AuthMode = AuthModeSimulate
+		}
+		if err := target.Validate(); err != nil {
+			return nil, fmt.Errorf("target %s: %w", name, err)
+		}
+		targets[name] = target
+	}
+	return targets, nil
+}
@@ -23,6 +23,12 @@ const (
 	SimulatedDriver_InitConnection_FullMethodName = "/genserver.SimulatedDriver/InitConnection"
 	SimulatedDriver_SetLocation_FullMethodName    = "/genserver.SimulatedDriver/SetLocation"
 	SimulatedDriver_IsAlive_FullMethodName        = "/genserver.SimulatedDriver/IsAlive"
+	SimulatedDriver_AcceptTrip_FullMethodName     = "/genserver.SimulatedDriver/AcceptTrip"
+	SimulatedDriver_RejectTrip_FullMethodName     = "/genserver.SimulatedDriver/RejectTrip"
+	SimulatedDriver_DriverArrival_FullMethodName  = "/genserver.SimulatedDriver/DriverArrival"
+	SimulatedDriver_StartTrip_FullMethodName      = "/genserver.SimulatedDriver/StartTrip"
+	SimulatedDriver_CompleteTrip_FullMethodName   = "/genserver.SimulatedDriver/CompleteTrip"
+	SimulatedDriver_RateCustomer_FullMethodName   = "/genserver.SimulatedDriver/RateCustomer"
 )
 
 // SimulatedDriverClient is the client API for SimulatedDriver service.
@@ -33,6 +39,12 @@ type SimulatedDriverClient interface {
 	InitConnection(ctx context.Context, in *InitConnectionRequest, opts ...grpc.CallOption) (*InitConnectionResponse, error)
 	SetLocation(ctx context.Context, in *SetLocationRequest, opts ...grpc.CallOption) (*SetLocationResponse, error)
 	IsAlive(ctx context.Context, in *IsAliveRequest, opts ...grpc.CallOption) (*IsAliveResponse, error)
+	AcceptTrip(ctx context.Context, in *DriverAcceptTripRequest, opts ...grpc.CallOption) (*DriverAcceptTripResponse, error)
+	RejectTrip(ctx context.Context, in *DriverRejectTripRequest, opts ...grpc.CallOption) (*DriverRejectTripResponse, error)
+	DriverArrival(ctx context.Context, in *DriverArrivalRequest, opts ...grpc.CallOption) (*DriverArrivalResponse, error)
+	StartTrip(ctx context.Context, in *DriverStartTripRequest, opts ...grpc.CallOption) (*DriverStartTripResponse, error)
+	CompleteTrip(ctx context.Context, in *DriverCompleteTripRequest, opts ...grpc.CallOption) (*DriverCompleteTripResponse, error)
+	RateCustomer(ctx context.Context, in *RatingRequest, opts ...grpc.CallOption) (*RatingResponse, error)
 }
 
 type simulatedDriverClient struct {
@@ -83,6 +95,66 @@ func (c *simulatedDriverClient) IsAlive(ctx context.Context, in *IsAliveRequest,
 	return out, nil
 }
 
+func (c *simulatedDriverClient) AcceptTrip(ctx context.Context, in *DriverAcceptTripRequest, opts ...grpc.CallOption) (*DriverAcceptTripResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DriverAcceptTripResponse)
+	err := c.cc.Invoke(ctx, SimulatedDriver_AcceptTrip_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *simulatedDriverClient) RejectTrip(ctx context.Context, in *DriverRejectTripRequest, opts ...grpc.CallOption) (*DriverRejectTripResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DriverRejectTripResponse)
+	err := c.cc.Invoke(ctx, SimulatedDriver_RejectTrip_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *simulatedDriverClient) DriverArrival(ctx context.Context, in *DriverArrivalRequest, opts ...grpc.CallOption) (*DriverArrivalResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DriverArrivalResponse)
+	err := c.cc.Invoke(ctx, SimulatedDriver_DriverArrival_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *simulatedDriverClient) StartTrip(ctx context.Context, in *DriverStartTripRequest, opts ...grpc.CallOption) (*DriverStartTripResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DriverStartTripResponse)
+	err := c.cc.Invoke(ctx, SimulatedDriver_StartTrip_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *simulatedDriverClient) CompleteTrip(ctx context.Context, in *DriverCompleteTripRequest, opts ...grpc.CallOption) (*DriverCompleteTripResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DriverCompleteTripResponse)
+	err := c.cc.Invoke(ctx, SimulatedDriver_CompleteTrip_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *simulatedDriverClient) RateCustomer(ctx context.Context, in *RatingRequest, opts ...grpc.CallOption) (*RatingResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RatingResponse)
+	err := c.cc.Invoke(ctx, SimulatedDriver_RateCustomer_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // SimulatedDriverServer is the server API for SimulatedDriver service.
 // All implementations must embed UnimplementedSimulatedDriverServer
 // for forward compatibility.
@@ -91,6 +163,12 @@ type SimulatedDriverServer interface {
 	InitConnection(context.Context, *InitConnectionRequest) (*InitConnectionResponse, error)
 	SetLocation(context.Context, *SetLocationRequest) (*SetLocationResponse, error)
 	IsAlive(context.Context, *IsAliveRequest) (*IsAliveResponse, error)
+	AcceptTrip(context.Context, *DriverAcceptTripRequest) (*DriverAcceptTripResponse, error)
+	RejectTrip(context.Context, *DriverRejectTripRequest) (*DriverRejectTripResponse, error)
+	DriverArrival(context.Context, *DriverArrivalRequest) (*DriverArrivalResponse, error)
+	StartTrip(context.Context, *DriverStartTripRequest) (*DriverStartTripResponse, error)
+	CompleteTrip(context.Context, *DriverCompleteTripRequest) (*DriverCompleteTripResponse, error)
+	RateCustomer(context.Context, *RatingRequest) (*RatingResponse, error)
 	mustEmbedUnimplementedSimulatedDriverServer()
 }
 
@@ -113,6 +191,24 @@ func (UnimplementedSimulatedDriverServer) SetLocation(context.Context, *SetLocat
 func (UnimplementedSimulatedDriverServer) IsAlive(context.Context, *IsAliveRequest) (*IsAliveResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method IsAlive not implemented")
 }
+func (UnimplementedSimulatedDriverServer) AcceptTrip(context.Context, *DriverAcceptTripRequest) (*DriverAcceptTripResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AcceptTrip not implemented")
+}
+func (UnimplementedSimulatedDriverServer) RejectTrip(context.Context, *DriverRejectTripRequest) (*DriverRejectTripResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RejectTrip not implemented")
+}
+func (UnimplementedSimulatedDriverServer) DriverArrival(context.Context, *DriverArrivalRequest) (*DriverArrivalResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DriverArrival not implemented")
+}
+func (UnimplementedSimulatedDriverServer) StartTrip(context.Context, *DriverStartTripRequest) (*DriverStartTripResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method StartTrip not implemented")
+}
+func (UnimplementedSimulatedDriverServer) CompleteTrip(context.Context, *DriverCompleteTripRequest) (*DriverCompleteTripResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CompleteTrip not implemented")
+}
+func (UnimplementedSimulatedDriverServer) RateCustomer(context.Context, *RatingRequest) (*RatingResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RateCustomer not implemented")
+}
 func (UnimplementedSimulatedDriverServer) mustEmbedUnimplementedSimulatedDriverServer() {}
 func (UnimplementedSimulatedDriverServer) testEmbeddedByValue()                         {}
 
@@ -206,6 +302,114 @@ func _SimulatedDriver_IsAlive_Handler(srv interface{}, ctx context.Context, dec
 	return interceptor(ctx, in, info, handler)
 }
 
+func _SimulatedDriver_AcceptTrip_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DriverAcceptTripRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SimulatedDriverServer).AcceptTrip(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SimulatedDriver_AcceptTrip_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SimulatedDriverServer).AcceptTrip(ctx, req.(*DriverAcceptTripRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SimulatedDriver_RejectTrip_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DriverRejectTripRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SimulatedDriverServer).RejectTrip(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SimulatedDriver_RejectTrip_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SimulatedDriverServer).RejectTrip(ctx, req.(*DriverRejectTripRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SimulatedDriver_DriverArrival_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DriverArrivalRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SimulatedDriverServer).DriverArrival(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SimulatedDriver_DriverArrival_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SimulatedDriverServer).DriverArrival(ctx, req.(*DriverArrivalRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SimulatedDriver_StartTrip_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DriverStartTripRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SimulatedDriverServer).StartTrip(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SimulatedDriver_StartTrip_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SimulatedDriverServer).StartTrip(ctx, req.(*DriverStartTripRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SimulatedDriver_CompleteTrip_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DriverCompleteTripRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SimulatedDriverServer).CompleteTrip(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SimulatedDriver_CompleteTrip_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SimulatedDriverServer).CompleteTrip(ctx, req.(*DriverCompleteTripRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SimulatedDriver_RateCustomer_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RatingRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SimulatedDriverServer).RateCustomer(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SimulatedDriver_RateCustomer_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SimulatedDriverServer).RateCustomer(ctx, req.(*RatingRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // SimulatedDriver_ServiceDesc is the grpc.ServiceDesc for SimulatedDriver service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -229,6 +433,30 @@ var SimulatedDriver_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "IsAlive",
 			Handler:    _SimulatedDriver_IsAlive_Handler,
 		},
+		{
+			MethodName: "AcceptTrip",
+			Handler:    _SimulatedDriver_AcceptTrip_Handler,
+		},
+		{
+			MethodName: "RejectTrip",
+			Handler:    _SimulatedDriver_RejectTrip_Handler,
+		},
+		{
+			MethodName: "DriverArrival",
+			Handler:    _SimulatedDriver_DriverArrival_Handler,
+		},
+		{
+			MethodName: "StartTrip",
+			Handler:    _SimulatedDriver_StartTrip_Handler,
+		},
+		{
+			MethodName: "CompleteTrip",
+			Handler:    _SimulatedDriver_CompleteTrip_Handler,
+		},
+		{
+			MethodName: "RateCustomer",
+			Handler:    _SimulatedDriver_RateCustomer_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "genserver.proto",
@@ -240,6 +468,7 @@ const (
 	SimulatedCustomer_IsAlive_FullMethodName        = "/genserver.SimulatedCustomer/IsAlive"
 	SimulatedCustomer_TripEstimate_FullMethodName   = "/genserver.SimulatedCustomer/TripEstimate"
 	SimulatedCustomer_ConfirmTrip_FullMethodName    = "/genserver.SimulatedCustomer/ConfirmTrip"
+	SimulatedCustomer_RateDriver_FullMethodName     = "/genserver.SimulatedCustomer/RateDriver"
 )
 
 // SimulatedCustomerClient is the client API for SimulatedCustomer service.
@@ -251,6 +480,7 @@ type SimulatedCustomerClient interface {
 	IsAlive(ctx context.Context, in *IsAliveRequest, opts ...grpc.CallOption) (*IsAliveResponse, error)
 	TripEstimate(ctx context.Context, in *TripEstimateRequest, opts ...grpc.CallOption) (*TripEstimateResponse, error)
 	ConfirmTrip(ctx context.Context, in *ConfirmTripRequest, opts ...grpc.CallOption) (*ConfirmTripResponse, error)
+	RateDriver(ctx context.Context, in *RatingRequest, opts ...grpc.CallOption) (*RatingResponse, error)
 }
 
 type simulatedCustomerClient struct {
@@ -311,6 +541,16 @@ func (c *simulatedCustomerClient) ConfirmTrip(ctx context.Context, in *ConfirmTr
 	return out, nil
 }
 
+func (c *simulatedCustomerClient) RateDriver(ctx context.Context, in *RatingRequest, opts ...grpc.CallOption) (*RatingResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RatingResponse)
+	err := c.cc.Invoke(ctx, SimulatedCustomer_RateDriver_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // SimulatedCustomerServer is the server API for SimulatedCustomer service.
 // All implementations must embed UnimplementedSimulatedCustomerServer
 // for forward compatibility.
@@ -320,6 +560,7 @@ type SimulatedCustomerServer interface {
 	IsAlive(context.Context, *IsAliveRequest) (*IsAliveResponse, error)
 	TripEstimate(context.Context, *TripEstimateRequest) (*TripEstimateResponse, error)
 	ConfirmTrip(context.Context, *ConfirmTripRequest) (*ConfirmTripResponse, error)
+	RateDriver(context.Context, *RatingRequest) (*RatingResponse, error)
 	mustEmbedUnimplementedSimulatedCustomerServer()
 }
 
@@ -345,6 +586,9 @@ func (UnimplementedSimulatedCustomerServer) TripEstimate(context.Context, *TripE
 func (UnimplementedSimulatedCustomerServer) ConfirmTrip(context.Context, *ConfirmTripRequest) (*ConfirmTripResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method ConfirmTrip not implemented")
 }
+func (UnimplementedSimulatedCustomerServer) RateDriver(context.Context, *RatingRequest) (*RatingResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RateDriver not implemented")
+}
 func (UnimplementedSimulatedCustomerServer) mustEmbedUnimplementedSimulatedCustomerServer() {}
 func (UnimplementedSimulatedCustomerServer) testEmbeddedByValue()                           {}
 
@@ -456,6 +700,24 @@ func _SimulatedCustomer_ConfirmTrip_Handler(srv interface{}, ctx context.Context
 	return interceptor(ctx, in, info, handler)
 }
 
+func _SimulatedCustomer_RateDriver_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RatingRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SimulatedCustomerServer).RateDriver(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SimulatedCustomer_RateDriver_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SimulatedCustomerServer).RateDriver(ctx, req.(*RatingRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // SimulatedCustomer_ServiceDesc is the grpc.ServiceDesc for SimulatedCustomer service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -483,6 +745,10 @@ var SimulatedCustomer_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "ConfirmTrip",
 			Handler:    _SimulatedCustomer_ConfirmTrip_Handler,
 		},
+		{
+			MethodName: "RateDriver",
+			Handler:    _SimulatedCustomer_RateDriver_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "genserver.proto",
@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"sort"
+
+	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"sim-server/internal/simulation/actors"
+	"sim-server/internal/simulation/customers"
+	"sim-server/internal/simulation/drivers"
+)
+
+type controlRequest struct {
+	// Defaults to the current trip of the actor
+	TripId string  `json:"trip_id"`
+	Rating float32 `json:"rating"`
+}
+
+// ListActors lists the drivers and customers running in this process, filtered by the kind query parameter
+func (handler SimHandler) ListActors(context *gin.Context) {
+	type actor struct {
+		Id   string `json:"id"`
+		Kind string `json:"kind"`
+	}
+
+	running := actors.All(context.Query("kind"))
+	result := make([]actor, 0, len(running))
+	for _, a := range running {
+		result = append(result, actor{Id: a.Id, Kind: a.Kind})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Id < result[j].Id })
+	context.JSON(http.StatusOK, result)
+}
+
+// DriverAction runs one manual step (accept, reject, arrive, start, complete or rate) on a driver started in manual mode
+func (handler SimHandler) DriverAction(context *gin.Context) {
+	var req controlRequest
+	if err := bindOptionalJSON(context, &req); err != nil {
+		context.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	success, err := drivers.Control(context.Param("id"), drivers.Action(context.Param("action")), req.TripId, req.Rating)
+	writeControlResult(context, success, err)
+}
+
+// RateDriver rates the driver of a trip on behalf of a customer started in manual mode
+func (handler SimHandler) RateDriver(context *gin.Context) {
+	var req controlRequest
+	if err := bindOptionalJSON(context, &req); err != nil {
+		context.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	success, err := customers.RateDriver(context.Param("id"), req.TripId, req.Rating)
+	writeControlResult(context, success, err)
+}
+
+// CallActor runs a synchronous GenServer action, such as state, on a driver or customer and returns its result.
+// The request body is the JSON payload of the action.
+func (handler SimHandler) CallActor(context *gin.Context) {
+	payload, err := io.ReadAll(context.Request.Body)
+	if err != nil {
+		context.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	result, err := actors.HandleCall(context.Request.Context(), context.Param("id"), context.Param("action"), payload)
+	if err != nil {
+		writeActorError(context, err)
+		return
+	}
+	context.Data(http.StatusOK, "application/json", result)
+}
+
+// CastActor queues an asynchronous GenServer action, such as set_acceptance_rate or go_offline, on a driver or
+// customer. The request body is the JSON payload of the action.
+func (handler SimHandler) CastActor(context *gin.Context) {
+	payload, err := io.ReadAll(context.Request.Body)
+	if err != nil {
+		context.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := actors.HandleCast(context.Param("id"), context.Param("action"), payload); err != nil {
+		writeActorError(context, err)
+		return
+	}
+	context.JSON(http.StatusAccepted, gin.H{"queued": true})
+}
+
+func writeActorError(context *gin.Context, err error) {
+	switch {
+	case errors.Is(err, actors.ErrNotRunning):
+		context.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, actors.ErrUnknownAction), errors.Is(err, actors.ErrBadPayload):
+		context.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, actors.ErrMailboxFull):
+		context.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+	case errors.Is(err, actors.ErrStopped):
+		context.JSON(http.StatusGone, gin.H{"error": err.Error()})
+	default:
+		context.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// bindOptionalJSON accepts an empty body, every field of the control requests is optional
+func bindOptionalJSON(context *gin.Context, req interface{}) error {
+	if context.Request.ContentLength == 0 {
+		return nil
+	}
+	return context.ShouldBindJSON(req)
+}
+
+func writeControlResult(context *gin.Context, success bool, err error) {
+	switch {
+	case errors.Is(err, actors.ErrNotRunning):
+		context.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case status.Code(err) == codes.InvalidArgument:
+		context.JSON(http.StatusBadRequest, gin.H{"error": status.Convert(err).Message()})
+	case status.Code(err) == codes.FailedPrecondition:
+		context.JSON(http.StatusConflict, gin.H{"error": status.Convert(err).Message()})
+	case err != nil:
+		context.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	case !success:
+		context.JSON(http.StatusBadGateway, gin.H{"error": "websocket of the actor is not connected"})
+	default:
+		context.JSON(http.StatusOK, gin.H{"success": true})
+	}
+}
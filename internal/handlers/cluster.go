@@ -0,0 +1,192 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"sim-server/internal/simulation/actors"
+	"sim-server/internal/simulation/checkpoint"
+	"sim-server/internal/simulation/clock"
+	"sim-server/internal/simulation/cluster"
+
+	"github.com/gin-gonic/gin"
+)
+
+// stateMixed is the aggregated state of a scenario whose nodes are not all in the same state
+const stateMixed = "mixed"
+
+var errUnknownScenario = errors.New("unknown scenario")
+
+// scenarioShare is the part of a scenario the coordinating node hands to another node
+type scenarioShare struct {
+	scenarioRequest
+	// ClockStart keeps the clocks of the nodes of a scenario in step
+	ClockStart time.Time `json:"clock_start"`
+}
+
+// nodeResult is the outcome of a scenario request on one of the nodes running it
+type nodeResult struct {
+	cluster.Share
+	State string `json:"state,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// onNodes runs fn for the share of every node of a scenario concurrently, local is set for the share
+// of this node. fn returns the state of the scenario on the node.
+func onNodes(shares []cluster.Share, fn func(share cluster.Share, local bool) (string, error)) []nodeResult {
+	self, _ := cluster.Self()
+	results := make([]nodeResult, len(shares))
+	var wg sync.WaitGroup
+	for i, share := range shares {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			state, err := fn(share, share.NodeId == self.Id)
+			results[i] = nodeResult{Share: share, State: state}
+			if err != nil {
+				results[i].Error = err.Error()
+			}
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+// ScenarioShare starts the part of a scenario the coordinating node handed to this one
+func (handler SimHandler) ScenarioShare(context *gin.Context) {
+	if refuseWhenShuttingDown(context) {
+		return
+	}
+	var share scenarioShare
+	if err := context.ShouldBindJSON(&share); err != nil {
+		context.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	target, err := handler.scenarioTarget(share.scenarioRequest)
+	if err != nil {
+		context.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	scenarioId := context.Param("id")
+	startScenario(scenarioId, clock.NewAt(share.Speed, share.ClockStart), target, share.scenarioRequest)
+	context.JSON(http.StatusOK, gin.H{"scenario_id": scenarioId, "state": actors.ChildRunning})
+}
+
+// PauseScenario holds the messages of every actor of a scenario until it is resumed
+func (handler SimHandler) PauseScenario(context *gin.Context) {
+	controlScenario(context, "pause", (*actors.Supervisor).Pause)
+}
+
+func (handler SimHandler) ResumeScenario(context *gin.Context) {
+	controlScenario(context, "resume", (*actors.Supervisor).Resume)
+}
+
+// StopScenario stops every actor of a scenario for good
+func (handler SimHandler) StopScenario(context *gin.Context) {
+	scenarioId := context.Param("id")
+	controlScenario(context, "stop", func(supervisor *actors.Supervisor) error {
+		supervisor.Stop()
+		return checkpoint.DeleteScenario(scenarioId)
+	})
+}
+
+// ListNodes lists the nodes registered in the cluster with their capacity and load
+func (handler SimHandler) ListNodes(context *gin.Context) {
+	nodes, err := cluster.Nodes()
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	context.JSON(http.StatusOK, nodes)
+}
+
+// controlScenario applies an action to the supervisor of a scenario on every node running it,
+// or on this node only when local is set
+func controlScenario(context *gin.Context, action string, apply func(supervisor *actors.Supervisor) error) {
+	scenarioId := context.Param("id")
+	shares, distributed := cluster.Placement(scenarioId)
+	if !distributed || context.Query("local") == "true" {
+		state, err := controlLocalScenario(scenarioId, apply)
+		switch {
+		case errors.Is(err, errUnknownScenario):
+			context.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, actors.ErrSupervisorStopped):
+			context.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		case err != nil:
+			context.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		default:
+			context.JSON(http.StatusOK, gin.H{"scenario_id": scenarioId, "state": state})
+		}
+		return
+	}
+
+	nodes := onNodes(shares, func(share cluster.Share, local bool) (string, error) {
+		if local {
+			return controlLocalScenario(scenarioId, apply)
+		}
+		var response struct {
+			State string `json:"state"`
+		}
+		path := "/simulation/scenarios/" + scenarioId + "/" + action + "?local=true"
+		err := cluster.Do(context, share.Address, http.MethodPost, path, nil, &response)
+		return response.State, err
+	})
+	context.JSON(http.StatusOK, gin.H{"scenario_id": scenarioId, "state": aggregateState(nodes), "nodes": nodes})
+}
+
+func controlLocalScenario(scenarioId string, apply func(supervisor *actors.Supervisor) error) (string, error) {
+	supervisor, ok := actors.LookupSupervisor(scenarioId)
+	if !ok {
+		return "", fmt.Errorf("%w %s", errUnknownScenario, scenarioId)
+	}
+	if err := apply(supervisor); err != nil {
+		return "", err
+	}
+	return supervisor.Status().State, nil
+}
+
+// mergeStatuses adds up the statuses the nodes of a scenario reported
+func mergeStatuses(scenarioId string, statuses []scenarioStatus, nodes []nodeResult) scenarioStatus {
+	merged := scenarioStatus{
+		SupervisorStatus: actors.SupervisorStatus{
+			Id:       scenarioId,
+			State:    aggregateState(nodes),
+			Children: []actors.ChildStatus{},
+			Failures: []actors.Failure{},
+		},
+		Nodes: nodes,
+	}
+	for i, status := range statuses {
+		if i == 0 {
+			merged.Speed, merged.Now = status.Speed, status.Now
+		}
+		if merged.StartedAt.IsZero() || status.StartedAt.Before(merged.StartedAt) {
+			merged.StartedAt = status.StartedAt
+		}
+		merged.Children = append(merged.Children, status.Children...)
+		merged.Failures = append(merged.Failures, status.Failures...)
+	}
+	sort.Slice(merged.Children, func(i, j int) bool { return merged.Children[i].Id < merged.Children[j].Id })
+	sort.Slice(merged.Failures, func(i, j int) bool { return merged.Failures[i].Time.Before(merged.Failures[j].Time) })
+	return merged
+}
+
+// aggregateState is the state the nodes that answered agree on, stateMixed when they do not
+func aggregateState(nodes []nodeResult) string {
+	state := ""
+	for _, node := range nodes {
+		switch {
+		case node.Error != "":
+		case state == "":
+			state = node.State
+		case state != node.State:
+			return stateMixed
+		}
+	}
+	return state
+}
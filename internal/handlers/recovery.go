@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"log"
+
+	"sim-server/config"
+	"sim-server/internal/simulation/actors"
+	"sim-server/internal/simulation/checkpoint"
+	"sim-server/internal/simulation/clock"
+	"sim-server/internal/simulation/customers"
+	"sim-server/internal/simulation/drivers"
+)
+
+// RecoverScenarios starts the actors this node ran before a restart again from their checkpoints,
+// under a supervisor and on the clock of their scenario. Every actor logs in with the token it had,
+// reconnects and syncs with the backend, which still has its shift and trips, and carries on from there.
+func (handler SimHandler) RecoverScenarios() {
+	scenarios, err := checkpoint.Scenarios()
+	if err != nil {
+		log.Printf("Error listing scenarios to recover: %v", err)
+		return
+	}
+	for _, saved := range scenarios {
+		target, err := handler.Config.Target(saved.Target)
+		if err == nil && saved.AuthMode != "" {
+			target.AuthMode = saved.AuthMode
+		}
+		var savedActors []checkpoint.Actor
+		if err == nil {
+			savedActors, err = checkpoint.Actors(saved.Id)
+		}
+		if err != nil {
+			log.Printf("scenario %s: not recovered: %v", saved.Id, err)
+			continue
+		}
+		if len(savedActors) == 0 {
+			log.Printf("scenario %s: no checkpoints left, forgetting it", saved.Id)
+			checkpoint.DeleteScenario(saved.Id)
+			continue
+		}
+
+		clock.Register(saved.Id, clock.NewAt(saved.Speed, saved.ClockStart))
+		scenario := &scenarioSupervisor{
+			Supervisor:  actors.NewSupervisor(saved.Id),
+			restart:     actors.RestartPolicy(saved.Restart),
+			maxRestarts: saved.MaxRestarts,
+		}
+		// refreshed with the recovered actors from now on
+		if err := checkpoint.SaveScenario(saved); err != nil {
+			log.Printf("scenario %s: failed to save checkpoint: %v", saved.Id, err)
+		}
+		for _, actor := range savedActors {
+			go recoverActor(scenario, actor, target)
+		}
+		log.Printf("scenario %s: recovering %d actors", saved.Id, len(savedActors))
+	}
+}
+
+// recoverActor restores an actor under the supervisor of its scenario. A restart after a crash
+// restores it from its latest checkpoint, the sync corrects whatever changed since.
+func recoverActor(scenario *scenarioSupervisor, saved checkpoint.Actor, target config.Target) {
+	scenario.StartChild(scenario.spec(saved.Kind, saved.Id, func() error {
+		if latest, ok := checkpoint.Load(saved.ScenarioId, saved.Id); ok {
+			saved = latest
+		}
+		switch saved.Kind {
+		case "driver":
+			// a running driver is not restored over, nor stopped
+			if err := drivers.Restore(saved, target); err != nil {
+				return err
+			}
+			if err := drivers.CheckAndGoOnline(saved.Id); err != nil {
+				return abandon(saved.Id, err)
+			}
+			if err := drivers.Connect(saved.Id); err != nil {
+				return abandon(saved.Id, err)
+			}
+		case "customer":
+			if err := customers.Restore(saved, target); err != nil {
+				return err
+			}
+			if err := customers.Connect(saved.Id); err != nil {
+				return abandon(saved.Id, err)
+			}
+		}
+		return nil
+	}))
+}
@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"sync/atomic"
+
+	"sim-server/internal/simulation/actors"
+	"sim-server/internal/simulation/checkpoint"
+	"sim-server/internal/simulation/cluster"
+
+	"github.com/gin-gonic/gin"
+)
+
+// shuttingDown is set once the process started to shut down, it takes no new scenario from then on
+var shuttingDown atomic.Bool
+
+// Shutdown refuses new scenarios, takes the node out of the cluster and drains its actors: the trips
+// in progress get until ctx is done to finish, then the drivers go offline and every actor stops.
+// The scenarios of the node are forgotten, a drained actor has nothing left to recover. The actors
+// that failed to drain keep their checkpoints and are recovered on the next start.
+func (handler SimHandler) Shutdown(ctx context.Context) {
+	shuttingDown.Store(true)
+	if err := cluster.Leave(); err != nil {
+		log.Printf("Error leaving the cluster: %v", err)
+	}
+
+	failed := actors.Shutdown(ctx)
+	keep := make([]string, 0, len(failed))
+	for actorId := range failed {
+		keep = append(keep, actorId)
+	}
+	if len(keep) > 0 {
+		log.Printf("Keeping the checkpoints of %d actors that did not drain: %v", len(keep), keep)
+	}
+
+	scenarios, err := checkpoint.Scenarios()
+	if err != nil {
+		log.Printf("Error listing the checkpoints to clear: %v", err)
+		return
+	}
+	for _, scenario := range scenarios {
+		if err := checkpoint.DeleteScenario(scenario.Id, keep...); err != nil {
+			log.Printf("scenario %s: failed to clear checkpoints: %v", scenario.Id, err)
+		}
+	}
+}
+
+// refuseWhenShuttingDown answers 503 to requests starting actors once the process shuts down
+func refuseWhenShuttingDown(context *gin.Context) bool {
+	if !shuttingDown.Load() {
+		return false
+	}
+	context.JSON(http.StatusServiceUnavailable, gin.H{"error": "sim-server is shutting down"})
+	return true
+}
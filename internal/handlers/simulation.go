@@ -1,41 +1,184 @@
 package handlers
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"log"
 	"math"
 	"math/rand"
 	"net/http"
+	"sim-server/config"
 	"sim-server/internal/models"
 	"sim-server/internal/services"
+	"sim-server/internal/simulation/actors"
+	"sim-server/internal/simulation/checkpoint"
+	"sim-server/internal/simulation/clock"
+	"sim-server/internal/simulation/cluster"
 	"sim-server/internal/simulation/customers"
+	"sim-server/internal/simulation/des"
 	"sim-server/internal/simulation/drivers"
+	"sim-server/internal/simulation/recorder"
+	"sim-server/internal/simulation/replay"
+	"sim-server/internal/simulation/socket"
 	"strconv"
+	"sync"
 	"time"
 )
 
 type SimHandler struct {
+	Config *config.Config
+}
+
+type scenarioRequest struct {
+	NumDrivers          int     `json:"num_drivers"`
+	NumCustomers        int     `json:"num_customers"`
+	CenterLat           float64 `json:"center_lat"`
+	CenterLng           float64 `json:"center_lng"`
+	Radius              float64 `json:"radius"`
+	Loop                bool    `json:"loop"`
+	AcceptanceRate      float64 `json:"acceptance_rate"`
+	DriverSeriesStart   int     `json:"driver_series_start"`
+	CustomerSeriesStart int     `json:"customer_series_start"`
+	Target              string  `json:"target"`
+	// Overrides the auth mode of the target, "simulate" or "otp"
+	AuthMode string `json:"auth_mode"`
+	// Share of customers booking ahead, with a lead time drawn uniformly between the min and max minutes
+	ScheduledRatio     float64 `json:"scheduled_ratio"`
+	LeadTimeMinMinutes int     `json:"lead_time_min_minutes"`
+	LeadTimeMaxMinutes int     `json:"lead_time_max_minutes"`
+	ModifyRate         float64 `json:"modify_rate"`
+	CancelRate         float64 `json:"cancel_rate"`
+	// Manual actors skip their script and wait for the control endpoints to tell them what to do
+	Manual bool `json:"manual"`
+	// How crashed actors are restarted, "permanent", "transient" (default) or "temporary"
+	RestartPolicy string `json:"restart_policy"`
+	MaxRestarts   int    `json:"max_restarts"`
+	// How many times faster than the wall clock the actors live, 10 runs an hour of traffic in six minutes
+	Speed float64 `json:"speed"`
+}
+
+// share returns the request for the part of the scenario a node runs
+func (req scenarioRequest) share(share cluster.Share) scenarioRequest {
+	req.NumDrivers = share.NumDrivers
+	req.NumCustomers = share.NumCustomers
+	req.DriverSeriesStart += share.DriverSeriesStart
+	req.CustomerSeriesStart += share.CustomerSeriesStart
+	return req
 }
 
 func (handler SimHandler) SimulateScenario(context *gin.Context) {
-	type request struct {
-		NumDrivers          int     `json:"num_drivers"`
-		NumCustomers        int     `json:"num_customers"`
-		CenterLat           float64 `json:"center_lat"`
-		CenterLng           float64 `json:"center_lng"`
-		Radius              float64 `json:"radius"`
-		Loop                bool    `json:"loop"`
-		AcceptanceRate      float64 `json:"acceptance_rate"`
-		DriverSeriesStart   int     `json:"driver_series_start"`
-		CustomerSeriesStart int     `json:"customer_series_start"`
+	if refuseWhenShuttingDown(context) {
+		return
 	}
-
-	var req request
+	var req scenarioRequest
 	err := context.ShouldBindJSON(&req)
 	if err != nil {
 		context.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	target, err := handler.scenarioTarget(req)
+	if err != nil {
+		context.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Tags the traffic of every actor started by this request
+	scenarioId := uuid.NewString()
+	scenarioClock := clock.New(req.Speed)
+
+	if _, joined := cluster.Self(); !joined {
+		startScenario(scenarioId, scenarioClock, target, req)
+		context.JSON(http.StatusOK, gin.H{"scenario_id": scenarioId})
+		return
+	}
+
+	// Spread the actors across the nodes of the cluster, this node coordinates the scenario
+	nodes, err := cluster.Nodes()
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	shares, err := cluster.Plan(nodes, req.NumDrivers, req.NumCustomers)
+	if errors.Is(err, cluster.ErrNoCapacity) {
+		context.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		return
+	}
+	if err == nil {
+		err = cluster.SavePlacement(scenarioId, shares)
+	}
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	results := onNodes(shares, func(share cluster.Share, local bool) (string, error) {
+		if local {
+			startScenario(scenarioId, scenarioClock, target, req.share(share))
+			return actors.ChildRunning, nil
+		}
+		var response struct {
+			State string `json:"state"`
+		}
+		path := "/simulation/scenarios/" + scenarioId + "/share"
+		err := cluster.Do(context, share.Address, http.MethodPost, path, scenarioShare{req.share(share), scenarioClock.Start()}, &response)
+		return response.State, err
+	})
+	context.JSON(http.StatusOK, gin.H{"scenario_id": scenarioId, "nodes": results})
+}
+
+// scenarioTarget checks a scenario request and returns the target it runs against
+func (handler SimHandler) scenarioTarget(req scenarioRequest) (config.Target, error) {
+	target, err := handler.Config.Target(req.Target)
+	if err != nil {
+		return config.Target{}, err
+	}
+	if req.AuthMode != "" {
+		target.AuthMode = req.AuthMode
+		if err := target.Validate(); err != nil {
+			return config.Target{}, err
+		}
+	}
+
+	switch actors.RestartPolicy(req.RestartPolicy) {
+	case "", actors.Permanent, actors.Transient, actors.Temporary:
+	default:
+		return config.Target{}, errors.New("restart_policy must be permanent, transient or temporary")
+	}
+
+	if req.Speed < 0 {
+		return config.Target{}, errors.New("speed must not be negative")
+	}
+	if req.Speed > 1 && target.WallClock {
+		// its offers would expire and its scheduled trips start in wall time
+		return config.Target{}, fmt.Errorf("target %s runs on the wall clock, speed must be at most 1", target.Name)
+	}
+	return target, nil
+}
+
+// startScenario starts the actors of a scenario, or of the share of it this node runs, under a supervisor
+func startScenario(scenarioId string, scenarioClock *clock.Clock, target config.Target, req scenarioRequest) {
+	clock.Register(scenarioId, scenarioClock)
+	scenario := &scenarioSupervisor{
+		Supervisor:  actors.NewSupervisor(scenarioId),
+		restart:     actors.RestartPolicy(req.RestartPolicy),
+		maxRestarts: req.MaxRestarts,
+	}
+	err := checkpoint.SaveScenario(checkpoint.Scenario{
+		Id:          scenarioId,
+		Target:      target.Name,
+		AuthMode:    target.AuthMode,
+		Restart:     req.RestartPolicy,
+		MaxRestarts: req.MaxRestarts,
+		Speed:       scenarioClock.Speed(),
+		ClockStart:  scenarioClock.Start(),
+	})
+	if err != nil {
+		log.Printf("scenario %s will not be recovered: %v", scenarioId, err)
+	}
 
 	// Initial coordinates
 	lat := req.CenterLat        // CP Lat
@@ -49,7 +192,7 @@ func (handler SimHandler) SimulateScenario(context *gin.Context) {
 		// Generate random point
 		rng := rand.New(rand.NewSource(time.Now().UnixNano()))
 		newLat, newLng := generateRandomPoint(lat, lng, float64(radius), rng)
-		go simNewDriver(phoneNumber, newLat, newLng, req.AcceptanceRate)
+		go simNewDriver(scenario, target, scenarioId, phoneNumber, newLat, newLng, req.AcceptanceRate, req.Manual)
 	}
 
 	for i := 1; i <= req.NumCustomers; i++ {
@@ -63,62 +206,334 @@ func (handler SimHandler) SimulateScenario(context *gin.Context) {
 		rng = rand.New(rand.NewSource(time.Now().UnixNano()))
 		desLat, desLng := generateRandomPoint(lat, lng, float64(radius)*4, rng)
 
-		go simNewCustomer(phoneNumber, req.Loop, orgLat, orgLng, desLat, desLng)
+		var scheduledAt int64
+		if rng.Float64() < req.ScheduledRatio {
+			leadTime := generateLeadTime(req.LeadTimeMinMinutes, req.LeadTimeMaxMinutes, rng)
+			scheduledAt = scenarioClock.Now().Add(leadTime).Unix()
+		}
+
+		go simNewCustomer(scenario, target, scenarioId, phoneNumber, req.Loop, orgLat, orgLng, desLat, desLng, scheduledAt, req.ModifyRate, req.CancelRate, req.Manual)
+	}
+}
+
+// scenarioStatus is the status of the actors of a scenario, on one node or aggregated across its nodes
+type scenarioStatus struct {
+	actors.SupervisorStatus
+	Speed float64      `json:"speed"`
+	Now   time.Time    `json:"now"` // on the clock of the scenario
+	Nodes []nodeResult `json:"nodes,omitempty"`
+}
+
+// ScenarioStatus reports the state of every actor of a scenario and every failure, crashes included.
+// Scenarios spread across nodes are reported as a whole unless local is set.
+func (handler SimHandler) ScenarioStatus(context *gin.Context) {
+	scenarioId := context.Param("id")
+	shares, distributed := cluster.Placement(scenarioId)
+	if !distributed || context.Query("local") == "true" {
+		status, err := localScenarioStatus(scenarioId)
+		if err != nil {
+			context.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		context.JSON(http.StatusOK, status)
+		return
+	}
+
+	var lock sync.Mutex
+	var statuses []scenarioStatus
+	nodes := onNodes(shares, func(share cluster.Share, local bool) (string, error) {
+		var status scenarioStatus
+		var err error
+		if local {
+			status, err = localScenarioStatus(scenarioId)
+		} else {
+			err = cluster.Do(context, share.Address, http.MethodGet, "/simulation/scenarios/"+scenarioId+"?local=true", nil, &status)
+		}
+		if err != nil {
+			return "", err
+		}
+		lock.Lock()
+		statuses = append(statuses, status)
+		lock.Unlock()
+		return status.State, nil
+	})
+	context.JSON(http.StatusOK, mergeStatuses(scenarioId, statuses, nodes))
+}
+
+func localScenarioStatus(scenarioId string) (scenarioStatus, error) {
+	supervisor, ok := actors.LookupSupervisor(scenarioId)
+	if !ok {
+		return scenarioStatus{}, fmt.Errorf("%w %s", errUnknownScenario, scenarioId)
+	}
+	scenarioClock := clock.For(scenarioId)
+	return scenarioStatus{SupervisorStatus: supervisor.Status(), Speed: scenarioClock.Speed(), Now: scenarioClock.Now()}, nil
+}
+
+// AckStats reports how many commands of each type were acknowledged, retried or lost, with their round-trip latency
+func (handler SimHandler) AckStats(context *gin.Context) {
+	context.JSON(http.StatusOK, socket.AckStats())
+}
+
+// LoginStats reports the latency of every login step, for both the simulate and the OTP flows
+func (handler SimHandler) LoginStats(context *gin.Context) {
+	context.JSON(http.StatusOK, services.LoginStats())
+}
+
+// ActorTranscript downloads the recorded websocket traffic of one driver or customer as NDJSON
+func (handler SimHandler) ActorTranscript(context *gin.Context) {
+	entries, err := recorder.ActorTranscript(context.Param("id"))
+	writeTranscript(context, "actor-"+context.Param("id"), entries, err)
+}
+
+// TripTranscript downloads the recorded websocket traffic of every actor involved in a trip as NDJSON
+func (handler SimHandler) TripTranscript(context *gin.Context) {
+	entries, err := recorder.TripTranscript(context.Param("id"))
+	writeTranscript(context, "trip-"+context.Param("id"), entries, err)
+}
+
+// Replay re-drives the outbound side of an NDJSON transcript against a target with its recorded
+// timing in the background, ReplayStatus reports every response that differs from the recording
+func (handler SimHandler) Replay(context *gin.Context) {
+	type request struct {
+		Target              string  `form:"target"`
+		Speed               float64 `form:"speed"`
+		DriverSeriesStart   int     `form:"driver_series_start"`
+		CustomerSeriesStart int     `form:"customer_series_start"`
+	}
+
+	var req request
+	if err := context.ShouldBindQuery(&req); err != nil {
+		context.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	target, err := handler.Config.Target(req.Target)
+	if err != nil {
+		context.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	entries, err := recorder.ReadEntries(context.Request.Body)
+	if err != nil {
+		context.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
 
+	job, err := replay.Start(entries, replay.Options{
+		Target:              target,
+		Speed:               req.Speed,
+		DriverSeriesStart:   req.DriverSeriesStart,
+		CustomerSeriesStart: req.CustomerSeriesStart,
+	})
+	if err != nil {
+		context.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	context.JSON(http.StatusAccepted, job)
 }
 
-func simulateDriver(driver *models.Driver, lat, lng, acceptanceRate float64) {
-	drivers.NewSimulatedDriver(*driver, lat, lng, acceptanceRate)
-	drivers.CheckAndGoOnline(driver.Id)
-	drivers.Connect(driver.Id)
+// ReplayStatus reports how a replay is getting on, with the differences once it finished
+func (handler SimHandler) ReplayStatus(context *gin.Context) {
+	job, ok := replay.Status(context.Param("id"))
+	if !ok {
+		context.JSON(http.StatusNotFound, gin.H{"error": "replay " + context.Param("id") + " not found"})
+		return
+	}
+	context.JSON(http.StatusOK, job)
 }
 
-func simulateCustomer(customer *models.Customer, loop bool) {
-	customers.NewSimulatedCustomer(*customer, loop)
-	customers.Connect(customer.Id)
+// CapacityStudy runs a headless discrete-event simulation of a zone against the in-process mock
+// dispatcher and reports how its trips turned out, per hour of the simulated time
+func (handler SimHandler) CapacityStudy(context *gin.Context) {
+	type request struct {
+		// Start of the simulated time, midnight of today by default
+		Start time.Time `json:"start"`
+		// How many hours to simulate, a day by default
+		Hours              float64 `json:"hours"`
+		Seed               int64   `json:"seed"`
+		NumDrivers         int     `json:"num_drivers"`
+		NumCustomers       int     `json:"num_customers"`
+		CenterLat          float64 `json:"center_lat"`
+		CenterLng          float64 `json:"center_lng"`
+		Radius             float64 `json:"radius"`
+		Loop               bool    `json:"loop"`
+		AcceptanceRate     float64 `json:"acceptance_rate"`
+		ScheduledRatio     float64 `json:"scheduled_ratio"`
+		LeadTimeMinMinutes int     `json:"lead_time_min_minutes"`
+		LeadTimeMaxMinutes int     `json:"lead_time_max_minutes"`
+		ModifyRate         float64 `json:"modify_rate"`
+		CancelRate         float64 `json:"cancel_rate"`
+	}
+
+	var req request
+	if err := context.ShouldBindJSON(&req); err != nil {
+		context.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	report, err := des.Run(des.Options{
+		Start:          req.Start,
+		Duration:       time.Duration(req.Hours * float64(time.Hour)),
+		Seed:           req.Seed,
+		NumDrivers:     req.NumDrivers,
+		NumCustomers:   req.NumCustomers,
+		CenterLat:      req.CenterLat,
+		CenterLng:      req.CenterLng,
+		Radius:         req.Radius,
+		AcceptanceRate: req.AcceptanceRate,
+		Loop:           req.Loop,
+		ScheduledRatio: req.ScheduledRatio,
+		LeadTimeMin:    time.Duration(req.LeadTimeMinMinutes) * time.Minute,
+		LeadTimeMax:    time.Duration(max(req.LeadTimeMaxMinutes, req.LeadTimeMinMinutes)) * time.Minute,
+		ModifyRate:     req.ModifyRate,
+		CancelRate:     req.CancelRate,
+	})
+	if err != nil {
+		context.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	context.JSON(http.StatusOK, report)
 }
 
-func simNewCustomer(phoneNumber int, loop bool, orgLat, orgLng, desLat, desLng float64) {
-	response, err := services.CustomerLogin(strconv.Itoa(phoneNumber))
+func writeTranscript(context *gin.Context, name string, entries []recorder.Entry, err error) {
+	if errors.Is(err, recorder.ErrDisabled) {
+		context.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
-		log.Printf("error logging in: %v", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if len(entries) == 0 {
+		context.JSON(http.StatusNotFound, gin.H{"error": "nothing recorded for " + name})
 		return
 	}
-	if response.Status {
-		var customer models.Customer
-		customer.Id = response.Data.(map[string]interface{})["id"].(string)
-		customer.Name = response.Data.(map[string]interface{})["name"].(string)
-		customer.PhoneNumber = response.Data.(map[string]interface{})["phone_number"].(string)
-		customer.AccessToken = response.Data.(map[string]interface{})["access_token"].(string)
-		simulateCustomer(&customer, loop)
-		customers.ConfirmTrip(customer.Id, orgLat, orgLng, desLat, desLng)
-	} else {
-		log.Printf("error: %v", response.Message)
+
+	context.Header("Content-Disposition", `attachment; filename="`+name+`.ndjson"`)
+	context.Header("Content-Type", "application/x-ndjson")
+	context.Status(http.StatusOK)
+	encoder := json.NewEncoder(context.Writer)
+	for _, entry := range entries {
+		if err := encoder.Encode(entry); err != nil {
+			log.Printf("error writing transcript %s: %v", name, err)
+			return
+		}
+	}
+}
+
+// simulateDriver starts a driver and takes it online, a driver that did not get online is stopped again
+func simulateDriver(driver *models.Driver, target config.Target, scenarioId string, lat, lng, acceptanceRate float64, manual bool) error {
+	drivers.NewSimulatedDriver(*driver, target, scenarioId, lat, lng, acceptanceRate, manual)
+	if err := drivers.CheckAndGoOnline(driver.Id); err != nil {
+		return abandon(driver.Id, err)
+	}
+	if err := drivers.Connect(driver.Id); err != nil {
+		return abandon(driver.Id, err)
+	}
+	return nil
+}
+
+// simulateCustomer starts a customer and connects it, a customer that did not connect is stopped again
+func simulateCustomer(customer *models.Customer, target config.Target, scenarioId string, loop bool, modifyRate, cancelRate float64, manual bool) error {
+	customers.NewSimulatedCustomer(*customer, target, scenarioId, loop, modifyRate, cancelRate, manual)
+	if err := customers.Connect(customer.Id); err != nil {
+		return abandon(customer.Id, err)
 	}
+	return nil
 }
 
-func simNewDriver(phoneNumber int, lat, lng, acceptanceRate float64) {
-	response, err := services.DriverLogin(strconv.Itoa(phoneNumber))
+// abandon stops the actor of a failed start and returns why it failed, so its supervisor records
+// the failure and starts it again according to the restart policy
+func abandon(id string, err error) error {
+	if actor, ok := actors.Lookup(id); ok {
+		actor.Stop()
+	}
+	return err
+}
+
+func simNewCustomer(scenario *scenarioSupervisor, target config.Target, scenarioId string, phoneNumber int, loop bool, orgLat, orgLng, desLat, desLng float64, scheduledAt int64, modifyRate, cancelRate float64, manual bool) {
+	response, err := services.CustomerAuth(target)(context.Background(), target, strconv.Itoa(phoneNumber))
+	if err == nil && !response.Status {
+		err = errors.New(response.Message)
+	}
+	var customer models.Customer
+	if err == nil {
+		customer.Id, customer.Name, customer.PhoneNumber, customer.AccessToken, err = loggedIn(response)
+	}
 	if err != nil {
-		log.Printf("error logging in: %v", err)
+		scenario.Fail("customer", strconv.Itoa(phoneNumber), fmt.Errorf("logging in: %w", err))
 		return
 	}
-	log.Printf("response: %v", response)
-	if response.Status {
-		var driver models.Driver
-		driver.Id = response.Data.(map[string]interface{})["id"].(string)
-		driver.Name = response.Data.(map[string]interface{})["name"].(string)
-		driver.PhoneNumber = response.Data.(map[string]interface{})["phone_number"].(string)
-		driver.AccessToken = response.Data.(map[string]interface{})["access_token"].(string)
-		simulateDriver(&driver, lat, lng, acceptanceRate)
-	} else {
-		log.Printf("error: %v", response.Message)
+
+	booked := false
+	scenario.StartChild(scenario.spec("customer", customer.Id, func() error {
+		if err := simulateCustomer(&customer, target, scenarioId, loop, modifyRate, cancelRate, manual); err != nil {
+			return err
+		}
+		// a restarted customer does not book again, the trip it booked is still with the backend
+		if !booked {
+			booked = true
+			customers.ConfirmTrip(customer.Id, orgLat, orgLng, desLat, desLng, scheduledAt)
+		}
+		return nil
+	}))
+}
+
+func simNewDriver(scenario *scenarioSupervisor, target config.Target, scenarioId string, phoneNumber int, lat, lng, acceptanceRate float64, manual bool) {
+	response, err := services.DriverAuth(target)(context.Background(), target, strconv.Itoa(phoneNumber))
+	if err == nil && !response.Status {
+		err = errors.New(response.Message)
 	}
+	var driver models.Driver
+	if err == nil {
+		log.Printf("response: %v", response)
+		driver.Id, driver.Name, driver.PhoneNumber, driver.AccessToken, err = loggedIn(response)
+	}
+	if err != nil {
+		scenario.Fail("driver", strconv.Itoa(phoneNumber), fmt.Errorf("logging in: %w", err))
+		return
+	}
+
+	scenario.StartChild(scenario.spec("driver", driver.Id, func() error {
+		return simulateDriver(&driver, target, scenarioId, lat, lng, acceptanceRate, manual)
+	}))
+}
+
+// loggedIn reads the actor out of a login response, refusing responses of an unexpected shape
+func loggedIn(response *models.CommonResponse) (id, name, phoneNumber, accessToken string, err error) {
+	data, ok := response.Data.(map[string]interface{})
+	if !ok {
+		return "", "", "", "", fmt.Errorf("unexpected login response data: %v", response.Data)
+	}
+	fields := []*string{&id, &name, &phoneNumber, &accessToken}
+	for i, key := range []string{"id", "name", "phone_number", "access_token"} {
+		if *fields[i], ok = data[key].(string); !ok {
+			return "", "", "", "", fmt.Errorf("login response has no %s", key)
+		}
+	}
+	return id, name, phoneNumber, accessToken, nil
+}
+
+// scenarioSupervisor starts every actor of a scenario with the restart policy of the request
+type scenarioSupervisor struct {
+	*actors.Supervisor
+	restart     actors.RestartPolicy
+	maxRestarts int
+}
+
+func (s *scenarioSupervisor) spec(kind, id string, start func() error) actors.ChildSpec {
+	return actors.ChildSpec{Kind: kind, Id: id, Restart: s.restart, MaxRestarts: s.maxRestarts, Start: start}
 }
 
 const EarthRadius = 6371000.0 // Earth's radius in meters
 
+// generateLeadTime picks a booking lead time uniformly between the given minutes
+func generateLeadTime(minMinutes, maxMinutes int, rng *rand.Rand) time.Duration {
+	if maxMinutes < minMinutes {
+		maxMinutes = minMinutes
+	}
+	minutes := float64(minMinutes) + rng.Float64()*float64(maxMinutes-minMinutes)
+	return time.Duration(minutes * float64(time.Minute))
+}
+
 // toRadians converts degrees to radians
 func toRadians(deg float64) float64 {
 	return deg * math.Pi / 180
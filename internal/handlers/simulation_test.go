@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"sim-server/config"
+	"sim-server/internal/mockbackend"
+	"sim-server/internal/simulation/actors"
+)
+
+func TestFailedStartIsRestarted(t *testing.T) {
+	backend := httptest.NewServer(mockbackend.NewServer().Handler())
+	defer backend.Close()
+	// the REST API answers, the websocket is refused
+	target := config.Target{Name: "mock", BaseURL: backend.URL, WebsocketURL: "ws://127.0.0.1:1"}
+
+	tests := []struct {
+		kind  string
+		start func(scenario *scenarioSupervisor)
+	}{
+		{kind: "driver", start: func(scenario *scenarioSupervisor) {
+			simNewDriver(scenario, target, "", 1111100001, 28.6139, 77.2090, 1, false)
+		}},
+		{kind: "customer", start: func(scenario *scenarioSupervisor) {
+			simNewCustomer(scenario, target, "", 1111100002, false, 28.62, 77.21, 28.65, 77.25, 0, 0, 0, false)
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.kind, func(t *testing.T) {
+			scenario := &scenarioSupervisor{Supervisor: actors.NewSupervisor("failed-start-" + tt.kind), restart: actors.Transient, maxRestarts: 1}
+			defer scenario.Stop()
+
+			tt.start(scenario)
+
+			// the first start fails, the restart a second later too and the supervisor gives up
+			deadline := time.Now().Add(5 * time.Second)
+			for {
+				status := scenario.Status()
+				if len(status.Children) == 1 && status.Children[0].State == actors.ChildFailed {
+					if len(status.Failures) != 2 || !status.Failures[0].Restarted || status.Failures[1].Restarted {
+						t.Errorf("failures %+v, want one restarted and the one it gave up on", status.Failures)
+					}
+					break
+				}
+				if time.Now().After(deadline) {
+					t.Fatalf("supervisor status %+v", status)
+				}
+				time.Sleep(20 * time.Millisecond)
+			}
+			if n := len(actors.All(tt.kind)); n != 0 {
+				t.Errorf("%d %ss left running", n, tt.kind)
+			}
+		})
+	}
+}
+
+func TestScenarioSpeed(t *testing.T) {
+	handler := SimHandler{Config: &config.Config{Targets: map[string]config.Target{
+		"backend": {Name: "backend"},
+		"mock":    {Name: "mock", WallClock: true},
+	}}}
+	tests := []struct {
+		target string
+		speed  float64
+		valid  bool
+	}{
+		{target: "backend", speed: 10, valid: true},
+		{target: "backend", speed: -1},
+		{target: "mock", speed: 1, valid: true},
+		{target: "mock", speed: 10},
+	}
+	for _, tt := range tests {
+		_, err := handler.scenarioTarget(scenarioRequest{Target: tt.target, Speed: tt.speed})
+		if (err == nil) != tt.valid {
+			t.Errorf("speed %v against %s: error %v", tt.speed, tt.target, err)
+		}
+	}
+}
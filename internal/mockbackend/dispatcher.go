@@ -0,0 +1,130 @@
+package mockbackend
+
+import (
+	"math"
+	"time"
+
+	"sim-server/internal/models"
+
+	"googlemaps.github.io/maps"
+)
+
+const (
+	offerTimeout  = 30 * time.Second
+	earthRadius   = 6371000.0 // meters
+	averageSpeed  = 10.0      // meters per second
+	routeSegments = 5
+	baseFare      = 5.0
+	farePerKm     = 1.5
+)
+
+// dispatch offers the trip to the nearest available driver that has not turned it down yet,
+// the caller must hold the server lock
+func (s *Server) dispatch(t *trip) {
+	var nearest *driverState
+	nearestDistance := math.MaxFloat64
+	for _, driver := range s.drivers {
+		if !s.available(driver) || t.rejectedBy[driver.user.Id] {
+			continue
+		}
+		if d := distance(driver.lat, driver.lng, t.OriginLat, t.OriginLng); d < nearestDistance {
+			nearest, nearestDistance = driver, d
+		}
+	}
+
+	customer := s.customers[t.customerId]
+	if nearest == nil {
+		t.Status = "no_driver"
+		customer.activeTrip = ""
+		command := models.NoDriverFound
+		if len(t.rejectedBy) > 0 {
+			command = models.NoDriverAcceptedTrip
+		}
+		customer.session.send(command, models.TripStatusMessage{TripId: t.Id, Status: t.Status})
+		return
+	}
+
+	nearest.pendingTrip = t.Id
+	nearest.session.send(models.NewTripOffer, models.NewTripOfferMessage{
+		TripOffer:      models.TripOffer{TripId: t.Id, Trip: t.Trip},
+		PickupEstimate: estimateRoute(nearest.lat, nearest.lng, t.OriginLat, t.OriginLng),
+		TripEstimate:   estimateRoute(t.OriginLat, t.OriginLng, t.DestinationLat, t.DestinationLng),
+	})
+
+	driverId := nearest.user.Id
+	t.offerTimer = s.afterFunc(offerTimeout, func() {
+		s.lock.Lock()
+		defer s.lock.Unlock()
+		driver := s.drivers[driverId]
+		if driver.pendingTrip != t.Id {
+			return
+		}
+		driver.session.send(models.TripTimedOut, models.TripStatusMessage{TripId: t.Id})
+		s.rejectTrip(driver, t)
+	})
+}
+
+// available reports whether the driver can be offered a trip now. Drivers holding
+// a scheduled trip stay available until they head to its pickup.
+func (s *Server) available(driver *driverState) bool {
+	return driver.session != nil && driver.hasShift && driver.located &&
+		driver.activeTrip == "" && driver.pendingTrip == ""
+}
+
+func (s *Server) acceptTrip(driver *driverState, t *trip) {
+	t.offerTimer.Stop()
+	driver.pendingTrip = ""
+	t.driverId = driver.user.Id
+	if t.ScheduledAt <= s.now().Unix() {
+		driver.activeTrip = t.Id
+	}
+	s.updateTrip(t, models.AcceptTrip, "accepted")
+
+	pickup := estimateRoute(driver.lat, driver.lng, t.OriginLat, t.OriginLng)
+	s.customers[t.customerId].session.send(models.Eta, models.EtaMessage{
+		TripId:   t.Id,
+		Distance: pickup.Distance,
+		Duration: pickup.Duration,
+	})
+}
+
+func (s *Server) rejectTrip(driver *driverState, t *trip) {
+	t.offerTimer.Stop()
+	driver.pendingTrip = ""
+	t.rejectedBy[driver.user.Id] = true
+	s.dispatch(t)
+}
+
+// estimateRoute returns a straight line route between the points, driven at averageSpeed
+func estimateRoute(fromLat, fromLng, toLat, toLng float64) models.RouteEstimate {
+	path := make([]maps.LatLng, 0, routeSegments+1)
+	for i := 0; i <= routeSegments; i++ {
+		f := float64(i) / routeSegments
+		path = append(path, maps.LatLng{
+			Lat: fromLat + (toLat-fromLat)*f,
+			Lng: fromLng + (toLng-fromLng)*f,
+		})
+	}
+	meters := distance(fromLat, fromLng, toLat, toLng)
+	return models.RouteEstimate{
+		Distance: meters,
+		Duration: meters / averageSpeed,
+		Route: models.Route{
+			Polyline: models.Polyline{EncodedPolyline: maps.Encode(path)},
+		},
+	}
+}
+
+func fare(meters float64) float64 {
+	return math.Round((baseFare+farePerKm*meters/1000)*100) / 100
+}
+
+// distance returns the great-circle distance between two points in meters
+func distance(lat1, lng1, lat2, lng2 float64) float64 {
+	lat1Rad, lat2Rad := lat1*math.Pi/180, lat2*math.Pi/180
+	deltaLat := (lat2 - lat1) * math.Pi / 180
+	deltaLng := (lng2 - lng1) * math.Pi / 180
+	a := math.Sin(deltaLat/2)*math.Sin(deltaLat/2) +
+		math.Cos(lat1Rad)*math.Cos(lat2Rad)*math.Sin(deltaLng/2)*math.Sin(deltaLng/2)
+	return 2 * earthRadius * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
+}
@@ -0,0 +1,52 @@
+package mockbackend
+
+import "sim-server/internal/models"
+
+// ConnectDriver logs a driver in, starts its shift and attaches an in-process session: the frames
+// the backend pushes to the driver are handed to deliver instead of a websocket. deliver is called
+// with the server lock held, so it must not call back into the server.
+func (s *Server) ConnectDriver(phoneNumber string, deliver func(message []byte)) (driverId string) {
+	u := s.login(roleDriver, phoneNumber)
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	driver := s.drivers[u.Id]
+	driver.hasShift = true
+	driver.session = &session{deliver: deliver}
+	return u.Id
+}
+
+// ConnectCustomer logs a customer in and attaches an in-process session, see ConnectDriver.
+func (s *Server) ConnectCustomer(phoneNumber string, deliver func(message []byte)) (customerId string) {
+	u := s.login(roleCustomer, phoneNumber)
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.customers[u.Id].session = &session{deliver: deliver}
+	return u.Id
+}
+
+// Receive handles a command of an in-process session as if it came over its websocket. Unlike
+// websocket traffic it is not kept for Received, a simulated day of it would pile up.
+func (s *Server) Receive(userId string, message models.IncomingMessage) {
+	s.lock.Lock()
+	var handle func(sess *session)
+	var sess *session
+	first := s.firstSeen(userId, message)
+	if driver, ok := s.drivers[userId]; ok {
+		sess = driver.session
+		handle = func(sess *session) { s.handleDriverCommand(driver, sess, message) }
+	} else if customer, ok := s.customers[userId]; ok {
+		sess = customer.session
+		handle = func(sess *session) { s.handleCustomerCommand(customer, sess, message) }
+	}
+	s.lock.Unlock()
+
+	if sess == nil {
+		return
+	}
+	sess.ack(message)
+	if first {
+		handle(sess)
+	}
+}
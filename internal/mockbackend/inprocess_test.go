@@ -0,0 +1,37 @@
+package mockbackend
+
+import (
+	"encoding/json"
+	"testing"
+
+	"sim-server/internal/models"
+)
+
+func TestResendIsHandledOnce(t *testing.T) {
+	s := NewServer()
+	var received []models.Command
+	customerId := s.ConnectCustomer("1111100001", func(message []byte) {
+		var frame models.ServerMessage
+		if err := json.Unmarshal(message, &frame); err == nil {
+			received = append(received, frame.Command)
+		}
+	})
+	payload, _ := json.Marshal(models.TripRequestPayload{})
+	confirm := models.IncomingMessage{Command: models.ConfirmTrip, MessageId: "confirm-1", Payload: payload}
+
+	// the ack of the first attempt got lost, the client sends the frame again
+	s.Receive(customerId, confirm)
+	s.Receive(customerId, confirm)
+	s.Receive(customerId, models.IncomingMessage{Command: models.ConfirmTrip, MessageId: "confirm-2", Payload: payload})
+
+	counts := make(map[models.Command]int)
+	for _, command := range received {
+		counts[command]++
+	}
+	if counts[models.Ack] != 3 || counts[models.ConfirmTrip] != 2 {
+		t.Errorf("customer received %v, want three acks and two bookings", received)
+	}
+	if len(s.trips) != 2 {
+		t.Errorf("%d trips booked, want 2", len(s.trips))
+	}
+}
@@ -0,0 +1,333 @@
+package mockbackend
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"sim-server/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	roleDriver        = "driver"
+	roleCustomer      = "customer"
+	closeTokenExpired = 4401
+	backdoorOtp       = "1234"
+)
+
+type user struct {
+	Id          string
+	Name        string
+	PhoneNumber string
+	AccessToken string
+	Role        string
+}
+
+type driverState struct {
+	user        *user
+	session     *session
+	hasShift    bool
+	lat         float64
+	lng         float64
+	located     bool
+	activeTrip  string
+	pendingTrip string // trip currently offered to the driver
+}
+
+type customerState struct {
+	user       *user
+	session    *session
+	activeTrip string
+}
+
+type trip struct {
+	models.Trip
+	customerId string
+	driverId   string
+	rejectedBy map[string]bool
+	offerTimer Timer
+}
+
+// Timer is a pending call of the clock of the server, *time.Timer satisfies it
+type Timer interface {
+	Stop() bool
+}
+
+// Server is a stand-in for the rh-core REST and websocket APIs used by the simulator.
+// It keeps all state in memory and dispatches trips to the nearest available driver.
+// All its users share one clock, so the target serving it declares wall_clock and the
+// scenarios against it run at speed 1.
+type Server struct {
+	lock      sync.Mutex
+	users     map[string]*user // by access token
+	phones    map[string]*user // by role and phone number
+	otps      map[string]bool  // requested OTPs by role and phone number
+	drivers   map[string]*driverState
+	customers map[string]*customerState
+	trips     map[string]*trip
+	received  map[string][]models.IncomingMessage // by user id, in arrival order, nil unless RecordReceived
+	handled   map[string]*recentIds               // by user id, see firstSeen
+
+	// the clock of the dispatcher, the wall clock unless UseClock replaced it
+	now       func() time.Time
+	afterFunc func(d time.Duration, f func()) Timer
+
+	httpServer *http.Server // set by ListenAndServe
+}
+
+func NewServer() *Server {
+	return &Server{
+		users:     make(map[string]*user),
+		phones:    make(map[string]*user),
+		otps:      make(map[string]bool),
+		drivers:   make(map[string]*driverState),
+		customers: make(map[string]*customerState),
+		trips:     make(map[string]*trip),
+		handled:   make(map[string]*recentIds),
+		now:       time.Now,
+		afterFunc: func(d time.Duration, f func()) Timer { return time.AfterFunc(d, f) },
+	}
+}
+
+// UseClock runs the dispatcher on another clock than the wall clock, such as the one of the
+// discrete-event engine. It must be called before the server is used.
+func (s *Server) UseClock(now func() time.Time, afterFunc func(d time.Duration, f func()) Timer) {
+	s.now = now
+	s.afterFunc = afterFunc
+}
+
+// Handler returns the HTTP handler serving the mocked REST and websocket endpoints.
+func (s *Server) Handler() http.Handler {
+	router := gin.New()
+	router.Use(gin.Recovery())
+
+	router.POST("/api/v1/admin/simulate/driver", s.simulateLogin(roleDriver))
+	router.POST("/api/v1/admin/simulate/customer", s.simulateLogin(roleCustomer))
+	router.POST("/api/v1/driver/auth/request_otp", s.requestOtp(roleDriver))
+	router.POST("/api/v1/driver/auth/verify_otp", s.verifyOtp(roleDriver))
+	router.POST("/api/v1/customer/auth/request_otp", s.requestOtp(roleCustomer))
+	router.POST("/api/v1/customer/auth/verify_otp", s.verifyOtp(roleCustomer))
+
+	driver := router.Group("/api/v1/driver", s.authenticate(roleDriver))
+	{
+		driver.GET("/shift_status", s.shiftStatus)
+		driver.POST("/go_online", s.goOnline)
+	}
+
+	router.GET("/ws/driver", s.authenticate(roleDriver), s.serveDriver)
+	router.GET("/ws/customer", s.authenticate(roleCustomer), s.serveCustomer)
+	return router
+}
+
+// ListenAndServe runs the mock backend on address until it fails.
+func (s *Server) ListenAndServe(address string) error {
+	s.lock.Lock()
+	s.httpServer = &http.Server{Addr: address, Handler: s.Handler()}
+	httpServer := s.httpServer
+	s.lock.Unlock()
+	log.Printf("Mock backend listening on %s", address)
+	if err := httpServer.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// Shutdown stops serving once the requests in flight are answered, the websockets stay with their clients
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.lock.Lock()
+	httpServer := s.httpServer
+	s.lock.Unlock()
+	if httpServer == nil {
+		return nil
+	}
+	return httpServer.Shutdown(ctx)
+}
+
+// RecordReceived keeps every websocket command for Received, for tests. A long running mock does
+// not, the commands would pile up. It must be called before the server is used.
+func (s *Server) RecordReceived() {
+	s.received = make(map[string][]models.IncomingMessage)
+}
+
+// Received returns the websocket commands the user sent so far, in the order they arrived,
+// see RecordReceived.
+func (s *Server) Received(userId string) []models.IncomingMessage {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return append([]models.IncomingMessage(nil), s.received[userId]...)
+}
+
+// ExpireTokens revokes every access token and drops the open websockets with closeTokenExpired,
+// as rh-core does when tokens run out during a long run.
+func (s *Server) ExpireTokens() {
+	s.lock.Lock()
+	for token := range s.users {
+		delete(s.users, token)
+	}
+	var sessions []*session
+	for _, driver := range s.drivers {
+		sessions = append(sessions, driver.session)
+	}
+	for _, customer := range s.customers {
+		sessions = append(sessions, customer.session)
+	}
+	s.lock.Unlock()
+
+	for _, sess := range sessions {
+		sess.close(closeTokenExpired, "token expired")
+	}
+}
+
+func (s *Server) simulateLogin(role string) gin.HandlerFunc {
+	return func(context *gin.Context) {
+		var req struct {
+			PhoneNumber string `json:"phone_number"`
+		}
+		if err := context.ShouldBindJSON(&req); err != nil || req.PhoneNumber == "" {
+			context.JSON(http.StatusBadRequest, models.CommonResponse{Status: false, Message: "phone_number is required"})
+			return
+		}
+
+		loggedIn(context, s.login(role, req.PhoneNumber))
+	}
+}
+
+// requestOtp pretends to text an OTP to the phone number, only the backdoor code verifies it
+func (s *Server) requestOtp(role string) gin.HandlerFunc {
+	return func(context *gin.Context) {
+		var req struct {
+			PhoneNumber string `json:"phone_number"`
+		}
+		if err := context.ShouldBindJSON(&req); err != nil || req.PhoneNumber == "" {
+			context.JSON(http.StatusBadRequest, models.CommonResponse{Status: false, Message: "phone_number is required"})
+			return
+		}
+
+		s.lock.Lock()
+		s.otps[role+":"+req.PhoneNumber] = true
+		s.lock.Unlock()
+
+		context.JSON(http.StatusOK, models.CommonResponse{Status: true, Message: "otp sent"})
+	}
+}
+
+func (s *Server) verifyOtp(role string) gin.HandlerFunc {
+	return func(context *gin.Context) {
+		var req struct {
+			PhoneNumber string `json:"phone_number"`
+			Otp         string `json:"otp"`
+		}
+		if err := context.ShouldBindJSON(&req); err != nil || req.PhoneNumber == "" || req.Otp == "" {
+			context.JSON(http.StatusBadRequest, models.CommonResponse{Status: false, Message: "phone_number and otp are required"})
+			return
+		}
+
+		key := role + ":" + req.PhoneNumber
+		s.lock.Lock()
+		requested := s.otps[key]
+		valid := requested && req.Otp == backdoorOtp
+		if valid {
+			delete(s.otps, key)
+		}
+		s.lock.Unlock()
+
+		if !valid {
+			context.JSON(http.StatusOK, models.CommonResponse{Status: false, Message: "invalid otp"})
+			return
+		}
+		loggedIn(context, s.login(role, req.PhoneNumber))
+	}
+}
+
+func loggedIn(context *gin.Context, u *user) {
+	context.JSON(http.StatusOK, models.CommonResponse{
+		Status: true,
+		Data: gin.H{
+			"id":           u.Id,
+			"name":         u.Name,
+			"phone_number": u.PhoneNumber,
+			"access_token": u.AccessToken,
+		},
+	})
+}
+
+// login returns the user for the phone number, creating it on first login, with a fresh token
+func (s *Server) login(role, phoneNumber string) *user {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	u, ok := s.phones[role+":"+phoneNumber]
+	if !ok {
+		u = &user{
+			Id:          newId(),
+			Name:        "Simulated " + role + " " + phoneNumber,
+			PhoneNumber: phoneNumber,
+			Role:        role,
+		}
+		s.phones[role+":"+phoneNumber] = u
+		switch role {
+		case roleDriver:
+			s.drivers[u.Id] = &driverState{user: u}
+		case roleCustomer:
+			s.customers[u.Id] = &customerState{user: u}
+		}
+	} else {
+		delete(s.users, u.AccessToken)
+	}
+	u.AccessToken = newId()
+	s.users[u.AccessToken] = u
+	return u
+}
+
+func (s *Server) authenticate(role string) gin.HandlerFunc {
+	return func(context *gin.Context) {
+		token := strings.TrimPrefix(context.GetHeader("Authorization"), "Bearer ")
+
+		s.lock.Lock()
+		u, ok := s.users[token]
+		s.lock.Unlock()
+
+		if !ok || u.Role != role {
+			context.AbortWithStatusJSON(http.StatusUnauthorized, models.CommonResponse{Status: false, Message: "unauthorized"})
+			return
+		}
+		context.Set("user", u)
+		context.Next()
+	}
+}
+
+func (s *Server) shiftStatus(context *gin.Context) {
+	u := context.MustGet("user").(*user)
+
+	s.lock.Lock()
+	hasShift := s.drivers[u.Id].hasShift
+	s.lock.Unlock()
+
+	context.JSON(http.StatusOK, models.CommonResponse{Status: true, Data: gin.H{"has_active_shift": hasShift}})
+}
+
+func (s *Server) goOnline(context *gin.Context) {
+	u := context.MustGet("user").(*user)
+
+	s.lock.Lock()
+	driver := s.drivers[u.Id]
+	started := !driver.hasShift
+	driver.hasShift = true
+	s.lock.Unlock()
+
+	context.JSON(http.StatusOK, models.CommonResponse{Status: true, Data: gin.H{"new_shift_started": started}})
+}
+
+func newId() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,400 @@
+package mockbackend
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"sim-server/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+var upgrader = websocket.Upgrader{
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+const (
+	// sessionQueue is how many frames a websocket may fall behind before the backend drops it
+	sessionQueue = 1024
+	// handledWindow is how many message ids of each user the backend remembers, a client resends
+	// an unacknowledged command within seconds
+	handledWindow = 64
+)
+
+// session is the websocket connection of one logged in user, or the callback of an in-process one.
+// The frames to a websocket are queued and written by writeLoop, so the server lock is never held
+// while writing to a client.
+type session struct {
+	conn      *websocket.Conn
+	deliver   func(message []byte)
+	writeLock sync.Mutex // serialises the writes to conn
+	outgoing  chan []byte
+	done      chan struct{} // closed once the connection is gone
+	doneOnce  sync.Once
+}
+
+func newSession(conn *websocket.Conn) *session {
+	s := &session{conn: conn, outgoing: make(chan []byte, sessionQueue), done: make(chan struct{})}
+	go s.writeLoop()
+	return s
+}
+
+func (s *session) send(command models.Command, data interface{}) {
+	if s == nil {
+		return
+	}
+	jsonData, err := json.Marshal(data)
+	if err != nil {
+		log.Printf("mock backend marshal %s: %v", command, err)
+		return
+	}
+	message, _ := json.Marshal(models.ServerMessage{
+		Command: command,
+		Status:  true,
+		Data:    jsonData,
+	})
+
+	if s.deliver != nil {
+		s.writeLock.Lock()
+		defer s.writeLock.Unlock()
+		s.deliver(message)
+		return
+	}
+	select {
+	case <-s.done:
+	case s.outgoing <- message:
+	default:
+		log.Printf("mock backend: dropping a client %d frames behind", sessionQueue)
+		go s.close(websocket.CloseTryAgainLater, "too far behind")
+	}
+}
+
+func (s *session) writeLoop() {
+	for {
+		select {
+		case <-s.done:
+			return
+		case message := <-s.outgoing:
+			s.writeLock.Lock()
+			err := s.conn.WriteMessage(websocket.TextMessage, message)
+			s.writeLock.Unlock()
+			if err != nil {
+				log.Printf("mock backend write: %v", err)
+				s.stop()
+				return
+			}
+		}
+	}
+}
+
+// close drops the connection with a close frame carrying code
+func (s *session) close(code int, reason string) {
+	if s == nil || s.conn == nil {
+		return
+	}
+	s.writeLock.Lock()
+	defer s.writeLock.Unlock()
+	s.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), time.Now().Add(time.Second))
+	s.conn.Close()
+	s.stop()
+}
+
+// stop ends writeLoop once the connection is gone, the frames still queued are dropped
+func (s *session) stop() {
+	s.doneOnce.Do(func() { close(s.done) })
+}
+
+func (s *session) ack(message models.IncomingMessage) {
+	if message.MessageId == "" || message.Command == models.DriverLocation {
+		return
+	}
+	s.send(models.Ack, models.AckMessage{MessageId: message.MessageId, Command: message.Command})
+}
+
+// recentIds are the latest message ids a user sent, oldest first
+type recentIds struct {
+	ids   map[string]bool
+	order []string
+}
+
+// firstSeen records the message id of a command and reports whether it is new. The simulator resends
+// a command that was not acknowledged with its message id, see socket.Client.SendCommand: a resend is
+// acknowledged again but handled only once, so a lost ack never books or completes a trip twice.
+// The caller must hold the server lock.
+func (s *Server) firstSeen(userId string, message models.IncomingMessage) bool {
+	if message.MessageId == "" || message.Command == models.DriverLocation {
+		return true
+	}
+	recent, ok := s.handled[userId]
+	if !ok {
+		recent = &recentIds{ids: make(map[string]bool)}
+		s.handled[userId] = recent
+	}
+	if recent.ids[message.MessageId] {
+		log.Printf("mock backend: %s %s was already handled", message.Command, message.MessageId)
+		return false
+	}
+	recent.ids[message.MessageId] = true
+	recent.order = append(recent.order, message.MessageId)
+	if len(recent.order) > handledWindow {
+		delete(recent.ids, recent.order[0])
+		recent.order = recent.order[1:]
+	}
+	return true
+}
+
+func (s *Server) serveDriver(context *gin.Context) {
+	u := context.MustGet("user").(*user)
+	conn, err := upgrader.Upgrade(context.Writer, context.Request, nil)
+	if err != nil {
+		return
+	}
+	sess := newSession(conn)
+
+	s.lock.Lock()
+	driver := s.drivers[u.Id]
+	driver.session = sess
+	s.lock.Unlock()
+
+	s.readLoop(u.Id, sess, func(message models.IncomingMessage) {
+		s.handleDriverCommand(driver, sess, message)
+	})
+
+	s.lock.Lock()
+	if driver.session == sess {
+		driver.session = nil
+	}
+	s.lock.Unlock()
+}
+
+func (s *Server) serveCustomer(context *gin.Context) {
+	u := context.MustGet("user").(*user)
+	conn, err := upgrader.Upgrade(context.Writer, context.Request, nil)
+	if err != nil {
+		return
+	}
+	sess := newSession(conn)
+
+	s.lock.Lock()
+	customer := s.customers[u.Id]
+	customer.session = sess
+	s.lock.Unlock()
+
+	s.readLoop(u.Id, sess, func(message models.IncomingMessage) {
+		s.handleCustomerCommand(customer, sess, message)
+	})
+
+	s.lock.Lock()
+	if customer.session == sess {
+		customer.session = nil
+	}
+	s.lock.Unlock()
+}
+
+func (s *Server) readLoop(userId string, sess *session, handle func(message models.IncomingMessage)) {
+	defer sess.stop()
+	defer sess.conn.Close()
+	for {
+		_, raw, err := sess.conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		var message models.IncomingMessage
+		if err := json.Unmarshal(raw, &message); err != nil {
+			log.Printf("mock backend: invalid frame: %v", err)
+			continue
+		}
+		s.lock.Lock()
+		if s.received != nil {
+			s.received[userId] = append(s.received[userId], message)
+		}
+		first := s.firstSeen(userId, message)
+		s.lock.Unlock()
+		sess.ack(message)
+		if first {
+			handle(message)
+		}
+	}
+}
+
+func (s *Server) handleDriverCommand(driver *driverState, sess *session, message models.IncomingMessage) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	switch message.Command {
+	case models.DriverLocation:
+		var payload models.DriverLocationPayload
+		if json.Unmarshal(message.Payload, &payload) != nil {
+			return
+		}
+		driver.lat = payload.RawLocation.Coordinates.Latitude
+		driver.lng = payload.RawLocation.Coordinates.Longitude
+		driver.located = true
+		if t, ok := s.trips[driver.activeTrip]; ok {
+			payload.DriverID = driver.user.Id
+			payload.TripId = t.Id
+			s.customers[t.customerId].session.send(models.DriverLocation, payload)
+		}
+	case models.AcceptTrip:
+		if t := s.offeredTrip(driver, message.Payload); t != nil {
+			s.acceptTrip(driver, t)
+		}
+	case models.RejectTrip:
+		if t := s.offeredTrip(driver, message.Payload); t != nil {
+			s.rejectTrip(driver, t)
+		}
+	case models.ArrivedForPickup:
+		if t := s.assignedTrip(driver, message.Payload); t != nil {
+			driver.activeTrip = t.Id
+			s.updateTrip(t, models.ArrivedForPickup, "arrived")
+		}
+	case models.StartTrip:
+		if t := s.assignedTrip(driver, message.Payload); t != nil {
+			s.updateTrip(t, models.StartTrip, "started")
+		}
+	case models.CompleteTrip:
+		if t := s.assignedTrip(driver, message.Payload); t != nil {
+			s.updateTrip(t, models.CompleteTrip, "completed")
+			driver.activeTrip = ""
+			s.customers[t.customerId].activeTrip = ""
+			sess.send(models.CompleteTrip, models.TripStatusMessage{TripId: t.Id, Status: t.Status})
+		}
+	case models.GoOffline:
+		// the shift ends, an offer still pending goes to the next driver
+		driver.hasShift = false
+		if t, ok := s.trips[driver.pendingTrip]; ok {
+			s.rejectTrip(driver, t)
+		}
+	case models.Sync:
+		sess.send(models.Sync, models.SyncMessage{ActiveTrip: s.tripView(driver.activeTrip)})
+	}
+}
+
+func (s *Server) handleCustomerCommand(customer *customerState, sess *session, message models.IncomingMessage) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	switch message.Command {
+	case models.RequestEstimate:
+		var payload models.TripRequestPayload
+		if json.Unmarshal(message.Payload, &payload) != nil {
+			return
+		}
+		estimate := estimateRoute(payload.Origin.Latitude, payload.Origin.Longitude, payload.Destination.Latitude, payload.Destination.Longitude)
+		sess.send(models.RequestEstimate, models.TripEstimateMessage{
+			Distance: estimate.Distance,
+			Duration: estimate.Duration,
+			Fare:     fare(estimate.Distance),
+			Route:    estimate.Route,
+		})
+	case models.ConfirmTrip:
+		var payload models.TripRequestPayload
+		if json.Unmarshal(message.Payload, &payload) != nil {
+			return
+		}
+		t := &trip{
+			Trip: models.Trip{
+				Id:             newId(),
+				Status:         "searching",
+				OriginLat:      payload.Origin.Latitude,
+				OriginLng:      payload.Origin.Longitude,
+				DestinationLat: payload.Destination.Latitude,
+				DestinationLng: payload.Destination.Longitude,
+				ScheduledAt:    payload.ScheduledAt,
+			},
+			customerId: customer.user.Id,
+			rejectedBy: make(map[string]bool),
+		}
+		s.trips[t.Id] = t
+		customer.activeTrip = t.Id
+		sess.send(models.ConfirmTrip, models.ConfirmTripMessage{Trip: t.Trip})
+		s.dispatch(t)
+	case models.ModifyTrip:
+		var payload models.ModifyTripPayload
+		if json.Unmarshal(message.Payload, &payload) != nil {
+			return
+		}
+		if t, ok := s.trips[payload.TripId]; ok && t.customerId == customer.user.Id {
+			t.ScheduledAt = payload.ScheduledAt
+			sess.send(models.ModifyTrip, models.ConfirmTripMessage{Trip: t.Trip})
+			if driver, ok := s.drivers[t.driverId]; ok {
+				driver.session.send(models.ModifyTrip, models.ConfirmTripMessage{Trip: t.Trip})
+			}
+		}
+	case models.CancelTrip:
+		var payload models.CancelTripPayload
+		if json.Unmarshal(message.Payload, &payload) != nil {
+			return
+		}
+		if t, ok := s.trips[payload.TripId]; ok && t.customerId == customer.user.Id {
+			s.cancelTrip(t)
+		}
+	case models.Sync:
+		sess.send(models.Sync, models.SyncMessage{ActiveTrip: s.tripView(customer.activeTrip)})
+	}
+}
+
+// offeredTrip returns the trip of an accept or reject if it is currently offered to the driver
+func (s *Server) offeredTrip(driver *driverState, raw json.RawMessage) *trip {
+	var payload models.TripActionPayload
+	if json.Unmarshal(raw, &payload) != nil || driver.pendingTrip != payload.TripId {
+		return nil
+	}
+	return s.trips[payload.TripId]
+}
+
+// assignedTrip returns the trip of a trip action if the driver accepted it
+func (s *Server) assignedTrip(driver *driverState, raw json.RawMessage) *trip {
+	var payload models.TripActionPayload
+	if json.Unmarshal(raw, &payload) != nil {
+		return nil
+	}
+	t, ok := s.trips[payload.TripId]
+	if !ok || t.driverId != driver.user.Id {
+		return nil
+	}
+	return t
+}
+
+// updateTrip moves the trip to status and tells the customer
+func (s *Server) updateTrip(t *trip, command models.Command, status string) {
+	t.Status = status
+	s.customers[t.customerId].session.send(command, models.TripStatusMessage{TripId: t.Id, Status: status})
+}
+
+func (s *Server) cancelTrip(t *trip) {
+	if t.offerTimer != nil {
+		t.offerTimer.Stop()
+	}
+	t.Status = "cancelled"
+	cancellation := models.TripStatusMessage{TripId: t.Id, Status: t.Status}
+	for _, driver := range s.drivers {
+		if driver.pendingTrip == t.Id || t.driverId == driver.user.Id {
+			if driver.pendingTrip == t.Id {
+				driver.pendingTrip = ""
+			}
+			if driver.activeTrip == t.Id {
+				driver.activeTrip = ""
+			}
+			driver.session.send(models.CancelTrip, cancellation)
+		}
+	}
+	customer := s.customers[t.customerId]
+	if customer.activeTrip == t.Id {
+		customer.activeTrip = ""
+	}
+	customer.session.send(models.CancelTrip, cancellation)
+}
+
+func (s *Server) tripView(tripId string) *models.Trip {
+	t, ok := s.trips[tripId]
+	if !ok {
+		return nil
+	}
+	view := t.Trip
+	return &view
+}
@@ -0,0 +1,229 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+)
+
+var (
+	ErrUnknownCommand = errors.New("unknown command")
+	ErrMissingData    = errors.New("message has no data")
+)
+
+// ServerMessage represents a frame pushed by the backend over the websocket.
+type ServerMessage struct {
+	Command Command         `json:"command"`
+	Status  bool            `json:"status"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data"`
+}
+
+// NewTripOfferMessage is sent to a driver when a trip is offered to them.
+type NewTripOfferMessage struct {
+	TripOffer      TripOffer     `json:"trip_offer"`
+	PickupEstimate RouteEstimate `json:"pickup_estimate"`
+	TripEstimate   RouteEstimate `json:"trip_estimate"`
+}
+
+func (m NewTripOfferMessage) Validate() error {
+	return validation.ValidateStruct(&m,
+		validation.Field(&m.TripOffer),
+		validation.Field(&m.PickupEstimate),
+		validation.Field(&m.TripEstimate),
+	)
+}
+
+type TripOffer struct {
+	TripId string `json:"trip_id"`
+	Trip   Trip   `json:"trip"`
+}
+
+func (o TripOffer) Validate() error {
+	return validation.ValidateStruct(&o,
+		validation.Field(&o.TripId, validation.Required),
+		validation.Field(&o.Trip),
+	)
+}
+
+// Trip represents the trip details shared with both sides of a trip.
+type Trip struct {
+	Id             string  `json:"id"`
+	Status         string  `json:"status,omitempty"`
+	OriginLat      float64 `json:"origin_lat"`
+	OriginLng      float64 `json:"origin_lng"`
+	DestinationLat float64 `json:"destination_lat"`
+	DestinationLng float64 `json:"destination_lng"`
+	// ScheduledAt is the pickup time in unix seconds, zero for an immediate trip
+	ScheduledAt int64 `json:"scheduled_at,omitempty"`
+}
+
+func (t Trip) Validate() error {
+	return validation.ValidateStruct(&t,
+		validation.Field(&t.OriginLat, validation.Required),
+		validation.Field(&t.OriginLng, validation.Required),
+		validation.Field(&t.DestinationLat, validation.Required),
+		validation.Field(&t.DestinationLng, validation.Required),
+	)
+}
+
+// ConfirmTripMessage is sent to a customer once their trip is booked.
+type ConfirmTripMessage struct {
+	Trip
+}
+
+func (m ConfirmTripMessage) Validate() error {
+	return validation.ValidateStruct(&m,
+		validation.Field(&m.Id, validation.Required),
+	)
+}
+
+type RouteEstimate struct {
+	Distance float64 `json:"distance,omitempty"`
+	Duration float64 `json:"duration,omitempty"`
+	Route    Route   `json:"route"`
+}
+
+func (e RouteEstimate) Validate() error {
+	return validation.ValidateStruct(&e,
+		validation.Field(&e.Route),
+	)
+}
+
+type Route struct {
+	Polyline Polyline `json:"polyline"`
+}
+
+func (r Route) Validate() error {
+	return validation.ValidateStruct(&r,
+		validation.Field(&r.Polyline),
+	)
+}
+
+type Polyline struct {
+	EncodedPolyline string `json:"encodedPolyline"`
+}
+
+func (p Polyline) Validate() error {
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.EncodedPolyline, validation.Required),
+	)
+}
+
+// TripEstimateMessage is the answer to a requestEstimate.
+type TripEstimateMessage struct {
+	Distance float64 `json:"distance,omitempty"`
+	Duration float64 `json:"duration,omitempty"`
+	Fare     float64 `json:"fare,omitempty"`
+	Route    Route   `json:"route"`
+}
+
+// EtaMessage carries the remaining time and distance until pickup or drop off.
+type EtaMessage struct {
+	TripId   string  `json:"trip_id"`
+	Distance float64 `json:"distance"`
+	Duration float64 `json:"duration"`
+}
+
+// DriverLocationMessage relays the assigned driver position to a customer.
+type DriverLocationMessage struct {
+	DriverLocationPayload
+}
+
+// TripStatusMessage is sent for trip lifecycle events like completion or cancellation.
+type TripStatusMessage struct {
+	TripId string `json:"trip_id,omitempty"`
+	Id     string `json:"id,omitempty"`
+	Status string `json:"status,omitempty"`
+}
+
+// TripID returns the trip id whichever key the backend used for it.
+func (m TripStatusMessage) TripID() string {
+	if m.TripId != "" {
+		return m.TripId
+	}
+	return m.Id
+}
+
+// RerouteMessage carries a new route for a trip in progress.
+type RerouteMessage struct {
+	TripId string `json:"trip_id"`
+	Route  Route  `json:"route"`
+}
+
+type CancellationReason struct {
+	Id     int    `json:"id"`
+	Reason string `json:"reason"`
+}
+
+type CancellationReasonsMessage struct {
+	Reasons []CancellationReason `json:"reasons"`
+}
+
+// AckMessage acknowledges a command sent by the simulator.
+type AckMessage struct {
+	MessageId string  `json:"message_id,omitempty"`
+	Command   Command `json:"command,omitempty"`
+}
+
+// SyncMessage carries the backend view of the actor after a sync request.
+type SyncMessage struct {
+	ActiveTrip *Trip `json:"active_trip,omitempty"`
+}
+
+// LocationUpdateMessage is a generic location push, used by the backend for customer positions.
+type LocationUpdateMessage struct {
+	Location LatLong `json:"location"`
+}
+
+// serverMessageTypes maps each command to the typed payload it is decoded into.
+var serverMessageTypes = map[Command]func() interface{}{
+	NewTripOffer:         func() interface{} { return &NewTripOfferMessage{} },
+	RequestEstimate:      func() interface{} { return &TripEstimateMessage{} },
+	ConfirmTrip:          func() interface{} { return &ConfirmTripMessage{} },
+	ModifyTrip:           func() interface{} { return &ConfirmTripMessage{} },
+	Eta:                  func() interface{} { return &EtaMessage{} },
+	DriverLocation:       func() interface{} { return &DriverLocationMessage{} },
+	LocationUpdate:       func() interface{} { return &LocationUpdateMessage{} },
+	AcceptTrip:           func() interface{} { return &TripStatusMessage{} },
+	RejectTrip:           func() interface{} { return &TripStatusMessage{} },
+	ArrivedForPickup:     func() interface{} { return &TripStatusMessage{} },
+	StartTrip:            func() interface{} { return &TripStatusMessage{} },
+	CompleteTrip:         func() interface{} { return &TripStatusMessage{} },
+	CancelTrip:           func() interface{} { return &TripStatusMessage{} },
+	NoDriverFound:        func() interface{} { return &TripStatusMessage{} },
+	NoDriverAcceptedTrip: func() interface{} { return &TripStatusMessage{} },
+	TripTimedOut:         func() interface{} { return &TripStatusMessage{} },
+	Reroute:              func() interface{} { return &RerouteMessage{} },
+	CancellationReasons:  func() interface{} { return &CancellationReasonsMessage{} },
+	Ack:                  func() interface{} { return &AckMessage{} },
+	Sync:                 func() interface{} { return &SyncMessage{} },
+}
+
+// DecodeServerMessage parses a backend frame and its data into the typed payload for its command.
+// The envelope is always returned when it could be parsed, so callers can log the command and message.
+func DecodeServerMessage(raw []byte) (*ServerMessage, interface{}, error) {
+	var message ServerMessage
+	if err := json.Unmarshal(raw, &message); err != nil {
+		return nil, nil, err
+	}
+	newPayload, ok := serverMessageTypes[message.Command]
+	if !ok {
+		return &message, nil, fmt.Errorf("%w: %q", ErrUnknownCommand, message.Command)
+	}
+	if len(message.Data) == 0 || string(message.Data) == "null" {
+		return &message, nil, fmt.Errorf("%s: %w: %s", message.Command, ErrMissingData, message.Message)
+	}
+	payload := newPayload()
+	if err := json.Unmarshal(message.Data, payload); err != nil {
+		return &message, nil, fmt.Errorf("%s: %w", message.Command, err)
+	}
+	if v, ok := payload.(validation.Validatable); ok {
+		if err := v.Validate(); err != nil {
+			return &message, nil, fmt.Errorf("%s: %w", message.Command, err)
+		}
+	}
+	return &message, payload, nil
+}
@@ -0,0 +1,12 @@
+package models
+
+import "testing"
+
+func TestFloatBetweenZeroToOne(t *testing.T) {
+	// a rate of one is compared with <, rounding up to 1.00 would fail it one time in two hundred
+	for i := 0; i < 10000; i++ {
+		if f := FloatBetweenZeroToOne(); f < 0 || f >= 1 {
+			t.Fatalf("draw %d is %v", i, f)
+		}
+	}
+}
@@ -22,6 +22,7 @@ const (
 	NoDriverFound        Command = "noDriverFound"
 	NoDriverAcceptedTrip Command = "noDriverAcceptedTrip"
 	CancelTrip           Command = "cancelTrip"
+	ModifyTrip           Command = "modifyTrip"
 	CancellationReasons  Command = "cancellationReasons"
 	RateDriver           Command = "rateDriver"
 	RateCustomer         Command = "rateCustomer"
@@ -36,8 +37,9 @@ const (
 
 // IncomingMessage represents to fetch only the command from the incoming.
 type IncomingMessage struct {
-	Command Command         `json:"command,omitempty"`
-	Payload json.RawMessage `json:"payload"`
+	Command   Command         `json:"command,omitempty"`
+	MessageId string          `json:"message_id,omitempty"`
+	Payload   json.RawMessage `json:"payload"`
 }
 
 // DriverLocationPayload represents the incoming location data from drivers.
@@ -85,6 +87,8 @@ type TripRequestPayload struct {
 	Destination       LatLong `json:"destination" validate:"required"`
 	DestinationName   string  `json:"destination_name"`
 	VehicleCategoryId int     `json:"category_id,omitempty"`
+	// ScheduledAt is the requested pickup time in unix seconds, zero for an immediate trip
+	ScheduledAt int64 `json:"scheduled_at,omitempty"`
 }
 
 type LatLong struct {
@@ -97,6 +101,12 @@ type CancelTripPayload struct {
 	ReasonId int    `json:"reason_id" validate:"required"`
 }
 
+// ModifyTripPayload represents a change to the pickup time of a scheduled trip
+type ModifyTripPayload struct {
+	TripId      string `json:"trip_id" validate:"required"`
+	ScheduledAt int64  `json:"scheduled_at" validate:"required"`
+}
+
 type TripRatingPayload struct {
 	TripId string  `json:"trip_id" validate:"required"`
 	Rating float64 `json:"rating" validate:"required"`
@@ -0,0 +1,230 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"sim-server/config"
+	"sim-server/internal/models"
+)
+
+const (
+	requestTimeout      = 15 * time.Second // per attempt, the caller's context bounds the whole call
+	maxAttempts         = 4
+	initialRetryDelay   = 200 * time.Millisecond
+	maxRetryDelay       = 5 * time.Second
+	maxIdleConns        = 512
+	maxIdleConnsPerHost = 256
+	idleConnTimeout     = 90 * time.Second
+)
+
+// HTTPError is a response from the backend with an unexpected status code
+type HTTPError struct {
+	StatusCode int
+	Message    string
+	retryAfter time.Duration
+}
+
+func (e *HTTPError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("backend responded %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+	}
+	return fmt.Sprintf("backend responded %d: %s", e.StatusCode, e.Message)
+}
+
+// BackendClient calls the REST API of one target. It is safe for concurrent use and
+// shares its pooled connections between all the actors pointed at the target.
+type BackendClient struct {
+	target config.Target
+	client *http.Client
+}
+
+var (
+	backendClients     = make(map[string]*BackendClient)
+	backendClientsLock sync.Mutex
+)
+
+// Backend returns the shared client of target, creating it on first use.
+func Backend(target config.Target) (*BackendClient, error) {
+	key := target.Name + " " + target.BaseURL
+	backendClientsLock.Lock()
+	defer backendClientsLock.Unlock()
+	if client, ok := backendClients[key]; ok {
+		return client, nil
+	}
+
+	tlsConfig, err := target.TLSConfig()
+	if err != nil {
+		return nil, err
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	transport.MaxIdleConns = maxIdleConns
+	transport.MaxIdleConnsPerHost = maxIdleConnsPerHost
+	transport.IdleConnTimeout = idleConnTimeout
+
+	client := &BackendClient{target: target, client: &http.Client{Transport: transport}}
+	backendClients[key] = client
+	return client, nil
+}
+
+// idempotentMethods leave the backend as they found it however often they arrive
+var idempotentMethods = map[string]bool{
+	http.MethodGet:     true,
+	http.MethodHead:    true,
+	http.MethodOptions: true,
+	http.MethodPut:     true,
+	http.MethodDelete:  true,
+}
+
+// Do sends a JSON request and decodes the common response envelope. A non empty token is sent as
+// the bearer token. Transient failures of idempotent methods are retried with a jittered exponential
+// backoff, other requests are sent once: a failure does not tell whether the backend acted on them,
+// such as a go_online whose response was lost after the shift started. See DoIdempotent.
+func (c *BackendClient) Do(ctx context.Context, method, path, token string, body interface{}) (*models.CommonResponse, error) {
+	return c.do(ctx, idempotentMethods[method], method, path, token, body)
+}
+
+// DoIdempotent is Do for a request the backend answers the same however often it arrives, such as
+// a login, it is retried whatever its method
+func (c *BackendClient) DoIdempotent(ctx context.Context, method, path, token string, body interface{}) (*models.CommonResponse, error) {
+	return c.do(ctx, true, method, path, token, body)
+}
+
+func (c *BackendClient) do(ctx context.Context, retry bool, method, path, token string, body interface{}) (*models.CommonResponse, error) {
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return nil, err
+		}
+	}
+
+	var err error
+	for attempt := 0; attempt < maxAttempts; attempt++ {
+		if attempt > 0 {
+			delay := retryDelay(attempt, err)
+			log.Printf("%s %s failed, retrying in %v: %v", method, path, delay, err)
+			select {
+			case <-time.After(delay):
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+
+		var response *models.CommonResponse
+		response, err = c.attempt(ctx, method, path, token, payload)
+		if err == nil {
+			return response, nil
+		}
+		if !retry || !retryable(ctx, err) {
+			return nil, err
+		}
+	}
+	return nil, err
+}
+
+func (c *BackendClient) attempt(ctx context.Context, method, path, token string, payload []byte) (*models.CommonResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+
+	var body io.Reader
+	if payload != nil {
+		body = bytes.NewReader(payload)
+	}
+	request, err := http.NewRequestWithContext(ctx, method, strings.TrimSuffix(c.target.BaseURL, "/")+path, body)
+	if err != nil {
+		return nil, err
+	}
+	if payload != nil {
+		request.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		request.Header.Set("Authorization", "Bearer "+token)
+	}
+	for key, value := range c.target.Headers {
+		request.Header.Set(key, value)
+	}
+
+	resp, err := c.client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	//Read the response
+	responseBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	var commonResponse models.CommonResponse
+	decodeErr := json.Unmarshal(responseBody, &commonResponse)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, &HTTPError{
+			StatusCode: resp.StatusCode,
+			Message:    commonResponse.Message,
+			retryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+		}
+	}
+	if decodeErr != nil {
+		return nil, fmt.Errorf("parsing response body: %w", decodeErr)
+	}
+	return &commonResponse, nil
+}
+
+// retryable tells transient failures, worth another attempt, from ones that would fail again
+func retryable(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		switch httpErr.StatusCode {
+		case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return true
+		}
+		return false
+	}
+	var certErr *tls.CertificateVerificationError
+	var authorityErr x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	if errors.As(err, &certErr) || errors.As(err, &authorityErr) || errors.As(err, &hostnameErr) {
+		return false
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, context.DeadlineExceeded)
+}
+
+// retryDelay doubles on every attempt up to maxRetryDelay with half of it randomized,
+// unless the backend asked for a specific delay
+func retryDelay(attempt int, err error) time.Duration {
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) && httpErr.retryAfter > 0 {
+		return min(httpErr.retryAfter, maxRetryDelay)
+	}
+	delay := min(initialRetryDelay<<(attempt-1), maxRetryDelay)
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)))
+}
+
+func parseRetryAfter(value string) time.Duration {
+	seconds, err := strconv.Atoi(value)
+	if err != nil || seconds < 0 {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
@@ -0,0 +1,155 @@
+package services
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"sim-server/config"
+)
+
+func TestRetryable(t *testing.T) {
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	tests := []struct {
+		name      string
+		ctx       context.Context
+		err       error
+		retryable bool
+	}{
+		{name: "too many requests", err: &HTTPError{StatusCode: 429}, retryable: true},
+		{name: "bad gateway", err: &HTTPError{StatusCode: 502}, retryable: true},
+		{name: "unavailable", err: &HTTPError{StatusCode: 503}, retryable: true},
+		{name: "gateway timeout", err: &HTTPError{StatusCode: 504}, retryable: true},
+		{name: "wrapped status", err: fmt.Errorf("login: %w", &HTTPError{StatusCode: 503}), retryable: true},
+		{name: "bad request", err: &HTTPError{StatusCode: 400}},
+		{name: "unauthorized", err: &HTTPError{StatusCode: 401}},
+		{name: "internal error", err: &HTTPError{StatusCode: 500}},
+		{name: "connection refused", err: &url.Error{Op: "Post", URL: "http://backend", Err: &net.OpError{Op: "dial", Err: errors.New("connection refused")}}, retryable: true},
+		{name: "connection dropped", err: io.ErrUnexpectedEOF, retryable: true},
+		{name: "closed early", err: fmt.Errorf("reading: %w", io.EOF), retryable: true},
+		{name: "attempt timed out", err: context.DeadlineExceeded, retryable: true},
+		{name: "caller gave up", ctx: cancelled, err: &HTTPError{StatusCode: 503}},
+		{name: "unknown authority", err: &url.Error{Op: "Post", URL: "https://backend", Err: &tls.CertificateVerificationError{Err: x509.UnknownAuthorityError{}}}},
+		{name: "wrong host", err: x509.HostnameError{Host: "backend"}},
+		{name: "unparsable body", err: fmt.Errorf("parsing response body: %w", errors.New("invalid character"))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := tt.ctx
+			if ctx == nil {
+				ctx = context.Background()
+			}
+			if retryable := retryable(ctx, tt.err); retryable != tt.retryable {
+				t.Errorf("retryable(%v) = %v, want %v", tt.err, retryable, tt.retryable)
+			}
+		})
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	tests := []struct {
+		name     string
+		attempt  int
+		err      error
+		min, max time.Duration // max excluded, unless it equals min
+	}{
+		{name: "first retry", attempt: 1, err: io.EOF, min: initialRetryDelay / 2, max: initialRetryDelay},
+		{name: "doubles", attempt: 3, err: io.EOF, min: 2 * initialRetryDelay, max: 4 * initialRetryDelay},
+		{name: "capped", attempt: 10, err: io.EOF, min: maxRetryDelay / 2, max: maxRetryDelay},
+		{name: "status without retry after", attempt: 1, err: &HTTPError{StatusCode: 503}, min: initialRetryDelay / 2, max: initialRetryDelay},
+		{name: "retry after", attempt: 1, err: &HTTPError{StatusCode: 429, retryAfter: 2 * time.Second}, min: 2 * time.Second, max: 2 * time.Second},
+		{name: "retry after capped", attempt: 1, err: &HTTPError{StatusCode: 429, retryAfter: time.Minute}, min: maxRetryDelay, max: maxRetryDelay},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i := 0; i < 100; i++ {
+				d := retryDelay(tt.attempt, tt.err)
+				if d < tt.min || d > tt.max || d == tt.max && tt.min != tt.max {
+					t.Fatalf("retrying after %v, want within [%v, %v)", d, tt.min, tt.max)
+				}
+			}
+		})
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	tests := map[string]time.Duration{
+		"3":                             3 * time.Second,
+		"0":                             0,
+		"":                              0,
+		"-1":                            0,
+		"Wed, 21 Oct 2015 07:28:00 GMT": 0,
+	}
+	for value, want := range tests {
+		if got := parseRetryAfter(value); got != want {
+			t.Errorf("Retry-After %q parsed as %v, want %v", value, got, want)
+		}
+	}
+}
+
+// TestDroppedResponse drops the connection of the first request to every path after reading it,
+// as when the backend acted on a request but its response was lost
+func TestDroppedResponse(t *testing.T) {
+	var lock sync.Mutex
+	hits := make(map[string]int)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		hits[r.URL.Path]++
+		first := hits[r.URL.Path] == 1
+		lock.Unlock()
+		if first {
+			conn, _, err := w.(http.Hijacker).Hijack()
+			if err == nil {
+				conn.Close()
+			}
+			return
+		}
+		w.Write([]byte(`{"status":true}`))
+	}))
+	defer server.Close()
+	client, err := Backend(config.Target{Name: t.Name(), BaseURL: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		method     string
+		idempotent bool
+		hits       int
+	}{
+		{name: "get", method: http.MethodGet, hits: 2},
+		{name: "put", method: http.MethodPut, hits: 2},
+		{name: "post", method: http.MethodPost, hits: 1},
+		{name: "marked-post", method: http.MethodPost, idempotent: true, hits: 2},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			do := client.Do
+			if test.idempotent {
+				do = client.DoIdempotent
+			}
+			path := "/" + test.name
+			_, err := do(context.Background(), test.method, path, "", nil)
+			if (err == nil) != (test.hits > 1) {
+				t.Errorf("error %v after %d requests", err, test.hits)
+			}
+			lock.Lock()
+			defer lock.Unlock()
+			if hits[path] != test.hits {
+				t.Errorf("sent %d times, want %d", hits[path], test.hits)
+			}
+		})
+	}
+}
@@ -2,16 +2,60 @@ package services
 
 import (
 	"context"
+	"errors"
 	"sim-server/database"
+	"strings"
+	"sync"
 	"time"
+
+	"github.com/go-redis/redis/v8"
 )
 
-func Set(key string, message []byte) error {
-	return database.RedisClient.Set(context.Background(), key, message, 0*time.Minute).Err()
+// localRegistry stands in for Redis when no client was initialised, e.g. in tests
+var localRegistry sync.Map
+
+type localEntry struct {
+	value   string
+	expires time.Time // zero when the entry never expires
+}
+
+// Set stores message under key, expiring it after ttl unless ttl is zero
+func Set(key string, message []byte, ttl time.Duration) error {
+	if database.RedisClient == nil {
+		entry := localEntry{value: string(message)}
+		if ttl > 0 {
+			entry.expires = time.Now().Add(ttl)
+		}
+		localRegistry.Store(key, entry)
+		return nil
+	}
+	return database.RedisClient.Set(context.Background(), key, message, ttl).Err()
+}
+
+// Delete removes key, it is not an error when the key does not exist
+func Delete(key string) error {
+	if database.RedisClient == nil {
+		localRegistry.Delete(key)
+		return nil
+	}
+	return database.RedisClient.Del(context.Background(), key).Err()
 }
 
 // CheckAndGetKey to check if a key exists in Redis
 func CheckAndGetKey(key string) (string, bool) {
+	if database.RedisClient == nil {
+		value, ok := localRegistry.Load(key)
+		if !ok {
+			return "", false
+		}
+		entry := value.(localEntry)
+		if !entry.expires.IsZero() && time.Now().After(entry.expires) {
+			localRegistry.CompareAndDelete(key, entry)
+			return "", false
+		}
+		return entry.value, true
+	}
+
 	// Use EXISTS to check if the key exists
 	exists, err := database.RedisClient.Exists(context.Background(), key).Result()
 	if err != nil {
@@ -30,3 +74,33 @@ func CheckAndGetKey(key string) (string, bool) {
 
 	return value, true
 }
+
+// List returns the value of every key starting with prefix, by key
+func List(prefix string) (map[string]string, error) {
+	values := make(map[string]string)
+	if database.RedisClient == nil {
+		localRegistry.Range(func(key, _ interface{}) bool {
+			if k := key.(string); strings.HasPrefix(k, prefix) {
+				if value, ok := CheckAndGetKey(k); ok {
+					values[k] = value
+				}
+			}
+			return true
+		})
+		return values, nil
+	}
+
+	ctx := context.Background()
+	iter := database.RedisClient.Scan(ctx, 0, prefix+"*", 0).Iterator()
+	for iter.Next(ctx) {
+		value, err := database.RedisClient.Get(ctx, iter.Val()).Result()
+		if errors.Is(err, redis.Nil) {
+			continue // expired since the scan
+		}
+		if err != nil {
+			return nil, err
+		}
+		values[iter.Val()] = value
+	}
+	return values, iter.Err()
+}
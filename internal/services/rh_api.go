@@ -1,161 +1,60 @@
 package services
 
 import (
-	"bytes"
-	"encoding/json"
+	"context"
 	"errors"
-	"io"
-	"log"
 	"net/http"
-	"net/url"
+	"sim-server/config"
 	"sim-server/internal/models"
 	"time"
 )
 
 const (
-	scheme      = "https"
-	host        = "rh-core.advantium.in"
 	backdoorOtp = "1234"
 )
 
-func DriverLogin(phoneNumber string) (*models.CommonResponse, error) {
-	address := url.URL{Scheme: scheme, Host: host, Path: "/api/v1/admin/simulate/driver"}
-	payload, err := json.Marshal(map[string]interface{}{"phone_number": phoneNumber})
-	if err != nil {
-		return &models.CommonResponse{}, err
-	}
-	request, err := http.NewRequest("POST", address.String(), bytes.NewBuffer(payload))
-	if err != nil {
-		log.Println("Error creating HTTP request:", err)
-		return &models.CommonResponse{}, err
-	}
-	request.Header.Set("MRSOOL-CLIENT", "Simulation")
-	client := &http.Client{Timeout: 100 * time.Second}
-	resp, err := client.Do(request)
-	if err != nil {
-		log.Printf("Error sending request : %v", err)
-		return &models.CommonResponse{}, err
-	}
-	defer resp.Body.Close()
-
-	//Read the response
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		log.Println("Error reading response body:", err)
-		return &models.CommonResponse{}, err
-	}
-	var commonResponse models.CommonResponse
-	err = json.Unmarshal(body, &commonResponse)
-	if err != nil {
-		log.Println("Error parsing response body:", err)
-		return &models.CommonResponse{}, err
-	}
-	return &commonResponse, nil
+func DriverLogin(ctx context.Context, target config.Target, phoneNumber string) (*models.CommonResponse, error) {
+	return simulateLogin(ctx, target, "driver", phoneNumber)
 }
 
-func CustomerLogin(phoneNumber string) (*models.CommonResponse, error) {
-	address := url.URL{Scheme: scheme, Host: host, Path: "/api/v1/admin/simulate/customer"}
-	payload, err := json.Marshal(map[string]interface{}{"phone_number": phoneNumber})
-	if err != nil {
-		return &models.CommonResponse{}, err
-	}
-	request, err := http.NewRequest("POST", address.String(), bytes.NewBuffer(payload))
-	if err != nil {
-		log.Println("Error creating HTTP request:", err)
-		return &models.CommonResponse{}, err
-	}
-	request.Header.Set("MRSOOL-CLIENT", "Simulation")
-	client := &http.Client{Timeout: 100 * time.Second}
-	resp, err := client.Do(request)
-	if err != nil {
-		log.Printf("Error sending request : %v", err)
-		return &models.CommonResponse{}, err
-	}
-	defer resp.Body.Close()
-
-	//Read the response
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		log.Println("Error reading response body:", err)
-		return &models.CommonResponse{}, err
-	}
-	var commonResponse models.CommonResponse
-	err = json.Unmarshal(body, &commonResponse)
-	if err != nil {
-		log.Println("Error parsing response body:", err)
-		return &models.CommonResponse{}, err
-	}
-	return &commonResponse, nil
+func CustomerLogin(ctx context.Context, target config.Target, phoneNumber string) (*models.CommonResponse, error) {
+	return simulateLogin(ctx, target, "customer", phoneNumber)
 }
 
-func CheckShiftStatus(token string) (*models.CommonResponse, error) {
-	address := url.URL{Scheme: scheme, Host: host, Path: "/api/v1/driver/shift_status"}
-	request, err := http.NewRequest("GET", address.String(), nil)
-	if err != nil {
-		log.Println("Error creating HTTP request:", err)
-		return nil, err
-	}
-	request.Header.Set("Authorization", "Bearer "+token)
-	request.Header.Set("MRSOOL-CLIENT", "Simulation")
-	client := &http.Client{Timeout: 100 * time.Second}
-	resp, err := client.Do(request)
-	if err != nil {
-		log.Printf("Error sending request : %v", err)
-		return nil, err
-	}
-	defer resp.Body.Close()
+func CheckShiftStatus(ctx context.Context, target config.Target, token string) (*models.CommonResponse, error) {
+	return call(ctx, target, http.MethodGet, "/api/v1/driver/shift_status", token, nil)
+}
 
-	//Read the response
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		log.Println("Error reading response body:", err)
-		return nil, err
-	}
-	var commonResponse models.CommonResponse
-	err = json.Unmarshal(body, &commonResponse)
-	if err != nil {
-		log.Println("Error parsing response body:", err)
-		return nil, err
-	}
-	if commonResponse.Status {
-		return &commonResponse, nil
-	} else {
-		return nil, errors.New(commonResponse.Message)
-	}
+func StartNewShift(ctx context.Context, target config.Target, token string) (*models.CommonResponse, error) {
+	return call(ctx, target, http.MethodPost, "/api/v1/driver/go_online", token, nil)
 }
 
-func StartNewShift(token string) (*models.CommonResponse, error) {
-	address := url.URL{Scheme: scheme, Host: host, Path: "/api/v1/driver/go_online"}
-	request, err := http.NewRequest("POST", address.String(), nil)
+// simulateLogin returns the response even when the backend refused the login, callers check its status
+func simulateLogin(ctx context.Context, target config.Target, role, phoneNumber string) (*models.CommonResponse, error) {
+	client, err := Backend(target)
 	if err != nil {
-		log.Println("Error creating HTTP request:", err)
 		return nil, err
 	}
-	request.Header.Set("Authorization", "Bearer "+token)
-	request.Header.Set("MRSOOL-CLIENT", "Simulation")
-	client := &http.Client{Timeout: 100 * time.Second}
-	resp, err := client.Do(request)
-	if err != nil {
-		log.Printf("Error sending request : %v", err)
-		return nil, err
-	}
-	defer resp.Body.Close()
+	started := time.Now()
+	// logging in again answers with the same user
+	response, err := client.DoIdempotent(ctx, http.MethodPost, "/api/v1/admin/simulate/"+role, "",
+		map[string]interface{}{"phone_number": phoneNumber})
+	loginStats.record(role+"_simulate", started, err == nil && response.Status)
+	return response, err
+}
 
-	//Read the response
-	body, err := io.ReadAll(resp.Body)
+// call fails with the message of the backend when the response status is false
+func call(ctx context.Context, target config.Target, method, path, token string, body interface{}) (*models.CommonResponse, error) {
+	client, err := Backend(target)
 	if err != nil {
-		log.Println("Error reading response body:", err)
 		return nil, err
 	}
-	log.Printf("%v", body)
-	var commonResponse models.CommonResponse
-	err = json.Unmarshal(body, &commonResponse)
+	commonResponse, err := client.Do(ctx, method, path, token, body)
 	if err != nil {
-		log.Println("Error parsing response body:", err)
 		return nil, err
 	}
 	if commonResponse.Status {
-		return &commonResponse, nil
+		return commonResponse, nil
 	} else {
 		return nil, errors.New(commonResponse.Message)
 	}
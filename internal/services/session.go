@@ -0,0 +1,91 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+
+	"sim-server/config"
+	"sim-server/internal/models"
+)
+
+// LoginFunc logs an actor in by phone number, like DriverLogin and CustomerLogin
+type LoginFunc func(ctx context.Context, target config.Target, phoneNumber string) (*models.CommonResponse, error)
+
+// Session holds the access token of one actor and logs in again once the backend rejects it,
+// so long running actors survive token expiry.
+type Session struct {
+	target      config.Target
+	phoneNumber string
+	login       LoginFunc
+
+	lock  sync.Mutex
+	token string
+}
+
+func NewSession(target config.Target, phoneNumber, token string, login LoginFunc) *Session {
+	return &Session{
+		target:      target,
+		phoneNumber: phoneNumber,
+		login:       login,
+		token:       token,
+	}
+}
+
+// Token returns the current access token.
+func (s *Session) Token() string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.token
+}
+
+// Refresh logs in again after the backend rejected the token. When another caller already
+// replaced the rejected token the current one is returned without logging in again.
+func (s *Session) Refresh(ctx context.Context, rejected string) (string, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.token != rejected {
+		return s.token, nil
+	}
+
+	response, err := s.login(ctx, s.target, s.phoneNumber)
+	if err != nil {
+		return "", err
+	}
+	if !response.Status {
+		return "", errors.New(response.Message)
+	}
+	data, ok := response.Data.(map[string]interface{})
+	if !ok {
+		return "", fmt.Errorf("unexpected login response data: %v", response.Data)
+	}
+	token, _ := data["access_token"].(string)
+	if token == "" {
+		return "", errors.New("login response has no access token")
+	}
+	s.token = token
+	log.Printf("logged in %s again after its token was rejected", s.phoneNumber)
+	return token, nil
+}
+
+// Call runs request with the current token, logging in again and retrying once if it was rejected.
+func (s *Session) Call(ctx context.Context, request func(token string) (*models.CommonResponse, error)) (*models.CommonResponse, error) {
+	token := s.Token()
+	response, err := request(token)
+	if !IsUnauthorized(err) {
+		return response, err
+	}
+	if token, err = s.Refresh(ctx, token); err != nil {
+		return nil, err
+	}
+	return request(token)
+}
+
+// IsUnauthorized reports whether the backend rejected the access token of a request.
+func IsUnauthorized(err error) bool {
+	var httpErr *HTTPError
+	return errors.As(err, &httpErr) && httpErr.StatusCode == http.StatusUnauthorized
+}
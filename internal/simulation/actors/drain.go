@@ -0,0 +1,131 @@
+package actors
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// drainPoll is how often Shutdown checks whether the actors finished their trips
+const drainPoll = 250 * time.Millisecond
+
+// drainer is implemented by the simulated drivers and customers, the methods run in their mailbox
+type drainer interface {
+	// Drain makes the actor take on no new trip, the trip in progress goes on
+	Drain()
+	// Drained reports whether the actor is done with its trips
+	Drained() bool
+	// SignOff ends the trip the actor is still on and takes it off the backend
+	SignOff()
+}
+
+// Shutdown drains every actor of the process and stops them. The actors get until ctx is done to
+// finish the trips in progress, then they sign off whatever is left. No actor is restarted afterwards.
+// It returns the actors that failed to drain or to sign off by id, they may still be on a trip.
+func Shutdown(ctx context.Context) map[string]error {
+	// an actor crashing during the drain would otherwise be restarted, go online and miss the drain
+	running := runningSupervisors()
+	for _, s := range running {
+		s.Drain()
+	}
+
+	var lock sync.Mutex
+	failed := make(map[string]error)
+	fail := func(actor *Actor, step string, err error) {
+		log.Printf("%s: failed to %s: %v", actor, step, err)
+		lock.Lock()
+		defer lock.Unlock()
+		if _, ok := failed[actor.Id]; !ok {
+			failed[actor.Id] = fmt.Errorf("%s: %w", step, err)
+		}
+	}
+
+	all := All("")
+	var wg sync.WaitGroup
+	for _, actor := range all {
+		// paused actors would hold the drain up until the deadline
+		actor.Resume()
+		d, ok := actor.Server.(drainer)
+		if !ok {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			callCtx, cancel := context.WithTimeout(context.Background(), PingTimeout)
+			defer cancel()
+			if err := actor.Call(callCtx, d.Drain); err != nil {
+				fail(actor, "drain", err)
+			}
+		}()
+	}
+	wg.Wait()
+	log.Printf("Draining %d actors", len(all))
+
+	ticker := time.NewTicker(drainPoll)
+	defer ticker.Stop()
+wait:
+	for !drained(all) {
+		select {
+		case <-ctx.Done():
+			log.Printf("Drain deadline passed, signing off the trips in progress")
+			break wait
+		case <-ticker.C:
+		}
+	}
+
+	for _, actor := range All("") {
+		d, ok := actor.Server.(drainer)
+		if !ok {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			callCtx, cancel := context.WithTimeout(context.Background(), PingTimeout)
+			defer cancel()
+			if err := actor.Call(callCtx, d.SignOff); err != nil {
+				fail(actor, "sign off", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	for _, s := range running {
+		s.Stop()
+	}
+	for _, actor := range All("") {
+		actor.Stop()
+	}
+	return failed
+}
+
+func runningSupervisors() []*Supervisor {
+	supervisorsLock.RLock()
+	defer supervisorsLock.RUnlock()
+	running := make([]*Supervisor, 0, len(supervisors))
+	for _, s := range supervisors {
+		running = append(running, s)
+	}
+	return running
+}
+
+// drained reports whether every actor still running is done with its trips
+func drained(all []*Actor) bool {
+	for _, actor := range all {
+		d, ok := actor.Server.(drainer)
+		if !ok {
+			continue
+		}
+		done := true
+		ctx, cancel := context.WithTimeout(context.Background(), PingTimeout)
+		err := actor.Call(ctx, func() { done = d.Drained() })
+		cancel()
+		if err == nil && !done {
+			return false
+		}
+	}
+	return true
+}
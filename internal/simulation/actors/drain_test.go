@@ -0,0 +1,104 @@
+package actors
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// drainable finishes its trip drainFor after it was told to drain, and records the calls of the drain
+type drainable struct {
+	drainFor time.Duration
+	crash    bool // panics when told to drain
+
+	lock      sync.Mutex
+	calls     []string
+	drainedAt time.Time
+}
+
+func (d *drainable) record(call string) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	d.calls = append(d.calls, call)
+}
+
+func (d *drainable) Drain() {
+	if d.crash {
+		panic("crashed while draining")
+	}
+	d.record("drain")
+	d.lock.Lock()
+	d.drainedAt = time.Now().Add(d.drainFor)
+	d.lock.Unlock()
+}
+
+func (d *drainable) Drained() bool {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	return !d.drainedAt.IsZero() && time.Now().After(d.drainedAt)
+}
+
+func (d *drainable) SignOff() {
+	d.record("sign off")
+}
+
+func (d *drainable) Calls() []string {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	return append([]string(nil), d.calls...)
+}
+
+func TestShutdown(t *testing.T) {
+	tests := []struct {
+		name     string
+		deadline time.Duration
+		drainFor time.Duration
+	}{
+		{name: "trip finishes", deadline: 5 * time.Second, drainFor: restartDelay + 500*time.Millisecond},
+		{name: "deadline passes", deadline: restartDelay + 500*time.Millisecond, drainFor: time.Hour},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			supervisor := NewSupervisor("shutdown-" + tt.name)
+			defer supervisor.Stop()
+
+			var crasherStarts atomic.Int32
+			supervisor.StartChild(ChildSpec{Kind: "test", Id: "crasher-" + tt.name, Restart: Permanent, Start: func() error {
+				crasherStarts.Add(1)
+				Spawn("test", "crasher-"+tt.name, &drainable{crash: true})
+				return nil
+			}})
+			driver := &drainable{drainFor: tt.drainFor}
+			supervisor.StartChild(ChildSpec{Kind: "test", Id: "driver-" + tt.name, Restart: Permanent, Start: func() error {
+				Spawn("test", "driver-"+tt.name, driver)
+				return nil
+			}})
+			paused, _ := Lookup("driver-" + tt.name)
+			paused.Pause()
+
+			ctx, cancel := context.WithTimeout(context.Background(), tt.deadline)
+			defer cancel()
+			failed := Shutdown(ctx)
+
+			// the crasher went down during the drain, which outlasted the restart delay
+			if n := crasherStarts.Load(); n != 1 {
+				t.Errorf("crasher started %d times, want once", n)
+			}
+			if _, ok := failed["crasher-"+tt.name]; !ok || len(failed) != 1 {
+				t.Errorf("failed %v, want the crasher only", failed)
+			}
+			if calls := driver.Calls(); !reflect.DeepEqual(calls, []string{"drain", "sign off"}) {
+				t.Errorf("driver calls %v, want drain then sign off", calls)
+			}
+			if n := len(All("")); n != 0 {
+				t.Errorf("%d actors still running", n)
+			}
+			if status := supervisor.Status(); status.State != ChildStopped {
+				t.Errorf("supervisor is %s", status.State)
+			}
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package actors
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+)
+
+var (
+	ErrUnknownAction = errors.New("unknown action")
+	ErrBadPayload    = errors.New("bad payload")
+)
+
+// callHandler and castHandler run in the mailbox of the actor with the payload already decoded
+type (
+	callHandler func(server interface{}, payload []byte) (func() (interface{}, error), error)
+	castHandler func(server interface{}, payload []byte) (func(), error)
+)
+
+var (
+	callHandlers = make(map[string]callHandler)
+	castHandlers = make(map[string]castHandler)
+	handlersLock sync.RWMutex
+)
+
+// RegisterCall adds a synchronous action to the actors of kind. The JSON payload of a call is decoded
+// into P, refusing unknown fields and running its Validate method if it has one, and the value handle
+// returns is sent back as JSON.
+func RegisterCall[S any, P any](kind, action string, handle func(server S, payload P) (interface{}, error)) {
+	handlersLock.Lock()
+	defer handlersLock.Unlock()
+	callHandlers[kind+"/"+action] = func(server interface{}, payload []byte) (func() (interface{}, error), error) {
+		s, p, err := decodeAction[S, P](server, payload)
+		if err != nil {
+			return nil, err
+		}
+		return func() (interface{}, error) { return handle(s, p) }, nil
+	}
+}
+
+// RegisterCast adds an asynchronous action to the actors of kind, the payload is decoded like for calls.
+func RegisterCast[S any, P any](kind, action string, handle func(server S, payload P)) {
+	handlersLock.Lock()
+	defer handlersLock.Unlock()
+	castHandlers[kind+"/"+action] = func(server interface{}, payload []byte) (func(), error) {
+		s, p, err := decodeAction[S, P](server, payload)
+		if err != nil {
+			return nil, err
+		}
+		return func() { handle(s, p) }, nil
+	}
+}
+
+// HandleCall runs a registered call action in the mailbox of the actor and returns its JSON encoded result.
+func HandleCall(ctx context.Context, id, action string, payload []byte) ([]byte, error) {
+	actor, ok := Lookup(id)
+	if !ok {
+		return nil, fmt.Errorf("%s: %w", id, ErrNotRunning)
+	}
+	handlersLock.RLock()
+	handler, ok := callHandlers[actor.Kind+"/"+action]
+	handlersLock.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%s %q: %w", actor.Kind, action, ErrUnknownAction)
+	}
+	run, err := handler(actor.Server, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	var result interface{}
+	if callErr := actor.Call(ctx, func() {
+		result, err = run()
+	}); callErr != nil {
+		return nil, callErr
+	}
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(result)
+}
+
+// HandleCast queues a registered cast action in the mailbox of the actor. The payload is decoded
+// before queueing, so a malformed one is refused rather than dropped.
+func HandleCast(id, action string, payload []byte) error {
+	actor, ok := Lookup(id)
+	if !ok {
+		return fmt.Errorf("%s: %w", id, ErrNotRunning)
+	}
+	handlersLock.RLock()
+	handler, ok := castHandlers[actor.Kind+"/"+action]
+	handlersLock.RUnlock()
+	if !ok {
+		return fmt.Errorf("%s %q: %w", actor.Kind, action, ErrUnknownAction)
+	}
+	run, err := handler(actor.Server, payload)
+	if err != nil {
+		return err
+	}
+	return actor.Cast(run)
+}
+
+func decodeAction[S any, P any](server interface{}, payload []byte) (s S, p P, err error) {
+	s, ok := server.(S)
+	if !ok {
+		return s, p, fmt.Errorf("actor does not take the action: %w", ErrUnknownAction)
+	}
+	if len(bytes.TrimSpace(payload)) > 0 {
+		decoder := json.NewDecoder(bytes.NewReader(payload))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&p); err != nil {
+			return s, p, fmt.Errorf("%w: %v", ErrBadPayload, err)
+		}
+	}
+	if v, ok := any(p).(validation.Validatable); ok {
+		if err := v.Validate(); err != nil {
+			return s, p, fmt.Errorf("%w: %v", ErrBadPayload, err)
+		}
+	}
+	return s, p, nil
+}
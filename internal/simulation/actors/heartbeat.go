@@ -0,0 +1,157 @@
+package actors
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	pb "sim-server/internal/genserver/proto"
+	"sim-server/internal/services"
+)
+
+const (
+	HeartbeatInterval = 10 * time.Second
+	PingTimeout       = 5 * time.Second
+	// maxMissedPings is how many heartbeats in a row a busy mailbox may leave unanswered before the actor
+	// is taken for stuck. Its messages block on backend calls, which retry for up to a minute.
+	maxMissedPings = 12
+	// registryTTL lets the registry entries of a crashed process expire after a few missed heartbeats
+	registryTTL = 3 * HeartbeatInterval
+)
+
+// aliveChecker is implemented by the simulated drivers and customers
+type aliveChecker interface {
+	IsAlive(ctx context.Context, req *pb.IsAliveRequest) (*pb.IsAliveResponse, error)
+}
+
+// advertiseHost is the host other nodes reach the gRPC listeners of the actors of this process on
+var advertiseHost = "localhost"
+
+// SetAdvertiseHost sets the host written to the registry with the port of every listener, it must be
+// called before any actor is advertised
+func SetAdvertiseHost(host string) {
+	advertiseHost = host
+}
+
+func AdvertiseHost() string {
+	return advertiseHost
+}
+
+// Advertise writes the address the actor serves gRPC on to the registry, the heartbeat keeps
+// the entry from expiring for as long as the actor is alive.
+func (a *Actor) Advertise(address string) error {
+	a.lock.Lock()
+	a.address = address
+	a.lock.Unlock()
+	return services.Set(a.Id, []byte(address), registryTTL)
+}
+
+// Ping runs IsAlive in the mailbox of the actor, failing when the actor reports itself dead
+// or does not get to the message within PingTimeout.
+func (a *Actor) Ping(ctx context.Context) error {
+	checker, ok := a.Server.(aliveChecker)
+	if !ok {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, PingTimeout)
+	defer cancel()
+
+	var err error
+	if callErr := a.Call(ctx, func() {
+		_, err = checker.IsAlive(ctx, &pb.IsAliveRequest{})
+	}); callErr != nil {
+		return callErr
+	}
+	return err
+}
+
+var startHeartbeat sync.Once
+
+// StartHeartbeat pings every actor each HeartbeatInterval, refreshing the registry entries of
+// the live ones and crashing the dead ones, for their supervisor to restart.
+func StartHeartbeat() {
+	startHeartbeat.Do(func() {
+		go func() {
+			ticker := time.NewTicker(HeartbeatInterval)
+			defer ticker.Stop()
+			for range ticker.C {
+				sweep()
+			}
+		}()
+	})
+}
+
+// sweep pings the actors concurrently so one stuck mailbox does not delay the others. Paused actors
+// cannot answer, their registry entries are renewed without a ping. A ping that times out means the
+// mailbox is busy, the actor is only crashed once it reports itself dead or misses maxMissedPings.
+func sweep() {
+	var wg sync.WaitGroup
+	for _, actor := range All("") {
+		if actor.Paused() {
+			actor.beat()
+			continue
+		}
+		wg.Add(1)
+		go func(actor *Actor) {
+			defer wg.Done()
+			switch err := actor.Ping(context.Background()); {
+			case errors.Is(err, context.DeadlineExceeded):
+				if missed := actor.missPing(); missed >= maxMissedPings {
+					actor.crash(fmt.Errorf("heartbeat: no answer to %d pings in a row", missed))
+					return
+				}
+			case err != nil:
+				actor.crash(fmt.Errorf("heartbeat: %w", err))
+				return
+			default:
+				actor.answerPing()
+			}
+			actor.beat()
+		}(actor)
+	}
+	wg.Wait()
+}
+
+// missPing counts a ping the mailbox did not get to in time and returns the misses in a row
+func (a *Actor) missPing() int {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	a.missedPings++
+	return a.missedPings
+}
+
+func (a *Actor) answerPing() {
+	a.lock.Lock()
+	a.missedPings = 0
+	a.lock.Unlock()
+}
+
+// beat renews the registry entry of an advertised actor
+func (a *Actor) beat() {
+	a.lock.Lock()
+	address := a.address
+	a.lock.Unlock()
+	if address == "" {
+		return
+	}
+	if err := services.Set(a.Id, []byte(address), registryTTL); err != nil {
+		log.Printf("%s: failed to renew registry entry: %v", a, err)
+	}
+}
+
+// unadvertise removes the registry entry of an advertised actor
+func (a *Actor) unadvertise() {
+	a.lock.Lock()
+	address := a.address
+	a.address = ""
+	a.lock.Unlock()
+	if address == "" {
+		return
+	}
+	if err := services.Delete(a.Id); err != nil {
+		log.Printf("%s: failed to remove registry entry: %v", a, err)
+	}
+}
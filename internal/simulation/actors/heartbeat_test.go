@@ -0,0 +1,69 @@
+package actors
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	pb "sim-server/internal/genserver/proto"
+)
+
+type pingable struct {
+	err error
+}
+
+func (p *pingable) IsAlive(ctx context.Context, req *pb.IsAliveRequest) (*pb.IsAliveResponse, error) {
+	return &pb.IsAliveResponse{}, p.err
+}
+
+func TestSweep(t *testing.T) {
+	tests := []struct {
+		name        string
+		busy        bool // the mailbox is on a message for longer than PingTimeout
+		missedPings int  // before the sweep
+		err         error
+		crashed     bool
+		missedAfter int
+	}{
+		{name: "alive", missedPings: 3, missedAfter: 0},
+		{name: "busy", busy: true, missedAfter: 1},
+		{name: "stuck", busy: true, missedPings: maxMissedPings - 1, crashed: true},
+		{name: "dead", err: errors.New("websocket is gone"), crashed: true},
+	}
+
+	spawned := make([]*Actor, len(tests))
+	for i, tt := range tests {
+		actor := Spawn("test", "sweep-"+tt.name, &pingable{err: tt.err})
+		defer actor.Stop()
+		actor.missedPings = tt.missedPings
+		if tt.busy {
+			actor.Cast(func() { time.Sleep(PingTimeout + time.Second) })
+		}
+		spawned[i] = actor
+	}
+
+	// the actors are pinged concurrently, the sweep takes PingTimeout
+	sweep()
+
+	for i, tt := range tests {
+		actor := spawned[i]
+		select {
+		case <-actor.Done():
+			if !tt.crashed {
+				t.Errorf("%s: crashed", tt.name)
+			}
+			continue
+		default:
+		}
+		if tt.crashed {
+			t.Errorf("%s: still running", tt.name)
+		}
+		actor.lock.Lock()
+		missed := actor.missedPings
+		actor.lock.Unlock()
+		if missed != tt.missedAfter {
+			t.Errorf("%s: %d missed pings, want %d", tt.name, missed, tt.missedAfter)
+		}
+	}
+}
@@ -0,0 +1,317 @@
+package actors
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+
+	"google.golang.org/grpc"
+)
+
+const mailboxSize = 64
+
+var (
+	ErrNotRunning  = errors.New("actor is not running")
+	ErrStopped     = errors.New("actor is stopped")
+	ErrMailboxFull = errors.New("actor mailbox is full")
+	ErrPanic       = errors.New("actor panicked")
+)
+
+// Actor is a simulated driver or customer running in this process. Messages sent to it
+// run one at a time on its own goroutine, in the order they were sent.
+type Actor struct {
+	Id     string
+	Kind   string
+	Server interface{} // the simulated actor the messages act on
+
+	mailbox  chan message
+	stopped  chan struct{}
+	stopOnce sync.Once
+
+	lock        sync.Mutex // guards address, exitReason, onExit, resumed and missedPings
+	address     string     // gRPC address advertised in the registry, empty without a listener
+	exitReason  error      // why the actor crashed, nil after a normal stop
+	onExit      func(reason error)
+	resumed     chan struct{} // closed on Resume, nil while the actor is not paused
+	missedPings int           // heartbeats in a row the mailbox was too busy to answer
+}
+
+// terminator is implemented by servers holding resources, such as a websocket, to release once the actor exits
+type terminator interface {
+	Terminate(reason error)
+}
+
+type message struct {
+	fn   func()
+	done chan error // receives the outcome once fn returned, nil for casts
+}
+
+var (
+	registry     = make(map[string]*Actor)
+	registryLock sync.RWMutex
+)
+
+// Spawn starts an actor for server and registers it under id, replacing the actor
+// previously registered under the same id.
+func Spawn(kind, id string, server interface{}) *Actor {
+	actor := &Actor{
+		Id:      id,
+		Kind:    kind,
+		Server:  server,
+		mailbox: make(chan message, mailboxSize),
+		stopped: make(chan struct{}),
+	}
+	go actor.loop()
+
+	registryLock.Lock()
+	previous := registry[id]
+	registry[id] = actor
+	registryLock.Unlock()
+
+	if previous != nil {
+		log.Printf("%s %s was spawned again, stopping the previous instance", kind, id)
+		previous.Stop()
+	}
+	return actor
+}
+
+// Lookup returns the running actor registered under id.
+func Lookup(id string) (*Actor, bool) {
+	registryLock.RLock()
+	defer registryLock.RUnlock()
+	actor, ok := registry[id]
+	return actor, ok
+}
+
+// All returns the running actors of kind, every actor when kind is empty.
+func All(kind string) []*Actor {
+	registryLock.RLock()
+	defer registryLock.RUnlock()
+	var result []*Actor
+	for _, actor := range registry {
+		if kind == "" || actor.Kind == kind {
+			result = append(result, actor)
+		}
+	}
+	return result
+}
+
+// Call runs fn in the mailbox of the actor and waits until it returned. A panic in fn crashes the actor
+// and is returned as ErrPanic.
+func (a *Actor) Call(ctx context.Context, fn func()) error {
+	done := make(chan error, 1)
+	select {
+	case a.mailbox <- message{fn: fn, done: done}:
+	case <-a.stopped:
+		return ErrStopped
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	select {
+	case err := <-done:
+		return err
+	case <-a.stopped:
+		// the message that crashed the actor answers before it stops
+		select {
+		case err := <-done:
+			return err
+		default:
+			return ErrStopped
+		}
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Cast queues fn in the mailbox of the actor without waiting for it.
+func (a *Actor) Cast(fn func()) error {
+	select {
+	case <-a.stopped:
+		return ErrStopped
+	default:
+	}
+	select {
+	case a.mailbox <- message{fn: fn}:
+		return nil
+	default:
+		return ErrMailboxFull
+	}
+}
+
+// Stop stops processing messages and unregisters the actor, queued messages are dropped.
+func (a *Actor) Stop() {
+	a.exit(nil)
+}
+
+// Done is closed once the actor stopped or crashed
+func (a *Actor) Done() <-chan struct{} {
+	return a.stopped
+}
+
+// Pause holds the messages of the actor in its mailbox until Resume. Callers keep waiting for their
+// calls meanwhile, so the websocket read loop and the trip goroutines of the actor stall with it.
+func (a *Actor) Pause() {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	if a.resumed == nil {
+		a.resumed = make(chan struct{})
+	}
+}
+
+// Resume runs the messages held since Pause, in the order they were sent
+func (a *Actor) Resume() {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	if a.resumed != nil {
+		close(a.resumed)
+		a.resumed = nil
+	}
+}
+
+// Paused reports whether the actor holds its messages
+func (a *Actor) Paused() bool {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	return a.resumed != nil
+}
+
+// crash stops the actor abnormally, its supervisor decides whether it is restarted
+func (a *Actor) crash(reason error) {
+	log.Printf("%s crashed: %v", a, reason)
+	a.exit(reason)
+}
+
+func (a *Actor) exit(reason error) {
+	a.stopOnce.Do(func() {
+		// stopped is closed under the lock so link either sees it or its onExit is called
+		a.lock.Lock()
+		a.exitReason = reason
+		close(a.stopped)
+		onExit := a.onExit
+		a.lock.Unlock()
+
+		registryLock.Lock()
+		if registry[a.Id] == a {
+			delete(registry, a.Id)
+		}
+		registryLock.Unlock()
+		a.unadvertise()
+		if t, ok := a.Server.(terminator); ok {
+			t.Terminate(reason)
+		}
+		if onExit != nil {
+			go onExit(reason)
+		}
+	})
+}
+
+// link makes onExit be called once the actor exits, right away if it already did
+func (a *Actor) link(onExit func(reason error)) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	select {
+	case <-a.stopped:
+		go onExit(a.exitReason)
+	default:
+		a.onExit = onExit
+	}
+}
+
+// Go runs fn on a goroutine of its own that crashes the actor rather than the process when it panics.
+func (a *Actor) Go(fn func()) {
+	go func() {
+		defer a.Recover()
+		fn()
+	}()
+}
+
+// UnaryInterceptor runs the calls served by the own gRPC listener of the actor in its mailbox,
+// serialized with every other message like the calls of the control server.
+func (a *Actor) UnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (response interface{}, err error) {
+		if callErr := a.Call(ctx, func() {
+			response, err = handler(ctx, req)
+		}); callErr != nil {
+			return nil, callErr
+		}
+		return response, err
+	}
+}
+
+// Recover crashes the actor when the goroutine deferring it panics. It is meant for the goroutines
+// an actor does not start through Go, such as websocket callbacks.
+func (a *Actor) Recover() {
+	r := recover()
+	if r == nil {
+		return
+	}
+	if a == nil {
+		log.Printf("panic outside of a running actor: %v", r)
+		return
+	}
+	a.crash(fmt.Errorf("%w: %v", ErrPanic, r))
+}
+
+func (a *Actor) loop() {
+	for {
+		select {
+		case <-a.stopped:
+			return
+		case m := <-a.mailbox:
+			if !a.awaitResume() {
+				return
+			}
+			a.run(m)
+		}
+	}
+}
+
+// awaitResume blocks while the actor is paused, reporting false when it stopped meanwhile
+func (a *Actor) awaitResume() bool {
+	a.lock.Lock()
+	resumed := a.resumed
+	a.lock.Unlock()
+	if resumed == nil {
+		return true
+	}
+	select {
+	case <-resumed:
+		return true
+	case <-a.stopped:
+		return false
+	}
+}
+
+// run crashes the actor when a message panics, instead of the process
+func (a *Actor) run(m message) {
+	var err error
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%w: %v", ErrPanic, r)
+		}
+		if m.done != nil {
+			m.done <- err
+		}
+		if err != nil {
+			a.crash(err)
+		}
+	}()
+	m.fn()
+}
+
+func (a *Actor) String() string {
+	return fmt.Sprintf("%s %s", a.Kind, a.Id)
+}
+
+// listeners makes every actor also serve gRPC on a port of its own, registered for tools outside the process
+var listeners bool
+
+func EnableListeners(enabled bool) {
+	listeners = enabled
+}
+
+func ListenersEnabled() bool {
+	return listeners
+}
@@ -0,0 +1,315 @@
+package actors
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+// RestartPolicy tells a supervisor what to do when one of its actors exits
+type RestartPolicy string
+
+const (
+	Permanent RestartPolicy = "permanent" // restarted whenever it exits
+	Transient RestartPolicy = "transient" // restarted after crashes only
+	Temporary RestartPolicy = "temporary" // never restarted
+)
+
+const DefaultMaxRestarts = 3
+
+var (
+	// restartWindow is how far back restarts count towards MaxRestarts
+	restartWindow = time.Minute
+	restartDelay  = time.Second
+)
+
+// Child states reported in the supervisor status, the supervisor itself is running, paused or stopped
+const (
+	ChildRunning    = "running"
+	ChildPaused     = "paused"
+	ChildRestarting = "restarting"
+	ChildStopped    = "stopped"
+	ChildFailed     = "failed"
+)
+
+var ErrSupervisorStopped = errors.New("scenario is stopped")
+
+// ChildSpec describes how a supervisor starts one actor and how it reacts to its exit.
+type ChildSpec struct {
+	Kind    string
+	Id      string
+	Restart RestartPolicy
+	// MaxRestarts within a minute before the supervisor gives up on the actor, DefaultMaxRestarts when zero
+	MaxRestarts int
+	// Start spawns the actor under Id, it is called again for every restart
+	Start func() error
+}
+
+// Failure is an actor that crashed or could not be started.
+type Failure struct {
+	Kind      string    `json:"kind"`
+	ActorId   string    `json:"actor_id"`
+	Reason    string    `json:"reason"`
+	Time      time.Time `json:"time"`
+	Restarted bool      `json:"restarted"`
+}
+
+type ChildStatus struct {
+	Kind     string `json:"kind"`
+	Id       string `json:"id"`
+	State    string `json:"state"`
+	Restarts int    `json:"restarts"`
+}
+
+type SupervisorStatus struct {
+	Id        string        `json:"id"`
+	State     string        `json:"state"`
+	StartedAt time.Time     `json:"started_at"`
+	Children  []ChildStatus `json:"children"`
+	Failures  []Failure     `json:"failures"`
+}
+
+// Supervisor starts the actors of one scenario and restarts them according to their policy,
+// keeping a record of every failure so none of them goes unnoticed.
+type Supervisor struct {
+	id        string
+	startedAt time.Time
+
+	lock     sync.Mutex
+	state    string
+	draining bool // no actor is started or restarted any more, see Drain
+	children map[string]*child
+	failures []Failure
+}
+
+type child struct {
+	spec     ChildSpec
+	state    string
+	restarts []time.Time // within restartWindow
+	total    int
+}
+
+var (
+	supervisors     = make(map[string]*Supervisor)
+	supervisorsLock sync.RWMutex
+)
+
+// NewSupervisor creates the supervisor of a scenario and registers it under the scenario id.
+func NewSupervisor(id string) *Supervisor {
+	s := &Supervisor{id: id, startedAt: time.Now(), state: ChildRunning, children: make(map[string]*child)}
+	supervisorsLock.Lock()
+	supervisors[id] = s
+	supervisorsLock.Unlock()
+	return s
+}
+
+// LookupSupervisor returns the supervisor registered under a scenario id.
+func LookupSupervisor(id string) (*Supervisor, bool) {
+	supervisorsLock.RLock()
+	defer supervisorsLock.RUnlock()
+	s, ok := supervisors[id]
+	return s, ok
+}
+
+// StartChild starts the actor of spec under supervision. A failed start counts like a crash,
+// so it is retried according to the restart policy as well.
+func (s *Supervisor) StartChild(spec ChildSpec) error {
+	if spec.Restart == "" {
+		spec.Restart = Transient
+	}
+	if spec.MaxRestarts <= 0 {
+		spec.MaxRestarts = DefaultMaxRestarts
+	}
+	c := &child{spec: spec, state: ChildRestarting}
+	s.lock.Lock()
+	s.children[spec.Id] = c
+	s.lock.Unlock()
+	return s.start(c)
+}
+
+// Fail records the failure of an actor that never got to be supervised, such as a refused login.
+func (s *Supervisor) Fail(kind, id string, reason error) {
+	log.Printf("scenario %s: %s %s failed: %v", s.id, kind, id, reason)
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.failures = append(s.failures, Failure{Kind: kind, ActorId: id, Reason: reason.Error(), Time: time.Now()})
+}
+
+// Status returns the state of every actor of the supervisor and the failures so far.
+func (s *Supervisor) Status() SupervisorStatus {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	status := SupervisorStatus{
+		Id:        s.id,
+		State:     s.state,
+		StartedAt: s.startedAt,
+		Children:  make([]ChildStatus, 0, len(s.children)),
+		Failures:  append([]Failure{}, s.failures...),
+	}
+	for _, c := range s.children {
+		status.Children = append(status.Children, ChildStatus{Kind: c.spec.Kind, Id: c.spec.Id, State: c.state, Restarts: c.total})
+	}
+	sort.Slice(status.Children, func(i, j int) bool { return status.Children[i].Id < status.Children[j].Id })
+	return status
+}
+
+// Pause holds the messages of every running actor of the supervisor, see Actor.Pause. Actors
+// (re)started while the supervisor is paused start paused.
+func (s *Supervisor) Pause() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.state == ChildStopped {
+		return ErrSupervisorStopped
+	}
+	s.state = ChildPaused
+	for _, c := range s.children {
+		if actor, ok := Lookup(c.spec.Id); ok && c.state == ChildRunning {
+			actor.Pause()
+			c.state = ChildPaused
+		}
+	}
+	return nil
+}
+
+// Resume runs the actors paused by Pause again
+func (s *Supervisor) Resume() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.state == ChildStopped {
+		return ErrSupervisorStopped
+	}
+	s.state = ChildRunning
+	for _, c := range s.children {
+		if actor, ok := Lookup(c.spec.Id); ok && c.state == ChildPaused {
+			actor.Resume()
+			c.state = ChildRunning
+		}
+	}
+	return nil
+}
+
+// Stop stops every actor of the supervisor for good, none of them is restarted and no new one is started
+func (s *Supervisor) Stop() {
+	s.lock.Lock()
+	s.state = ChildStopped
+	var running []*Actor
+	for _, c := range s.children {
+		if actor, ok := Lookup(c.spec.Id); ok && (c.state == ChildRunning || c.state == ChildPaused) {
+			running = append(running, actor)
+		}
+	}
+	s.lock.Unlock()
+
+	for _, actor := range running {
+		actor.Stop()
+	}
+}
+
+// Drain lets the running actors of the supervisor go on with their trips but starts none of them again,
+// so an actor crashing while the process shuts down does not come back behind the drain
+func (s *Supervisor) Drain() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.draining = true
+}
+
+func (s *Supervisor) start(c *child) error {
+	s.lock.Lock()
+	stopped := s.state == ChildStopped || s.draining
+	if stopped {
+		c.state = ChildStopped
+	}
+	s.lock.Unlock()
+	if stopped {
+		return ErrSupervisorStopped
+	}
+
+	err := safely(c.spec.Start)
+	if err == nil {
+		actor, ok := Lookup(c.spec.Id)
+		if ok {
+			s.lock.Lock()
+			c.state = ChildRunning
+			switch {
+			case s.state == ChildPaused && !s.draining:
+				actor.Pause()
+				c.state = ChildPaused
+			case s.state == ChildStopped || s.draining:
+				// stopped or drained while the actor was starting
+				c.state = ChildStopped
+				s.lock.Unlock()
+				actor.Stop()
+				return ErrSupervisorStopped
+			}
+			s.lock.Unlock()
+			actor.link(func(reason error) { s.exited(c, reason) })
+			return nil
+		}
+		err = fmt.Errorf("%s %s did not start", c.spec.Kind, c.spec.Id)
+	}
+	s.exited(c, err)
+	return err
+}
+
+// exited applies the restart policy of c, giving up once it restarted MaxRestarts times within restartWindow
+func (s *Supervisor) exited(c *child, reason error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.state == ChildStopped || s.draining {
+		c.state = ChildStopped
+		return
+	}
+	restart := c.spec.Restart == Permanent || (c.spec.Restart == Transient && reason != nil)
+	if !restart && reason == nil {
+		c.state = ChildStopped
+		return
+	}
+
+	now := time.Now()
+	recent := c.restarts[:0]
+	for _, at := range c.restarts {
+		if now.Sub(at) < restartWindow {
+			recent = append(recent, at)
+		}
+	}
+	c.restarts = recent
+	if restart && len(c.restarts) >= c.spec.MaxRestarts {
+		restart = false
+		reason = fmt.Errorf("giving up after %d restarts within %v: %v", len(c.restarts), restartWindow, reason)
+	}
+
+	if reason != nil {
+		s.failures = append(s.failures, Failure{
+			Kind:      c.spec.Kind,
+			ActorId:   c.spec.Id,
+			Reason:    reason.Error(),
+			Time:      now,
+			Restarted: restart,
+		})
+	}
+	if !restart {
+		c.state = ChildFailed
+		log.Printf("scenario %s: %s %s failed: %v", s.id, c.spec.Kind, c.spec.Id, reason)
+		return
+	}
+
+	c.state = ChildRestarting
+	c.restarts = append(c.restarts, now)
+	c.total++
+	log.Printf("scenario %s: restarting %s %s in %v: %v", s.id, c.spec.Kind, c.spec.Id, restartDelay, reason)
+	time.AfterFunc(restartDelay, func() { s.start(c) })
+}
+
+// safely turns a panic of start into an error
+func safely(start func() error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%w: %v", ErrPanic, r)
+		}
+	}()
+	return start()
+}
@@ -0,0 +1,145 @@
+package actors
+
+import (
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// shortRestarts restarts the actors of the test after a few milliseconds, and forgets their
+// restarts after window
+func shortRestarts(t *testing.T, window time.Duration) {
+	delay, previousWindow := restartDelay, restartWindow
+	restartDelay, restartWindow = 10*time.Millisecond, window
+	t.Cleanup(func() { restartDelay, restartWindow = delay, previousWindow })
+}
+
+// eventually waits for cond, failing the test when it does not hold within a second
+func eventually(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func childStatus(s *Supervisor, id string) ChildStatus {
+	for _, child := range s.Status().Children {
+		if child.Id == id {
+			return child
+		}
+	}
+	return ChildStatus{}
+}
+
+func TestRestartPolicy(t *testing.T) {
+	tests := []struct {
+		name     string
+		policy   RestartPolicy
+		crash    bool // rather than stopping normally
+		starts   int32
+		state    string
+		failures int
+	}{
+		{name: "permanent crash", policy: Permanent, crash: true, starts: 2, state: ChildRunning, failures: 1},
+		{name: "permanent exit", policy: Permanent, starts: 2, state: ChildRunning},
+		{name: "transient crash", policy: Transient, crash: true, starts: 2, state: ChildRunning, failures: 1},
+		{name: "transient exit", policy: Transient, starts: 1, state: ChildStopped},
+		{name: "temporary crash", policy: Temporary, crash: true, starts: 1, state: ChildFailed, failures: 1},
+		{name: "temporary exit", policy: Temporary, starts: 1, state: ChildStopped},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			shortRestarts(t, time.Minute)
+			supervisor := NewSupervisor("policy-" + tt.name)
+			defer supervisor.Stop()
+
+			id := "policy-" + tt.name
+			var starts atomic.Int32
+			err := supervisor.StartChild(ChildSpec{Kind: "test", Id: id, Restart: tt.policy, Start: func() error {
+				starts.Add(1)
+				Spawn("test", id, &pingable{})
+				return nil
+			}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			actor, _ := Lookup(id)
+			if tt.crash {
+				actor.crash(errors.New("websocket is gone"))
+			} else {
+				actor.Stop()
+			}
+
+			eventually(t, tt.state, func() bool {
+				return starts.Load() == tt.starts && childStatus(supervisor, id).State == tt.state
+			})
+			// nothing more happens after the restart delay
+			time.Sleep(5 * restartDelay)
+			if n := starts.Load(); n != tt.starts {
+				t.Errorf("started %d times, want %d", n, tt.starts)
+			}
+			status := supervisor.Status()
+			if len(status.Failures) != tt.failures {
+				t.Fatalf("failures %+v, want %d", status.Failures, tt.failures)
+			}
+			if tt.failures > 0 && status.Failures[0].Restarted != (tt.starts > 1) {
+				t.Errorf("failure restarted %v, want %v", status.Failures[0].Restarted, tt.starts > 1)
+			}
+		})
+	}
+}
+
+func TestMaxRestarts(t *testing.T) {
+	tests := []struct {
+		name     string
+		window   time.Duration
+		failures int32 // failed starts before the actor comes up
+		starts   int32
+		state    string
+		restarts int
+	}{
+		{name: "gives up within the window", window: time.Minute, failures: 10, starts: 3, state: ChildFailed, restarts: 2},
+		{name: "comes up before giving up", window: time.Minute, failures: 2, starts: 3, state: ChildRunning, restarts: 2},
+		// every restart is older than the window by the time the next start fails
+		{name: "restarts out of the window", window: time.Millisecond, failures: 5, starts: 6, state: ChildRunning, restarts: 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			shortRestarts(t, tt.window)
+			supervisor := NewSupervisor("max-restarts-" + tt.name)
+			defer supervisor.Stop()
+
+			id := "max-restarts-" + tt.name
+			var starts atomic.Int32
+			supervisor.StartChild(ChildSpec{Kind: "test", Id: id, Restart: Permanent, MaxRestarts: 2, Start: func() error {
+				if starts.Add(1) <= tt.failures {
+					return errors.New("login refused")
+				}
+				Spawn("test", id, &pingable{})
+				return nil
+			}})
+
+			eventually(t, tt.state, func() bool {
+				return starts.Load() == tt.starts && childStatus(supervisor, id).State == tt.state
+			})
+			time.Sleep(5 * restartDelay)
+			if n := starts.Load(); n != tt.starts {
+				t.Errorf("started %d times, want %d", n, tt.starts)
+			}
+			if child := childStatus(supervisor, id); child.Restarts != tt.restarts {
+				t.Errorf("restarted %d times, want %d", child.Restarts, tt.restarts)
+			}
+			failures := supervisor.Status().Failures
+			last := failures[len(failures)-1]
+			if gaveUp := tt.state == ChildFailed; gaveUp != strings.HasPrefix(last.Reason, "giving up") || gaveUp == last.Restarted {
+				t.Errorf("last failure %+v, gave up %v", last, gaveUp)
+			}
+		})
+	}
+}
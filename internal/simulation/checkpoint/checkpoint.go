@@ -0,0 +1,284 @@
+// Package checkpoint saves the state of every actor to the registry at regular intervals, so the
+// actors of a node can be started again where they were after the process restarted mid-scenario.
+package checkpoint
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"sim-server/internal/models"
+	"sim-server/internal/services"
+	"sim-server/internal/simulation/actors"
+	"sim-server/internal/simulation/fsm"
+)
+
+const (
+	Interval = 15 * time.Second
+	// saveTimeout bounds one round of checkpoints, it ends before the next one starts
+	saveTimeout = 10 * time.Second
+	// ttl drops the checkpoints that are no longer refreshed, a node down for longer is not recovered
+	ttl            = time.Hour
+	scenarioPrefix = "checkpoint-scenario:"
+	actorPrefix    = "checkpoint-actor:"
+)
+
+// Scenario is what a node needs to supervise the actors of a scenario again
+type Scenario struct {
+	Id          string    `json:"id"`
+	Target      string    `json:"target"`
+	AuthMode    string    `json:"auth_mode,omitempty"`
+	Restart     string    `json:"restart_policy,omitempty"`
+	MaxRestarts int       `json:"max_restarts,omitempty"`
+	Speed       float64   `json:"speed"`
+	ClockStart  time.Time `json:"clock_start"`
+}
+
+// Actor is a driver or customer as of its last checkpoint: who it is, its token, where it is in
+// its lifecycle and where it is on the map
+type Actor struct {
+	Kind        string    `json:"kind"`
+	ScenarioId  string    `json:"scenario_id"`
+	Id          string    `json:"id"`
+	Name        string    `json:"name"`
+	PhoneNumber string    `json:"phone_number"`
+	AccessToken string    `json:"access_token"`
+	State       fsm.State `json:"state"`
+	Lat         float64   `json:"lat"`
+	Lng         float64   `json:"lng"`
+	TripId      string    `json:"trip_id,omitempty"`
+	Manual      bool      `json:"manual,omitempty"`
+
+	// drivers only, the offer of the current trip carries its routes
+	AcceptanceRate float64                       `json:"acceptance_rate,omitempty"`
+	TripOffer      *models.NewTripOfferMessage   `json:"trip_offer,omitempty"`
+	ScheduledTrips []*models.NewTripOfferMessage `json:"scheduled_trips,omitempty"`
+
+	// customers only, the last trip they booked
+	Loop           bool          `json:"loop,omitempty"`
+	ModifyRate     float64       `json:"modify_rate,omitempty"`
+	CancelRate     float64       `json:"cancel_rate,omitempty"`
+	OriginLat      float64       `json:"origin_lat,omitempty"`
+	OriginLng      float64       `json:"origin_lng,omitempty"`
+	DestinationLat float64       `json:"destination_lat,omitempty"`
+	DestinationLng float64       `json:"destination_lng,omitempty"`
+	LeadTime       time.Duration `json:"lead_time,omitempty"`
+
+	SavedAt time.Time `json:"saved_at"`
+}
+
+// Checkpointer is implemented by the simulated drivers and customers, it is called in their mailbox
+type Checkpointer interface {
+	Checkpoint() Actor
+}
+
+var (
+	start sync.Once
+	// node scopes the checkpoints to this node, so the nodes sharing a registry recover their own
+	// actors only. It is set by Start, checkpoints are neither written nor read until then.
+	node string
+
+	// running are the scenarios this node supervises, their checkpoints are refreshed with the actors
+	running     = make(map[string]Scenario)
+	runningLock sync.Mutex
+)
+
+// Start saves every actor of the node each Interval until the process exits, under nodeId. Paused
+// actors keep their last checkpoint. It is called once at startup, before any scenario runs.
+func Start(nodeId string) {
+	start.Do(func() {
+		node = nodeId
+		go func() {
+			ticker := time.NewTicker(Interval)
+			defer ticker.Stop()
+			for range ticker.C {
+				saveAll()
+			}
+		}()
+	})
+}
+
+func enabled() bool {
+	return node != ""
+}
+
+// saveAll checkpoints the actors concurrently, so a busy mailbox does not hold up the others, and
+// refreshes the scenarios still supervised. A stopped scenario is left to expire.
+func saveAll() {
+	ctx, cancel := context.WithTimeout(context.Background(), saveTimeout)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	for _, actor := range actors.All("") {
+		checkpointer, ok := actor.Server.(Checkpointer)
+		if !ok || actor.Paused() {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var saved Actor
+			err := actor.Call(ctx, func() { saved = checkpointer.Checkpoint() })
+			if err == nil {
+				err = Save(saved)
+			}
+			if err != nil {
+				log.Printf("%s: checkpoint failed: %v", actor, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	runningLock.Lock()
+	scenarios := make([]Scenario, 0, len(running))
+	for id, scenario := range running {
+		if supervisor, ok := actors.LookupSupervisor(id); !ok || supervisor.Status().State == actors.ChildStopped {
+			delete(running, id)
+			continue
+		}
+		scenarios = append(scenarios, scenario)
+	}
+	runningLock.Unlock()
+	for _, scenario := range scenarios {
+		if err := writeScenario(scenario); err != nil {
+			log.Printf("scenario %s: checkpoint failed: %v", scenario.Id, err)
+		}
+	}
+}
+
+// SaveScenario records a scenario this node runs actors of, it is refreshed with the actors until
+// DeleteScenario or until its supervisor stops
+func SaveScenario(scenario Scenario) error {
+	if !enabled() {
+		return nil
+	}
+	runningLock.Lock()
+	running[scenario.Id] = scenario
+	runningLock.Unlock()
+	return writeScenario(scenario)
+}
+
+func writeScenario(scenario Scenario) error {
+	data, err := json.Marshal(scenario)
+	if err != nil {
+		return err
+	}
+	return services.Set(scenarioPrefix+node+"/"+scenario.Id, data, ttl)
+}
+
+// DeleteScenario forgets a scenario and the checkpoints of its actors, so it is not recovered. The
+// actors in keep are left to recover, with the scenario they belong to.
+func DeleteScenario(scenarioId string, keep ...string) error {
+	if !enabled() {
+		return nil
+	}
+	runningLock.Lock()
+	delete(running, scenarioId)
+	runningLock.Unlock()
+
+	kept := make(map[string]bool, len(keep))
+	for _, actorId := range keep {
+		kept[actorId] = true
+	}
+	saved, err := services.List(actorPrefix + node + "/" + scenarioId + "/")
+	if err != nil {
+		return err
+	}
+	recovered := false
+	for key := range saved {
+		if kept[strings.TrimPrefix(key, actorKey(scenarioId, ""))] {
+			recovered = true
+			continue
+		}
+		if err := services.Delete(key); err != nil {
+			return err
+		}
+	}
+	if recovered {
+		return nil
+	}
+	return services.Delete(scenarioPrefix + node + "/" + scenarioId)
+}
+
+// Save writes the checkpoint of an actor
+func Save(actor Actor) error {
+	if !enabled() {
+		return nil
+	}
+	actor.SavedAt = time.Now()
+	data, err := json.Marshal(actor)
+	if err != nil {
+		return err
+	}
+	return services.Set(actorKey(actor.ScenarioId, actor.Id), data, ttl)
+}
+
+// Load returns the last checkpoint of an actor
+func Load(scenarioId, actorId string) (Actor, bool) {
+	if !enabled() {
+		return Actor{}, false
+	}
+	value, ok := services.CheckAndGetKey(actorKey(scenarioId, actorId))
+	if !ok {
+		return Actor{}, false
+	}
+	var actor Actor
+	if err := json.Unmarshal([]byte(value), &actor); err != nil {
+		log.Printf("invalid checkpoint of %s: %v", actorId, err)
+		return Actor{}, false
+	}
+	return actor, true
+}
+
+// Scenarios returns the scenarios this node ran actors of
+func Scenarios() ([]Scenario, error) {
+	if !enabled() {
+		return nil, nil
+	}
+	entries, err := services.List(scenarioPrefix + node + "/")
+	if err != nil {
+		return nil, err
+	}
+	scenarios := make([]Scenario, 0, len(entries))
+	for key, value := range entries {
+		var scenario Scenario
+		if err := json.Unmarshal([]byte(value), &scenario); err != nil {
+			log.Printf("skipping checkpoint %s: %v", key, err)
+			continue
+		}
+		scenarios = append(scenarios, scenario)
+	}
+	sort.Slice(scenarios, func(i, j int) bool { return scenarios[i].Id < scenarios[j].Id })
+	return scenarios, nil
+}
+
+// Actors returns the checkpoints of the actors of a scenario this node ran, by id
+func Actors(scenarioId string) ([]Actor, error) {
+	if !enabled() {
+		return nil, nil
+	}
+	entries, err := services.List(actorPrefix + node + "/" + scenarioId + "/")
+	if err != nil {
+		return nil, err
+	}
+	saved := make([]Actor, 0, len(entries))
+	for key, value := range entries {
+		var actor Actor
+		if err := json.Unmarshal([]byte(value), &actor); err != nil {
+			log.Printf("skipping checkpoint %s: %v", key, err)
+			continue
+		}
+		saved = append(saved, actor)
+	}
+	sort.Slice(saved, func(i, j int) bool { return saved[i].Id < saved[j].Id })
+	return saved, nil
+}
+
+func actorKey(scenarioId, actorId string) string {
+	return fmt.Sprintf("%s%s/%s/%s", actorPrefix, node, scenarioId, actorId)
+}
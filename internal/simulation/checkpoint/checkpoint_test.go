@@ -0,0 +1,137 @@
+package checkpoint
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"sim-server/internal/models"
+	"sim-server/internal/simulation/actors"
+)
+
+type checkpointer struct {
+	actor Actor
+}
+
+func (c *checkpointer) Checkpoint() Actor {
+	return c.actor
+}
+
+// onNode runs the test with checkpoints enabled for a node of its own, in the local registry
+func onNode(t *testing.T, id string) {
+	previous := node
+	node = id
+	t.Cleanup(func() { node = previous })
+}
+
+func TestRoundTrip(t *testing.T) {
+	onNode(t, "round-trip")
+	scenario := Scenario{Id: "scenario-1", Target: "mock", Restart: "permanent", MaxRestarts: 2, Speed: 10, ClockStart: time.Now().UTC()}
+	driver := Actor{
+		Kind:           "driver",
+		ScenarioId:     scenario.Id,
+		Id:             "driver-1",
+		PhoneNumber:    "1111100001",
+		AccessToken:    "token",
+		State:          "en_route",
+		Lat:            28.6139,
+		Lng:            77.2090,
+		TripId:         "trip-1",
+		AcceptanceRate: 0.8,
+		TripOffer:      &models.NewTripOfferMessage{TripOffer: models.TripOffer{TripId: "trip-1", Trip: models.Trip{Id: "trip-1", Status: "accepted"}}},
+		ScheduledTrips: []*models.NewTripOfferMessage{{TripOffer: models.TripOffer{TripId: "trip-2", Trip: models.Trip{Id: "trip-2", ScheduledAt: 1700000000}}}},
+	}
+	customer := Actor{Kind: "customer", ScenarioId: scenario.Id, Id: "customer-1", State: "idle", Loop: true, LeadTime: 20 * time.Minute}
+
+	if err := SaveScenario(scenario); err != nil {
+		t.Fatal(err)
+	}
+	for _, actor := range []Actor{customer, driver} {
+		if err := Save(actor); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	scenarios, err := Scenarios()
+	if err != nil || !reflect.DeepEqual(scenarios, []Scenario{scenario}) {
+		t.Errorf("scenarios %+v, want %+v: %v", scenarios, scenario, err)
+	}
+	loaded, ok := Load(scenario.Id, driver.Id)
+	loaded.SavedAt = time.Time{}
+	if !ok || !reflect.DeepEqual(loaded, driver) {
+		t.Errorf("loaded %+v, want %+v", loaded, driver)
+	}
+	saved, err := Actors(scenario.Id)
+	if err != nil || len(saved) != 2 || saved[0].Id != customer.Id || saved[1].Id != driver.Id {
+		t.Errorf("actors %+v: %v", saved, err)
+	}
+
+	// another node sharing the registry does not see them
+	onNode(t, "other")
+	if scenarios, _ := Scenarios(); len(scenarios) != 0 {
+		t.Errorf("other node sees %+v", scenarios)
+	}
+	onNode(t, "round-trip")
+
+	// a driver that did not drain is left to recover, with its scenario
+	if err := DeleteScenario(scenario.Id, driver.Id); err != nil {
+		t.Fatal(err)
+	}
+	if scenarios, _ := Scenarios(); len(scenarios) != 1 {
+		t.Errorf("scenario of a kept actor not listed: %+v", scenarios)
+	}
+	if saved, _ := Actors(scenario.Id); len(saved) != 1 || saved[0].Id != driver.Id {
+		t.Errorf("actors %+v, want the kept driver only", saved)
+	}
+
+	if err := DeleteScenario(scenario.Id); err != nil {
+		t.Fatal(err)
+	}
+	if scenarios, _ := Scenarios(); len(scenarios) != 0 {
+		t.Errorf("deleted scenario still listed: %+v", scenarios)
+	}
+	if _, ok := Load(scenario.Id, driver.Id); ok {
+		t.Error("checkpoint of a deleted scenario still loads")
+	}
+}
+
+func TestDisabled(t *testing.T) {
+	onNode(t, "")
+	if err := SaveScenario(Scenario{Id: "disabled"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := Save(Actor{ScenarioId: "disabled", Id: "driver-1"}); err != nil {
+		t.Fatal(err)
+	}
+	onNode(t, "disabled")
+	if scenarios, _ := Scenarios(); len(scenarios) != 0 {
+		t.Errorf("saved while disabled: %+v", scenarios)
+	}
+}
+
+func TestSaveAll(t *testing.T) {
+	onNode(t, "save-all")
+	supervisor := actors.NewSupervisor("supervised")
+	defer supervisor.Stop()
+	if err := SaveScenario(Scenario{Id: "supervised"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := SaveScenario(Scenario{Id: "stopped"}); err != nil {
+		t.Fatal(err)
+	}
+	actor := actors.Spawn("driver", "save-all-driver", &checkpointer{Actor{Kind: "driver", ScenarioId: "supervised", Id: "save-all-driver"}})
+	defer actor.Stop()
+
+	saveAll()
+
+	if _, ok := Load("supervised", "save-all-driver"); !ok {
+		t.Error("actor not checkpointed")
+	}
+	runningLock.Lock()
+	_, supervised := running["supervised"]
+	_, stopped := running["stopped"]
+	runningLock.Unlock()
+	if !supervised || stopped {
+		t.Errorf("refreshing supervised %v and stopped %v, want only the supervised scenario", supervised, stopped)
+	}
+}
@@ -0,0 +1,96 @@
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock is the time of a scenario. It starts at the wall time it was created at and runs speed times
+// faster from there, so at 10x an actor sleeping for an hour wakes up after six minutes, and the
+// timestamps it sends are the ones it would have sent an hour later.
+type Clock struct {
+	speed float64
+	start time.Time
+}
+
+// Real is the wall clock, used by the actors started outside of a scenario
+var Real = &Clock{speed: 1}
+
+// New returns a clock running speed times faster than the wall clock, speeds up to 1 run at wall speed
+func New(speed float64) *Clock {
+	if speed <= 1 {
+		return Real
+	}
+	return &Clock{speed: speed, start: time.Now()}
+}
+
+// NewAt returns a clock like New that started at start, so the nodes sharing a scenario keep the same time
+func NewAt(speed float64, start time.Time) *Clock {
+	if speed <= 1 {
+		return Real
+	}
+	return &Clock{speed: speed, start: start}
+}
+
+// Start is the wall time the clock started at, zero for the wall clock
+func (c *Clock) Start() time.Time {
+	return c.start
+}
+
+// Speed is how many times faster than the wall clock the clock runs
+func (c *Clock) Speed() float64 {
+	return c.speed
+}
+
+func (c *Clock) Now() time.Time {
+	if c.speed == 1 {
+		return time.Now()
+	}
+	return c.start.Add(c.scaled(time.Since(c.start)))
+}
+
+// Sleep pauses for d of the time of the clock
+func (c *Clock) Sleep(d time.Duration) {
+	time.Sleep(c.Wall(d))
+}
+
+// After is time.After for d of the time of the clock, the channel receives the wall time
+func (c *Clock) After(d time.Duration) <-chan time.Time {
+	return time.After(c.Wall(d))
+}
+
+// Until returns the time of the clock left until t
+func (c *Clock) Until(t time.Time) time.Duration {
+	return t.Sub(c.Now())
+}
+
+// Wall converts a duration of the clock into wall time
+func (c *Clock) Wall(d time.Duration) time.Duration {
+	return time.Duration(float64(d) / c.speed)
+}
+
+func (c *Clock) scaled(wall time.Duration) time.Duration {
+	return time.Duration(float64(wall) * c.speed)
+}
+
+var (
+	clocks     = make(map[string]*Clock)
+	clocksLock sync.RWMutex
+)
+
+// Register makes c the clock of every actor of a scenario.
+func Register(scenarioId string, c *Clock) {
+	clocksLock.Lock()
+	defer clocksLock.Unlock()
+	clocks[scenarioId] = c
+}
+
+// For returns the clock of a scenario, the wall clock for unknown scenarios.
+func For(scenarioId string) *Clock {
+	clocksLock.RLock()
+	defer clocksLock.RUnlock()
+	if c, ok := clocks[scenarioId]; ok {
+		return c
+	}
+	return Real
+}
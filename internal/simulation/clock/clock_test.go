@@ -0,0 +1,87 @@
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		speed float64
+		real  bool
+	}{
+		{speed: -1, real: true},
+		{speed: 0, real: true},
+		{speed: 1, real: true},
+		{speed: 1.5},
+		{speed: 100},
+	}
+	for _, tt := range tests {
+		c := New(tt.speed)
+		if real := c == Real; real != tt.real {
+			t.Errorf("speed %v runs on the wall clock %v, want %v", tt.speed, real, tt.real)
+		}
+		if !tt.real && c.Speed() != tt.speed {
+			t.Errorf("speed %v runs at %v", tt.speed, c.Speed())
+		}
+	}
+
+	start := time.Now().Add(-time.Hour)
+	if c := NewAt(10, start); !c.Start().Equal(start) {
+		t.Errorf("started at %v, want %v", c.Start(), start)
+	}
+}
+
+func TestWall(t *testing.T) {
+	tests := []struct {
+		speed float64
+		d     time.Duration
+		wall  time.Duration
+	}{
+		{speed: 1, d: time.Minute, wall: time.Minute},
+		{speed: 10, d: time.Hour, wall: 6 * time.Minute},
+		{speed: 60, d: time.Minute, wall: time.Second},
+		{speed: 4, d: -time.Minute, wall: -15 * time.Second},
+	}
+	for _, tt := range tests {
+		if wall := New(tt.speed).Wall(tt.d); wall != tt.wall {
+			t.Errorf("%v at %vx takes %v of wall time, want %v", tt.d, tt.speed, wall, tt.wall)
+		}
+	}
+}
+
+func TestNowAndSleep(t *testing.T) {
+	const speed, nap = 1000, 5 * time.Second // five milliseconds of wall time
+	c := New(speed)
+	wallStart, start := time.Now(), c.Now()
+
+	c.Sleep(nap)
+
+	wall, elapsed := time.Since(wallStart), c.Now().Sub(start)
+	if wall < c.Wall(nap) {
+		t.Errorf("slept %v of wall time, want at least %v", wall, c.Wall(nap))
+	}
+	// the time of the clock is the wall time it took, sped up
+	if elapsed < nap || elapsed > time.Duration(float64(wall)*speed)+time.Second {
+		t.Errorf("%v went by on the clock over %v of wall time", elapsed, wall)
+	}
+	if until := c.Until(c.Now().Add(time.Hour)); until > time.Hour || until < time.Hour-time.Second {
+		t.Errorf("an hour from now is %v away", until)
+	}
+	select {
+	case <-c.After(nap):
+	case <-time.After(time.Second):
+		t.Error("After did not fire on the time of the clock")
+	}
+}
+
+func TestRegister(t *testing.T) {
+	if For("unknown") != Real {
+		t.Error("an unknown scenario does not run on the wall clock")
+	}
+	c := New(10)
+	Register("registered", c)
+	if For("registered") != c {
+		t.Error("the scenario does not run on its registered clock")
+	}
+}
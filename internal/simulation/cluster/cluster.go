@@ -0,0 +1,283 @@
+// Package cluster lets several sim-server nodes share the actors of a scenario. Every node registers
+// itself in the registry with the address of its HTTP API and how many actors it can run, and the
+// node a scenario is started on spreads it across the registered nodes by their free capacity.
+package cluster
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"sim-server/internal/services"
+	"sim-server/internal/simulation/actors"
+)
+
+const (
+	nodePrefix      = "node:"
+	placementPrefix = "placement:"
+	// nodeTTL drops the entry of a node that missed a few heartbeats
+	nodeTTL = 3 * actors.HeartbeatInterval
+	// placementTTL is how long the status of a scenario stays aggregated across its nodes
+	placementTTL   = 24 * time.Hour
+	requestTimeout = 10 * time.Second
+)
+
+var ErrNoCapacity = errors.New("not enough free capacity in the cluster")
+
+// Node is a sim-server process taking part in the cluster
+type Node struct {
+	Id        string    `json:"id"`
+	Address   string    `json:"address"`  // base URL of the HTTP API, e.g. http://10.0.0.5:8081
+	Capacity  int       `json:"capacity"` // the most actors the node runs
+	Actors    int       `json:"actors"`   // the actors it runs now
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (n Node) free() int {
+	return max(n.Capacity-n.Actors, 0)
+}
+
+// Share is the part of a scenario one node runs. The series starts are offsets into the phone
+// number series of the scenario, so the shares of the nodes do not log the same actors in.
+type Share struct {
+	NodeId              string `json:"node_id"`
+	Address             string `json:"address"`
+	NumDrivers          int    `json:"num_drivers"`
+	NumCustomers        int    `json:"num_customers"`
+	DriverSeriesStart   int    `json:"driver_series_start"`
+	CustomerSeriesStart int    `json:"customer_series_start"`
+}
+
+var (
+	self     *Node // nil until the node joined
+	left     chan struct{}
+	selfLock sync.Mutex
+)
+
+// Join registers the node and renews its entry every heartbeat with the number of actors it runs,
+// until Leave
+func Join(node Node) error {
+	selfLock.Lock()
+	self = &node
+	left = make(chan struct{})
+	done := left
+	selfLock.Unlock()
+	if err := register(); err != nil {
+		return err
+	}
+
+	go func() {
+		ticker := time.NewTicker(actors.HeartbeatInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+			if err := register(); err != nil {
+				log.Printf("node %s: failed to renew registry entry: %v", node.Id, err)
+			}
+		}
+	}()
+	log.Printf("Joined the cluster as node %s at %s", node.Id, node.Address)
+	return nil
+}
+
+// Leave removes the entry of the node, no scenario is planned onto it anymore. The node keeps its
+// id for the scenarios it still runs.
+func Leave() error {
+	selfLock.Lock()
+	if self == nil || left == nil {
+		selfLock.Unlock()
+		return nil
+	}
+	close(left)
+	left = nil
+	id := self.Id
+	selfLock.Unlock()
+	log.Printf("Leaving the cluster as node %s", id)
+	return services.Delete(nodePrefix + id)
+}
+
+func register() error {
+	node, _ := Self()
+	data, err := json.Marshal(node)
+	if err != nil {
+		return err
+	}
+	return services.Set(nodePrefix+node.Id, data, nodeTTL)
+}
+
+// Self returns this node, false when it did not join a cluster
+func Self() (Node, bool) {
+	selfLock.Lock()
+	defer selfLock.Unlock()
+	if self == nil {
+		return Node{}, false
+	}
+	node := *self
+	node.Actors = len(actors.All(""))
+	node.UpdatedAt = time.Now()
+	return node, true
+}
+
+// Nodes returns the registered nodes by id
+func Nodes() ([]Node, error) {
+	entries, err := services.List(nodePrefix)
+	if err != nil {
+		return nil, err
+	}
+	nodes := make([]Node, 0, len(entries))
+	for key, value := range entries {
+		var node Node
+		if err := json.Unmarshal([]byte(value), &node); err != nil {
+			log.Printf("skipping registry entry %s: %v", key, err)
+			continue
+		}
+		nodes = append(nodes, node)
+	}
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].Id < nodes[j].Id })
+	return nodes, nil
+}
+
+// Plan spreads the drivers and customers of a scenario across nodes in proportion to their free
+// capacity. Every node gets drivers and customers in about the ratio of the scenario.
+func Plan(nodes []Node, numDrivers, numCustomers int) ([]Share, error) {
+	free := make([]int, len(nodes))
+	totalFree := 0
+	for i, node := range nodes {
+		free[i] = node.free()
+		totalFree += free[i]
+	}
+	total := numDrivers + numCustomers
+	if total > totalFree {
+		return nil, fmt.Errorf("%w: %d actors requested, room for %d", ErrNoCapacity, total, totalFree)
+	}
+
+	quotas := apportion(total, free)
+	drivers := apportion(numDrivers, quotas)
+	var shares []Share
+	driverOffset, customerOffset := 0, 0
+	for i, node := range nodes {
+		if quotas[i] == 0 {
+			continue
+		}
+		share := Share{
+			NodeId:              node.Id,
+			Address:             node.Address,
+			NumDrivers:          drivers[i],
+			NumCustomers:        quotas[i] - drivers[i],
+			DriverSeriesStart:   driverOffset,
+			CustomerSeriesStart: customerOffset,
+		}
+		driverOffset += share.NumDrivers
+		customerOffset += share.NumCustomers
+		shares = append(shares, share)
+	}
+	return shares, nil
+}
+
+// apportion splits total in proportion to weights by the largest remainder, no part exceeds its weight
+// as long as total does not exceed the sum of the weights
+func apportion(total int, weights []int) []int {
+	parts := make([]int, len(weights))
+	sum := 0
+	for _, weight := range weights {
+		sum += weight
+	}
+	if sum == 0 {
+		return parts
+	}
+	remainders := make([]int, len(weights))
+	assigned := 0
+	for i, weight := range weights {
+		parts[i] = total * weight / sum
+		remainders[i] = total * weight % sum
+		assigned += parts[i]
+	}
+	order := make([]int, len(weights))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return remainders[order[a]] > remainders[order[b]] })
+	for _, i := range order[:total-assigned] {
+		parts[i]++
+	}
+	return parts
+}
+
+// SavePlacement records which nodes run a scenario, for any node to aggregate it
+func SavePlacement(scenarioId string, shares []Share) error {
+	data, err := json.Marshal(shares)
+	if err != nil {
+		return err
+	}
+	return services.Set(placementPrefix+scenarioId, data, placementTTL)
+}
+
+// Placement returns the shares of a scenario spread across nodes, false for scenarios run by one node only
+func Placement(scenarioId string) ([]Share, bool) {
+	value, ok := services.CheckAndGetKey(placementPrefix + scenarioId)
+	if !ok {
+		return nil, false
+	}
+	var shares []Share
+	if err := json.Unmarshal([]byte(value), &shares); err != nil {
+		log.Printf("invalid placement of scenario %s: %v", scenarioId, err)
+		return nil, false
+	}
+	return shares, true
+}
+
+// Do sends body as JSON to the HTTP API of a node and decodes the JSON response into out. Responses
+// other than 2xx fail with the error they carry.
+func Do(ctx context.Context, address, method, path string, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+	request, err := http.NewRequestWithContext(ctx, method, strings.TrimSuffix(address, "/")+path, reader)
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	data, err := io.ReadAll(response.Body)
+	if err != nil {
+		return err
+	}
+	if response.StatusCode/100 != 2 {
+		var failure struct {
+			Error string `json:"error"`
+		}
+		if json.Unmarshal(data, &failure) == nil && failure.Error != "" {
+			return fmt.Errorf("%s %s: %s", method, path, failure.Error)
+		}
+		return fmt.Errorf("%s %s: %s", method, path, response.Status)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(data, out)
+}
@@ -0,0 +1,102 @@
+package cluster
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestApportion(t *testing.T) {
+	tests := []struct {
+		name    string
+		total   int
+		weights []int
+		parts   []int
+	}{
+		{name: "exact", total: 6, weights: []int{2, 2, 2}, parts: []int{2, 2, 2}},
+		{name: "largest remainder", total: 7, weights: []int{5, 3, 2}, parts: []int{4, 2, 1}},
+		{name: "equal remainders go in order", total: 2, weights: []int{1, 1, 1}, parts: []int{1, 1, 0}},
+		{name: "one left over", total: 10, weights: []int{1, 1, 1}, parts: []int{4, 3, 3}},
+		{name: "zero weight gets nothing", total: 5, weights: []int{0, 5}, parts: []int{0, 5}},
+		{name: "nothing to split", total: 0, weights: []int{3, 4}, parts: []int{0, 0}},
+		{name: "no weight at all", total: 3, weights: []int{0, 0}, parts: []int{0, 0}},
+		{name: "no weights", total: 5, weights: nil, parts: []int{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if parts := apportion(tt.total, tt.weights); !reflect.DeepEqual(parts, tt.parts) {
+				t.Errorf("apportion(%d, %v) = %v, want %v", tt.total, tt.weights, parts, tt.parts)
+			}
+		})
+	}
+}
+
+func TestPlan(t *testing.T) {
+	tests := []struct {
+		name         string
+		nodes        []Node
+		numDrivers   int
+		numCustomers int
+		shares       []Share
+		err          error
+	}{
+		{
+			name: "by free capacity",
+			nodes: []Node{
+				{Id: "a", Capacity: 10},
+				{Id: "b", Capacity: 10, Actors: 5},
+				{Id: "full", Capacity: 5, Actors: 5},
+			},
+			numDrivers:   6,
+			numCustomers: 3,
+			shares: []Share{
+				{NodeId: "a", NumDrivers: 4, NumCustomers: 2},
+				{NodeId: "b", NumDrivers: 2, NumCustomers: 1, DriverSeriesStart: 4, CustomerSeriesStart: 2},
+			},
+		},
+		{
+			name:         "remainders",
+			nodes:        []Node{{Id: "a", Capacity: 1}, {Id: "b", Capacity: 1}, {Id: "c", Capacity: 1}},
+			numDrivers:   1,
+			numCustomers: 1,
+			shares: []Share{
+				{NodeId: "a", NumDrivers: 1},
+				{NodeId: "b", NumCustomers: 1, DriverSeriesStart: 1},
+			},
+		},
+		{
+			name:         "overloaded node",
+			nodes:        []Node{{Id: "a", Capacity: 4, Actors: 9}, {Id: "b", Capacity: 4}},
+			numDrivers:   2,
+			numCustomers: 2,
+			shares:       []Share{{NodeId: "b", NumDrivers: 2, NumCustomers: 2}},
+		},
+		{
+			name:         "not enough room",
+			nodes:        []Node{{Id: "a", Capacity: 3}},
+			numDrivers:   2,
+			numCustomers: 2,
+			err:          ErrNoCapacity,
+		},
+		{
+			name:         "no nodes",
+			numDrivers:   1,
+			numCustomers: 1,
+			err:          ErrNoCapacity,
+		},
+		{
+			name: "no nodes and no actors",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			shares, err := Plan(tt.nodes, tt.numDrivers, tt.numCustomers)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Plan returned %v, want %v", err, tt.err)
+			}
+			if !reflect.DeepEqual(shares, tt.shares) {
+				t.Errorf("shares %+v, want %+v", shares, tt.shares)
+			}
+		})
+	}
+}
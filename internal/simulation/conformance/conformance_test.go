@@ -0,0 +1,435 @@
+// Package conformance checks the frames the simulated drivers and customers send against the mock backend.
+package conformance
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"sim-server/config"
+	"sim-server/internal/mockbackend"
+	"sim-server/internal/models"
+	"sim-server/internal/services"
+	"sim-server/internal/simulation/clock"
+	"sim-server/internal/simulation/customers"
+	"sim-server/internal/simulation/drivers"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// the scenario clock runs a trip of the default scripts in about half a second
+	speed            = 100
+	lifecycleTimeout = 10 * time.Second
+	settleTime       = 200 * time.Millisecond
+	// the fake backend routes are straight lines of five segments
+	routePoints = 6
+)
+
+// sent is the expected command of one websocket frame and the keys of its payload
+type sent struct {
+	command models.Command
+	keys    []string
+}
+
+func location() sent {
+	return sent{models.DriverLocation, []string{"rawLocation", "vehicleCategoryId"}}
+}
+
+func locations(n int) []sent {
+	frames := make([]sent, n)
+	for i := range frames {
+		frames[i] = location()
+	}
+	return frames
+}
+
+func tripAction(command models.Command) sent {
+	return sent{command, []string{"trip_id"}}
+}
+
+func rating(command models.Command) sent {
+	return sent{command, []string{"trip_id", "rating"}}
+}
+
+func tripRequest(command models.Command, scheduled bool) sent {
+	keys := []string{"origin", "origin_name", "destination", "destination_name"}
+	if command == models.ConfirmTrip {
+		keys = append(keys, "category_id")
+	}
+	if scheduled {
+		keys = append(keys, "scheduled_at")
+	}
+	return sent{command, keys}
+}
+
+func frames(groups ...[]sent) []sent {
+	var all []sent
+	for _, group := range groups {
+		all = append(all, group...)
+	}
+	return all
+}
+
+// completedTrip is what a driver sends from the first location ping to rating the customer
+func completedTrip() []sent {
+	return frames(
+		[]sent{location(), tripAction(models.AcceptTrip)},
+		locations(routePoints+1),
+		[]sent{tripAction(models.ArrivedForPickup), tripAction(models.StartTrip)},
+		locations(routePoints+1),
+		[]sent{tripAction(models.CompleteTrip), rating(models.RateCustomer)},
+	)
+}
+
+func TestProtocolConformance(t *testing.T) {
+	tests := []struct {
+		name           string
+		authMode       string
+		acceptanceRate float64
+		estimate       bool
+		expireTokens   bool             // the backend drops every token once the driver is online
+		scheduleIn     time.Duration    // in the time of the scenario
+		manual         []drivers.Action // steps the test takes for the driver, the customer then rates by hand too
+		driver         []sent
+		customer       []sent
+	}{
+		{
+			name:           "trip now",
+			acceptanceRate: 1,
+			driver:         completedTrip(),
+			customer: []sent{
+				tripRequest(models.ConfirmTrip, false),
+				rating(models.RateDriver),
+			},
+		},
+		{
+			name:           "estimate before booking",
+			acceptanceRate: 1,
+			estimate:       true,
+			driver:         completedTrip(),
+			customer: []sent{
+				tripRequest(models.RequestEstimate, false),
+				tripRequest(models.ConfirmTrip, false),
+				rating(models.RateDriver),
+			},
+		},
+		{
+			name:           "otp login",
+			authMode:       config.AuthModeOtp,
+			acceptanceRate: 1,
+			driver:         completedTrip(),
+			customer: []sent{
+				tripRequest(models.ConfirmTrip, false),
+				rating(models.RateDriver),
+			},
+		},
+		{
+			name:           "expired tokens",
+			acceptanceRate: 1,
+			expireTokens:   true,
+			driver:         frames([]sent{location(), {models.Sync, nil}}, completedTrip()[1:]),
+			customer: []sent{
+				tripRequest(models.ConfirmTrip, false),
+				rating(models.RateDriver),
+			},
+		},
+		{
+			name:           "rejected offer",
+			acceptanceRate: 0,
+			driver:         []sent{location(), tripAction(models.RejectTrip)},
+			customer:       []sent{tripRequest(models.ConfirmTrip, false)},
+		},
+		{
+			name:           "scheduled trip",
+			acceptanceRate: 0, // pre-assigned scheduled offers are honoured regardless
+			scheduleIn:     time.Minute,
+			driver:         completedTrip(),
+			customer: []sent{
+				tripRequest(models.ConfirmTrip, true),
+				rating(models.RateDriver),
+			},
+		},
+		{
+			name:   "manual driver",
+			manual: []drivers.Action{drivers.ActionAccept, drivers.ActionArrive, drivers.ActionStart, drivers.ActionComplete, drivers.ActionRate},
+			driver: []sent{
+				location(), tripAction(models.AcceptTrip),
+				location(), tripAction(models.ArrivedForPickup), tripAction(models.StartTrip),
+				location(), tripAction(models.CompleteTrip), rating(models.RateCustomer),
+			},
+			customer: []sent{
+				tripRequest(models.ConfirmTrip, false),
+				rating(models.RateDriver),
+			},
+		},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// the actors and the backend share a fast clock, the actors follow the default scripts on it
+			scenarioId := "conformance-" + tt.name
+			scenarioClock := clock.New(speed)
+			clock.Register(scenarioId, scenarioClock)
+			backend := mockbackend.NewServer()
+			backend.UseClock(scenarioClock.Now, func(d time.Duration, f func()) mockbackend.Timer {
+				return time.AfterFunc(scenarioClock.Wall(d), f)
+			})
+			backend.RecordReceived()
+			server := httptest.NewServer(backend.Handler())
+			defer server.Close()
+			target := config.Target{
+				Name:         "mock",
+				BaseURL:      server.URL,
+				WebsocketURL: "ws" + strings.TrimPrefix(server.URL, "http"),
+				AuthMode:     tt.authMode,
+				// the mock acknowledges a message id it already handled without handling it again
+				DedupMessageIds: true,
+			}
+
+			driver := loginDriver(t, target, 1000+i)
+			customer := loginCustomer(t, target, 2000+i)
+
+			manual := len(tt.manual) > 0
+			drivers.NewSimulatedDriver(driver, target, scenarioId, 28.6139, 77.2090, tt.acceptanceRate, manual)
+			// reading the state throughout the lifecycle shows unsynchronised access up under -race
+			stopPolling := pollSnapshots(driver.Id, customer.Id)
+			defer stopPolling()
+			if err := drivers.CheckAndGoOnline(driver.Id); err != nil {
+				t.Fatal(err)
+			}
+			if err := drivers.Connect(driver.Id); err != nil {
+				t.Fatal(err)
+			}
+			awaitSent(backend, driver.Id, []sent{location()})
+			if tt.expireTokens {
+				// the driver is dropped with 4401 and the customer's token is rejected with 401,
+				// both have to log in again to get through the trip
+				backend.ExpireTokens()
+				awaitSent(backend, driver.Id, []sent{location(), {models.Sync, nil}})
+			}
+
+			customers.NewSimulatedCustomer(customer, target, scenarioId, false, 0, 0, manual)
+			if err := customers.Connect(customer.Id); err != nil {
+				t.Fatal(err)
+			}
+			if tt.estimate {
+				customers.RequestEstimate(customer.Id, 28.6200, 77.2100, 28.6500, 77.2500)
+				awaitSent(backend, customer.Id, []sent{tripRequest(models.RequestEstimate, false)})
+			}
+			var scheduledAt int64
+			if tt.scheduleIn > 0 {
+				scheduledAt = scenarioClock.Now().Add(tt.scheduleIn).Unix()
+			}
+			customers.ConfirmTrip(customer.Id, 28.6200, 77.2100, 28.6500, 77.2500, scheduledAt)
+			for _, action := range tt.manual {
+				control(t, driver.Id, action)
+			}
+			if manual {
+				retry(t, "rating the driver", func() (bool, error) { return customers.RateDriver(customer.Id, "", 5) })
+			}
+
+			awaitSent(backend, driver.Id, tt.driver)
+			awaitSent(backend, customer.Id, tt.customer)
+			// anything but a location ping sent past the expected frames shows up as a mismatch
+			time.Sleep(settleTime)
+			driverFrames := backend.Received(driver.Id)
+			customerFrames := backend.Received(customer.Id)
+
+			assertFrames(t, "driver", driverFrames, tt.driver)
+			assertFrames(t, "customer", customerFrames, tt.customer)
+			assertSameTrip(t, append(driverFrames, customerFrames...))
+			assertIdle(t, driver.Id, customer.Id)
+		})
+	}
+}
+
+func loginDriver(t *testing.T, target config.Target, phoneNumber int) models.Driver {
+	t.Helper()
+	response, err := services.DriverAuth(target)(context.Background(), target, strconv.Itoa(phoneNumber))
+	if err != nil || !response.Status {
+		t.Fatalf("driver login: %v %v", err, response)
+	}
+	data := response.Data.(map[string]interface{})
+	return models.Driver{
+		Id:          data["id"].(string),
+		Name:        data["name"].(string),
+		PhoneNumber: data["phone_number"].(string),
+		AccessToken: data["access_token"].(string),
+	}
+}
+
+func loginCustomer(t *testing.T, target config.Target, phoneNumber int) models.Customer {
+	t.Helper()
+	response, err := services.CustomerAuth(target)(context.Background(), target, strconv.Itoa(phoneNumber))
+	if err != nil || !response.Status {
+		t.Fatalf("customer login: %v %v", err, response)
+	}
+	data := response.Data.(map[string]interface{})
+	return models.Customer{
+		Id:          data["id"].(string),
+		Name:        data["name"].(string),
+		PhoneNumber: data["phone_number"].(string),
+		AccessToken: data["access_token"].(string),
+	}
+}
+
+// control runs a manual step, waiting for the trip offer to reach the driver first
+func control(t *testing.T, driverId string, action drivers.Action) {
+	t.Helper()
+	retry(t, string(action), func() (bool, error) { return drivers.Control(driverId, action, "", 4.5) })
+}
+
+// retry runs a manual step until the actor reached the state the step needs
+func retry(t *testing.T, step string, run func() (bool, error)) {
+	t.Helper()
+	deadline := time.Now().Add(lifecycleTimeout)
+	for {
+		ok, err := run()
+		if ok && err == nil {
+			return
+		}
+		if status.Code(err) != codes.FailedPrecondition || time.Now().After(deadline) {
+			t.Fatalf("%s: %v %v", step, ok, err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// pollSnapshots reads the state of the driver and the customer until it is stopped
+func pollSnapshots(driverId, customerId string) (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case <-time.After(5 * time.Millisecond):
+				drivers.Snapshot(driverId)
+				customers.Snapshot(customerId)
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+// assertIdle checks the driver and the customer are available again once the lifecycle ended
+func assertIdle(t *testing.T, driverId, customerId string) {
+	t.Helper()
+	driver, err := drivers.Snapshot(driverId)
+	if err != nil || driver.State != drivers.StateIdle || driver.TripId != "" {
+		t.Errorf("driver is %s with trip %q: %v", driver.State, driver.TripId, err)
+	}
+	customer, err := customers.Snapshot(customerId)
+	if err != nil || customer.State != customers.StateIdle || customer.TripId != "" {
+		t.Errorf("customer is %s with trip %q: %v", customer.State, customer.TripId, err)
+	}
+}
+
+// matched returns how many of the expected frames were sent, in order. The driver pings its location
+// every PingLocation while online, on the scenario clock that lands anywhere in a trip, so location
+// frames beyond the expected ones are skipped. It stops at the first frame that does not fit.
+func matched(got []models.IncomingMessage, want []sent) (n int, unexpected int) {
+	for i, message := range got {
+		switch {
+		case n < len(want) && message.Command == want[n].command:
+			n++
+		case message.Command != models.DriverLocation:
+			return n, i
+		}
+	}
+	return n, -1
+}
+
+// awaitSent waits until the backend received the expected frames from the user, or a frame that does not fit
+func awaitSent(backend *mockbackend.Server, userId string, want []sent) {
+	deadline := time.Now().Add(lifecycleTimeout)
+	for {
+		n, unexpected := matched(backend.Received(userId), want)
+		if n == len(want) || unexpected >= 0 || time.Now().After(deadline) {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func assertFrames(t *testing.T, actor string, got []models.IncomingMessage, want []sent) {
+	t.Helper()
+	n, unexpected := matched(got, want)
+	if unexpected >= 0 {
+		t.Fatalf("%s frame %d is %s, want %s: %v", actor, unexpected, got[unexpected].Command, expected(want, n), commands(got))
+	}
+	if n != len(want) {
+		t.Fatalf("%s sent %d of %d frames: %v", actor, n, len(want), commands(got))
+	}
+	i := 0
+	for f, message := range got {
+		if message.MessageId == "" {
+			t.Errorf("%s frame %d (%s) has no message id", actor, f, message.Command)
+		}
+		if i == len(want) || message.Command != want[i].command {
+			// a location ping of its own
+			continue
+		}
+		var payload map[string]json.RawMessage
+		if err := json.Unmarshal(message.Payload, &payload); err != nil {
+			t.Fatalf("%s frame %d (%s) payload: %v", actor, f, message.Command, err)
+		}
+		keys := make([]string, 0, len(payload))
+		for key := range payload {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		wantKeys := append([]string{}, want[i].keys...)
+		sort.Strings(wantKeys)
+		if !reflect.DeepEqual(keys, wantKeys) {
+			t.Errorf("%s frame %d (%s) payload keys %v, want %v", actor, f, message.Command, keys, wantKeys)
+		}
+		i++
+	}
+}
+
+// expected names the frame the n-th match waited for
+func expected(want []sent, n int) models.Command {
+	if n == len(want) {
+		return "nothing more"
+	}
+	return want[n].command
+}
+
+// assertSameTrip checks every trip action of a lifecycle refers to the one trip that was booked
+func assertSameTrip(t *testing.T, messages []models.IncomingMessage) {
+	t.Helper()
+	tripId := ""
+	for _, message := range messages {
+		var payload struct {
+			TripId *string `json:"trip_id"`
+		}
+		if json.Unmarshal(message.Payload, &payload) != nil || payload.TripId == nil {
+			continue
+		}
+		switch {
+		case *payload.TripId == "":
+			t.Errorf("%s has an empty trip id", message.Command)
+		case tripId == "":
+			tripId = *payload.TripId
+		case *payload.TripId != tripId:
+			t.Errorf("%s refers to trip %s, want %s", message.Command, *payload.TripId, tripId)
+		}
+	}
+}
+
+func commands(messages []models.IncomingMessage) []models.Command {
+	commands := make([]models.Command, len(messages))
+	for i, message := range messages {
+		commands[i] = message.Command
+	}
+	return commands
+}
@@ -0,0 +1,235 @@
+package control
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net"
+
+	pb "sim-server/internal/genserver/proto"
+	"sim-server/internal/simulation/actors"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// ActorIdKey is the request metadata key naming the actor a call is for
+const ActorIdKey = "actor-id"
+
+// Server serves the SimulatedDriver, SimulatedCustomer and GenServer services of every actor on one
+// address, routing each call to the actor named by the actor-id metadata.
+type Server struct {
+	grpc *grpc.Server
+}
+
+func NewServer() *Server {
+	s := grpc.NewServer()
+	pb.RegisterSimulatedDriverServer(s, &driverRouter{})
+	pb.RegisterSimulatedCustomerServer(s, &customerRouter{})
+	pb.RegisterGenServerServer(s, &genServer{})
+	return &Server{grpc: s}
+}
+
+// ListenAndServe serves until Stop
+func (s *Server) ListenAndServe(address string) error {
+	lis, err := net.Listen("tcp", address)
+	if err != nil {
+		return err
+	}
+	defer lis.Close()
+
+	log.Printf("Control server listening on %s", lis.Addr())
+	return s.grpc.Serve(lis)
+}
+
+// Stop lets the calls in flight finish and closes the listener
+func (s *Server) Stop() {
+	s.grpc.GracefulStop()
+}
+
+type driverRouter struct {
+	pb.UnimplementedSimulatedDriverServer
+}
+
+func (r *driverRouter) GoOnline(ctx context.Context, req *pb.GoOnlineRequest) (*pb.GoOnlineResponse, error) {
+	return route(ctx, func(server pb.SimulatedDriverServer) (*pb.GoOnlineResponse, error) {
+		return server.GoOnline(ctx, req)
+	})
+}
+
+func (r *driverRouter) InitConnection(ctx context.Context, req *pb.InitConnectionRequest) (*pb.InitConnectionResponse, error) {
+	return route(ctx, func(server pb.SimulatedDriverServer) (*pb.InitConnectionResponse, error) {
+		return server.InitConnection(ctx, req)
+	})
+}
+
+func (r *driverRouter) SetLocation(ctx context.Context, req *pb.SetLocationRequest) (*pb.SetLocationResponse, error) {
+	return route(ctx, func(server pb.SimulatedDriverServer) (*pb.SetLocationResponse, error) {
+		return server.SetLocation(ctx, req)
+	})
+}
+
+func (r *driverRouter) IsAlive(ctx context.Context, req *pb.IsAliveRequest) (*pb.IsAliveResponse, error) {
+	return route(ctx, func(server pb.SimulatedDriverServer) (*pb.IsAliveResponse, error) {
+		return server.IsAlive(ctx, req)
+	})
+}
+
+func (r *driverRouter) AcceptTrip(ctx context.Context, req *pb.DriverAcceptTripRequest) (*pb.DriverAcceptTripResponse, error) {
+	return route(ctx, func(server pb.SimulatedDriverServer) (*pb.DriverAcceptTripResponse, error) {
+		return server.AcceptTrip(ctx, req)
+	})
+}
+
+func (r *driverRouter) RejectTrip(ctx context.Context, req *pb.DriverRejectTripRequest) (*pb.DriverRejectTripResponse, error) {
+	return route(ctx, func(server pb.SimulatedDriverServer) (*pb.DriverRejectTripResponse, error) {
+		return server.RejectTrip(ctx, req)
+	})
+}
+
+func (r *driverRouter) DriverArrival(ctx context.Context, req *pb.DriverArrivalRequest) (*pb.DriverArrivalResponse, error) {
+	return route(ctx, func(server pb.SimulatedDriverServer) (*pb.DriverArrivalResponse, error) {
+		return server.DriverArrival(ctx, req)
+	})
+}
+
+func (r *driverRouter) StartTrip(ctx context.Context, req *pb.DriverStartTripRequest) (*pb.DriverStartTripResponse, error) {
+	return route(ctx, func(server pb.SimulatedDriverServer) (*pb.DriverStartTripResponse, error) {
+		return server.StartTrip(ctx, req)
+	})
+}
+
+func (r *driverRouter) CompleteTrip(ctx context.Context, req *pb.DriverCompleteTripRequest) (*pb.DriverCompleteTripResponse, error) {
+	return route(ctx, func(server pb.SimulatedDriverServer) (*pb.DriverCompleteTripResponse, error) {
+		return server.CompleteTrip(ctx, req)
+	})
+}
+
+func (r *driverRouter) RateCustomer(ctx context.Context, req *pb.RatingRequest) (*pb.RatingResponse, error) {
+	return route(ctx, func(server pb.SimulatedDriverServer) (*pb.RatingResponse, error) {
+		return server.RateCustomer(ctx, req)
+	})
+}
+
+type customerRouter struct {
+	pb.UnimplementedSimulatedCustomerServer
+}
+
+func (r *customerRouter) InitConnection(ctx context.Context, req *pb.InitConnectionRequest) (*pb.InitConnectionResponse, error) {
+	return route(ctx, func(server pb.SimulatedCustomerServer) (*pb.InitConnectionResponse, error) {
+		return server.InitConnection(ctx, req)
+	})
+}
+
+func (r *customerRouter) SetLocation(ctx context.Context, req *pb.SetLocationRequest) (*pb.SetLocationResponse, error) {
+	return route(ctx, func(server pb.SimulatedCustomerServer) (*pb.SetLocationResponse, error) {
+		return server.SetLocation(ctx, req)
+	})
+}
+
+func (r *customerRouter) IsAlive(ctx context.Context, req *pb.IsAliveRequest) (*pb.IsAliveResponse, error) {
+	return route(ctx, func(server pb.SimulatedCustomerServer) (*pb.IsAliveResponse, error) {
+		return server.IsAlive(ctx, req)
+	})
+}
+
+func (r *customerRouter) TripEstimate(ctx context.Context, req *pb.TripEstimateRequest) (*pb.TripEstimateResponse, error) {
+	return route(ctx, func(server pb.SimulatedCustomerServer) (*pb.TripEstimateResponse, error) {
+		return server.TripEstimate(ctx, req)
+	})
+}
+
+func (r *customerRouter) ConfirmTrip(ctx context.Context, req *pb.ConfirmTripRequest) (*pb.ConfirmTripResponse, error) {
+	return route(ctx, func(server pb.SimulatedCustomerServer) (*pb.ConfirmTripResponse, error) {
+		return server.ConfirmTrip(ctx, req)
+	})
+}
+
+func (r *customerRouter) RateDriver(ctx context.Context, req *pb.RatingRequest) (*pb.RatingResponse, error) {
+	return route(ctx, func(server pb.SimulatedCustomerServer) (*pb.RatingResponse, error) {
+		return server.RateDriver(ctx, req)
+	})
+}
+
+type genServer struct {
+	pb.UnimplementedGenServerServer
+}
+
+func (g *genServer) HandleCall(ctx context.Context, req *pb.HandleCallRequest) (*pb.HandleCallResponse, error) {
+	actorId := actorIdOf(ctx)
+	if actorId == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "missing %s metadata", ActorIdKey)
+	}
+	result, err := actors.HandleCall(ctx, actorId, req.GetAction(), req.GetPayload())
+	if err != nil {
+		return nil, statusOf(err)
+	}
+	return &pb.HandleCallResponse{Result: result}, nil
+}
+
+func (g *genServer) HandleCast(ctx context.Context, req *pb.HandleCastRequest) (*pb.HandleCastResponse, error) {
+	actorId := actorIdOf(ctx)
+	if actorId == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "missing %s metadata", ActorIdKey)
+	}
+	if err := actors.HandleCast(actorId, req.GetAction(), req.GetPayload()); err != nil {
+		return nil, statusOf(err)
+	}
+	return &pb.HandleCastResponse{}, nil
+}
+
+// statusOf maps the errors of the actor runtime to gRPC status codes
+func statusOf(err error) error {
+	switch {
+	case errors.Is(err, actors.ErrNotRunning):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, actors.ErrUnknownAction), errors.Is(err, actors.ErrBadPayload):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, actors.ErrStopped):
+		return status.Error(codes.Unavailable, err.Error())
+	case errors.Is(err, actors.ErrMailboxFull):
+		return status.Error(codes.ResourceExhausted, err.Error())
+	case errors.Is(err, actors.ErrPanic):
+		return status.Error(codes.Internal, err.Error())
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return status.FromContextError(err).Err()
+	}
+	return err
+}
+
+// route runs handle in the mailbox of the actor named by the metadata of ctx, so calls from
+// outside the process are serialized with the ones made by the simulation itself
+func route[S any, R any](ctx context.Context, handle func(server S) (R, error)) (response R, err error) {
+	actorId := actorIdOf(ctx)
+	if actorId == "" {
+		return response, status.Errorf(codes.InvalidArgument, "missing %s metadata", ActorIdKey)
+	}
+	actor, ok := actors.Lookup(actorId)
+	if !ok {
+		return response, status.Errorf(codes.NotFound, "no running actor %s", actorId)
+	}
+	server, ok := actor.Server.(S)
+	if !ok {
+		return response, status.Errorf(codes.FailedPrecondition, "%s does not serve this method", actor)
+	}
+
+	if callErr := actor.Call(ctx, func() {
+		response, err = handle(server)
+	}); callErr != nil {
+		return response, statusOf(callErr)
+	}
+	return response, err
+}
+
+func actorIdOf(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	if values := md.Get(ActorIdKey); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
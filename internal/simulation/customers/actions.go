@@ -0,0 +1,92 @@
+package customers
+
+import (
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+
+	"sim-server/internal/simulation/actors"
+	"sim-server/internal/simulation/fsm"
+)
+
+// Actions a customer takes through the GenServer service, see actors.RegisterCall and actors.RegisterCast
+func init() {
+	actors.RegisterCall(actorKind, "state", func(sim *SimulatedCustomer, _ struct{}) (interface{}, error) {
+		return sim.snapshot(), nil
+	})
+	actors.RegisterCast(actorKind, "set_booking_rates", func(sim *SimulatedCustomer, payload bookingRatesPayload) {
+		if payload.ModifyRate != nil {
+			sim.modifyRate = *payload.ModifyRate
+		}
+		if payload.CancelRate != nil {
+			sim.cancelRate = *payload.CancelRate
+		}
+	})
+	actors.RegisterCast(actorKind, "set_loop", func(sim *SimulatedCustomer, payload loopPayload) {
+		sim.loop = payload.Loop
+	})
+	actors.RegisterCast(actorKind, "set_manual", func(sim *SimulatedCustomer, payload manualPayload) {
+		sim.manual = payload.Manual
+	})
+}
+
+// CustomerState is a consistent copy of the state of a customer, returned by Snapshot and the state call
+type CustomerState struct {
+	Id         string    `json:"id"`
+	ScenarioId string    `json:"scenario_id,omitempty"`
+	State      fsm.State `json:"state"`
+	StateSince time.Time `json:"state_since"`
+	Lat        float64   `json:"lat"`
+	Lng        float64   `json:"lng"`
+	TripId     string    `json:"trip_id,omitempty"`
+	Loop       bool      `json:"loop"`
+	ModifyRate float64   `json:"modify_rate"`
+	CancelRate float64   `json:"cancel_rate"`
+	Manual     bool      `json:"manual"`
+	Connected  bool      `json:"connected"`
+}
+
+// bookingRatesPayload leaves the rates it does not set unchanged
+type bookingRatesPayload struct {
+	ModifyRate *float64 `json:"modify_rate"`
+	CancelRate *float64 `json:"cancel_rate"`
+}
+
+func (p bookingRatesPayload) Validate() error {
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.ModifyRate, validation.Min(0.0), validation.Max(1.0)),
+		validation.Field(&p.CancelRate, validation.Min(0.0), validation.Max(1.0)),
+	)
+}
+
+type loopPayload struct {
+	Loop bool `json:"loop"`
+}
+
+type manualPayload struct {
+	Manual bool `json:"manual"`
+}
+
+// Snapshot copies the state of a running customer in its mailbox, so it is safe to call from anywhere.
+func Snapshot(customerId string) (state CustomerState, err error) {
+	err = call(customerId, func(sim *SimulatedCustomer) { state = sim.snapshot() })
+	return state, err
+}
+
+func (sim *SimulatedCustomer) snapshot() CustomerState {
+	state, since := sim.machine.Current()
+	return CustomerState{
+		Id:         sim.customer.Id,
+		ScenarioId: sim.scenarioId,
+		State:      state,
+		StateSince: since,
+		Lat:        sim.lat,
+		Lng:        sim.lng,
+		TripId:     sim.tripId,
+		Loop:       sim.loop,
+		ModifyRate: sim.modifyRate,
+		CancelRate: sim.cancelRate,
+		Manual:     sim.manual,
+		Connected:  sim.conn != nil && sim.conn.Connected(),
+	}
+}
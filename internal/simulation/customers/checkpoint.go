@@ -0,0 +1,76 @@
+package customers
+
+import (
+	"fmt"
+	"log"
+
+	"sim-server/config"
+	"sim-server/internal/models"
+	"sim-server/internal/simulation/checkpoint"
+)
+
+// Checkpoint returns what it takes to start the customer again after a restart, it runs in the mailbox
+func (sim *SimulatedCustomer) Checkpoint() checkpoint.Actor {
+	state, _ := sim.machine.Current()
+	return checkpoint.Actor{
+		Kind:           actorKind,
+		ScenarioId:     sim.scenarioId,
+		Id:             sim.customer.Id,
+		Name:           sim.customer.Name,
+		PhoneNumber:    sim.customer.PhoneNumber,
+		AccessToken:    sim.session.Token(),
+		State:          state,
+		Lat:            sim.lat,
+		Lng:            sim.lng,
+		TripId:         sim.tripId,
+		Manual:         sim.manual,
+		Loop:           sim.loop,
+		ModifyRate:     sim.modifyRate,
+		CancelRate:     sim.cancelRate,
+		OriginLat:      sim.originLat,
+		OriginLng:      sim.originLng,
+		DestinationLat: sim.destinationLat,
+		DestinationLng: sim.destinationLng,
+		LeadTime:       sim.leadTime,
+	}
+}
+
+// Restore starts a customer again from its checkpoint with the token it had. Once it is connected it
+// syncs with the backend and carries on with its bookings, see resume. A customer that is already
+// running is left as it is, its state is newer than the checkpoint.
+func Restore(saved checkpoint.Actor, target config.Target) error {
+	customer := models.Customer{Id: saved.Id, Name: saved.Name, PhoneNumber: saved.PhoneNumber, AccessToken: saved.AccessToken}
+	if !newSimulatedCustomer(customer, target, saved.ScenarioId, saved.Loop, saved.ModifyRate, saved.CancelRate, saved.Manual) {
+		return fmt.Errorf("customer %s is already running, not restoring its checkpoint", saved.Id)
+	}
+	return call(saved.Id, func(sim *SimulatedCustomer) {
+		sim.machine.Reset(saved.State)
+		sim.tripId = saved.TripId
+		sim.lat, sim.lng = saved.Lat, saved.Lng
+		sim.originLat, sim.originLng = saved.OriginLat, saved.OriginLng
+		sim.destinationLat, sim.destinationLng = saved.DestinationLat, saved.DestinationLng
+		sim.leadTime = saved.LeadTime
+		sim.recovering = true
+	})
+}
+
+// resume carries on with the scenario after the first sync of a restored customer: a completed trip
+// is rated, and a looping customer without a trip books the next one
+func (sim *SimulatedCustomer) resume() {
+	state, _ := sim.machine.Current()
+	log.Printf("customer %s: resuming in %s", sim.customer.Id, state)
+	if sim.manual {
+		return
+	}
+	switch {
+	case sim.machine.Is(StateRating):
+		sim.rateDriver(models.FloatBetweenZeroToOne() * 5)
+		if sim.loop {
+			sim.rebook(true)
+		}
+	case sim.machine.Is(StateIdle, StateEstimating) && sim.loop && sim.originLat != 0:
+		// the trip ended or the next booking was due while the customer was down
+		sim.machine.Reset(StateIdle)
+		sim.rebook(true)
+	}
+}
@@ -0,0 +1,32 @@
+package customers
+
+// Drain stops the customer from booking again and cancels the trips no driver is on the way to yet,
+// scheduled ones included. A trip a driver is on goes on, see actors.Shutdown.
+func (sim *SimulatedCustomer) Drain() {
+	sim.draining = true
+	if sim.tripId == "" {
+		return
+	}
+	if sim.machine.Is(StateSearching) || sim.machine.Is(StateMatched) && sim.scheduledLater() {
+		sim.CancelTrip(sim.tripId)
+	}
+}
+
+// Drained reports whether the customer is done with its trip
+func (sim *SimulatedCustomer) Drained() bool {
+	return sim.machine.Is(StateIdle, StateEstimating)
+}
+
+// SignOff cancels the trip still in progress
+func (sim *SimulatedCustomer) SignOff() {
+	sim.draining = true
+	if sim.tripId != "" && !sim.machine.Is(StateRating) {
+		sim.CancelTrip(sim.tripId)
+	}
+}
+
+// scheduledLater reports whether the current trip is booked for later
+func (sim *SimulatedCustomer) scheduledLater() bool {
+	booking := sim.confirmTripData
+	return booking != nil && booking.Id == sim.tripId && booking.ScheduledAt > sim.clock.Now().Unix()
+}
@@ -0,0 +1,32 @@
+package customers
+
+import (
+	"testing"
+	"time"
+
+	"sim-server/internal/models"
+	"sim-server/internal/simulation/clock"
+)
+
+func TestScheduledLaterAfterModification(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name   string
+		booked time.Time
+		moved  time.Time
+		later  bool
+	}{
+		{name: "moved closer", booked: now.Add(time.Hour), moved: now.Add(-time.Minute)},
+		{name: "moved later", booked: now.Add(-time.Minute), moved: now.Add(time.Hour), later: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sim := &SimulatedCustomer{clock: clock.Real, tripId: "trip"}
+			sim.handleConfirmTrip(&models.ConfirmTripMessage{Trip: models.Trip{Id: "trip", ScheduledAt: tt.booked.Unix()}})
+			sim.handleTripModification(&models.ConfirmTripMessage{Trip: models.Trip{Id: "trip", ScheduledAt: tt.moved.Unix()}})
+			if later := sim.scheduledLater(); later != tt.later {
+				t.Errorf("scheduled later %v, want %v", later, tt.later)
+			}
+		})
+	}
+}
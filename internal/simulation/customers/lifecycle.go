@@ -0,0 +1,33 @@
+package customers
+
+import "sim-server/internal/simulation/fsm"
+
+// States of a customer, reported by the state call
+const (
+	StateIdle       fsm.State = "idle"
+	StateEstimating fsm.State = "estimating" // an estimate was requested and not answered yet
+	StateSearching  fsm.State = "searching"  // the trip is booked, no driver accepted it yet
+	StateMatched    fsm.State = "matched"    // a driver is on the way to the pickup
+	StateOnTrip     fsm.State = "on_trip"
+	StateRating     fsm.State = "rating" // the trip is complete, the driver is not rated yet
+)
+
+// Lifecycle is the order a customer goes through a trip. A booking can end without a trip when
+// no driver is found or when either side cancels it before the start.
+var Lifecycle = fsm.Table{
+	StateIdle:       {StateEstimating, StateSearching},
+	StateEstimating: {StateEstimating, StateIdle, StateSearching},
+	StateSearching:  {StateMatched, StateIdle},
+	StateMatched:    {StateOnTrip, StateIdle},
+	StateOnTrip:     {StateRating, StateIdle},
+	StateRating:     {StateIdle},
+}
+
+// tripStates maps the status of the active trip the backend reports on sync to the customer state
+var tripStates = map[string]fsm.State{
+	"searching": StateSearching,
+	"accepted":  StateMatched,
+	"arrived":   StateMatched,
+	"started":   StateOnTrip,
+	"completed": StateRating,
+}
@@ -0,0 +1,54 @@
+package customers
+
+import (
+	"testing"
+
+	"sim-server/internal/simulation/fsm"
+)
+
+func TestLifecycle(t *testing.T) {
+	tests := []struct {
+		name  string
+		path  []fsm.State
+		legal bool
+	}{
+		{name: "trip now", path: []fsm.State{StateIdle, StateSearching, StateMatched, StateOnTrip, StateRating, StateIdle}, legal: true},
+		{name: "estimate first", path: []fsm.State{StateIdle, StateEstimating, StateEstimating, StateSearching}, legal: true},
+		{name: "estimate only", path: []fsm.State{StateIdle, StateEstimating, StateIdle}, legal: true},
+		{name: "no driver found", path: []fsm.State{StateSearching, StateIdle}, legal: true},
+		{name: "cancelled on board", path: []fsm.State{StateOnTrip, StateIdle}, legal: true},
+		{name: "second booking", path: []fsm.State{StateSearching, StateSearching}},
+		{name: "on board without a driver", path: []fsm.State{StateSearching, StateOnTrip}},
+		{name: "estimate during a trip", path: []fsm.State{StateMatched, StateEstimating}},
+		{name: "rating twice", path: []fsm.State{StateRating, StateRating}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := fsm.New("customer", tt.path[0], Lifecycle)
+			var err error
+			for _, state := range tt.path[1:] {
+				if err = m.Transition(state); err != nil {
+					break
+				}
+			}
+			if legal := err == nil; legal != tt.legal {
+				t.Errorf("path %v legal %v, want %v: %v", tt.path, legal, tt.legal, err)
+			}
+		})
+	}
+}
+
+func TestLifecycleIsClosed(t *testing.T) {
+	for from, targets := range Lifecycle {
+		for _, to := range targets {
+			if _, ok := Lifecycle[to]; !ok {
+				t.Errorf("%s moves to %s, which has no transitions", from, to)
+			}
+		}
+	}
+	for status, state := range tripStates {
+		if _, ok := Lifecycle[state]; !ok {
+			t.Errorf("trip status %s maps to %s, which is not in the lifecycle", status, state)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package customers
+
+import (
+	"context"
+
+	pb "sim-server/internal/genserver/proto"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// RateDriver rates the driver of a trip on behalf of a customer started in manual mode, the current
+// trip is rated when tripId is empty. It reports whether the rating was sent to the backend.
+func RateDriver(customerId, tripId string, rating float32) (success bool, err error) {
+	callErr := call(customerId, func(sim *SimulatedCustomer) {
+		var response *pb.RatingResponse
+		response, err = sim.RateDriver(context.Background(), &pb.RatingRequest{TripId: tripId, Rating: rating})
+		success = response.GetSuccess()
+	})
+	if callErr != nil {
+		return false, callErr
+	}
+	return success, err
+}
+
+func (sim *SimulatedCustomer) RateDriver(ctx context.Context, req *pb.RatingRequest) (*pb.RatingResponse, error) {
+	if !sim.manual {
+		return nil, status.Error(codes.FailedPrecondition, "customer is not in manual mode")
+	}
+	if req.GetRating() < 0 || req.GetRating() > 5 {
+		return nil, status.Error(codes.InvalidArgument, "rating must be between 0 and 5")
+	}
+	if req.GetTripId() != "" && req.GetTripId() != sim.tripId {
+		return nil, status.Errorf(codes.FailedPrecondition, "trip %s is not the current trip", req.GetTripId())
+	}
+	if sim.tripId == "" {
+		return nil, status.Error(codes.FailedPrecondition, "customer has no current trip")
+	}
+	sent, err := sim.rateDriver(float64(req.GetRating()))
+	if err != nil {
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	}
+	return &pb.RatingResponse{Success: sent}, nil
+}
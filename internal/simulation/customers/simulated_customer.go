@@ -2,35 +2,64 @@ package customers
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"log"
 	"net"
-	"net/http"
-	"net/url"
+	"sim-server/config"
 	"sim-server/internal/services"
 	"sync"
 	"time"
 
 	"sim-server/internal/models"
+	"sim-server/internal/simulation/clock"
+	"sim-server/internal/simulation/fsm"
+	"sim-server/internal/simulation/recorder"
+	"sim-server/internal/simulation/socket"
 
-	"github.com/gorilla/websocket"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
 
 	pb "sim-server/internal/genserver/proto"
+	"sim-server/internal/simulation/actors"
 )
 
-const (
-	scheme             = "wss"
-	host               = "rh-core.advantium.in"
-	sleepBeforeLooping = 20 * time.Second
-)
+const actorKind = "customer"
+
+// Script is the timing of a scripted customer, in durations of the clock of the scenario, see clock.Clock.
+// The discrete-event engine drives its customers with it as well.
+type Script struct {
+	BeforeLooping       time.Duration // from the end of a trip to booking the next one
+	BeforeBookingChange time.Duration // from booking a scheduled trip to cancelling or moving it
+	MaxBookingShift     time.Duration // how far a scheduled trip is moved either way
+}
+
+// defaultScript is followed by every customer, see DefaultScript
+var defaultScript = Script{
+	BeforeLooping:       20 * time.Second,
+	BeforeBookingChange: 30 * time.Second,
+	MaxBookingShift:     15 * time.Minute,
+}
 
+// DefaultScript returns the timing every customer follows. A scenario speeds it up through its clock.
+func DefaultScript() Script {
+	return defaultScript
+}
+
+const defaultCancellationReasonId = 1
+
+// SimulatedCustomer is owned by the mailbox of its actor: the websocket callbacks, the booking goroutines
+// and the gRPC listener hand their work to the mailbox, so the state is only touched from one goroutine.
+// Use Snapshot to read it from outside.
 type SimulatedCustomer struct {
 	pb.UnimplementedSimulatedCustomerServer
 	customer            models.Customer
+	target              config.Target
+	scenarioId          string
+	session             *services.Session
+	clock               *clock.Clock // of the scenario, the customer sleeps and timestamps through it
 	lat                 float64
 	lng                 float64
 	originLat           float64
@@ -38,101 +67,136 @@ type SimulatedCustomer struct {
 	destinationLat      float64
 	destinationLng      float64
 	loop                bool
-	conn                *websocket.Conn
-	requestEstimateData map[string]interface{}
-	confirmTripData     map[string]interface{}
+	leadTime            time.Duration
+	modifyRate          float64
+	cancelRate          float64
+	manual              bool // the customer only rates when told to through the manual control RPCs, and never loops
+	conn                *socket.Client
+	connLock            sync.Mutex   // guards writes to conn, Terminate closes it from outside the mailbox
+	machine             *fsm.Machine // where the customer is in a trip, see Lifecycle
+	requestEstimateData *models.TripEstimateMessage
+	confirmTripData     *models.ConfirmTripMessage
 	tripId              string
-	writeLock           sync.Mutex
+	draining            bool          // the process shuts down, the customer books no new trip, see Drain
+	recovering          bool          // restored from a checkpoint, the first sync resumes the scenario, see Restore
+	started             bool          // InitConnection ran, from then on the customer needs its websocket
+	self                *actors.Actor // the mailbox owning the customer, it also runs its goroutines so a panic crashes the actor rather than the process
 }
 
 // Client Methods
 
-func NewSimulatedCustomer(customer models.Customer, loop bool) {
+func NewSimulatedCustomer(customer models.Customer, target config.Target, scenarioId string, loop bool, modifyRate, cancelRate float64, manual bool) {
+	newSimulatedCustomer(customer, target, scenarioId, loop, modifyRate, cancelRate, manual)
+}
+
+// newSimulatedCustomer reports whether it started the customer, false when it was already running
+func newSimulatedCustomer(customer models.Customer, target config.Target, scenarioId string, loop bool, modifyRate, cancelRate float64, manual bool) bool {
 	sim := &SimulatedCustomer{
-		customer: customer,
-		loop:     loop,
+		customer:   customer,
+		target:     target,
+		scenarioId: scenarioId,
+		session:    services.NewSession(target, customer.PhoneNumber, customer.AccessToken, services.CustomerAuth(target)),
+		clock:      clock.For(scenarioId),
+		loop:       loop,
+		modifyRate: modifyRate,
+		cancelRate: cancelRate,
+		manual:     manual,
+		machine:    fsm.New("customer "+customer.Id, StateIdle, Lifecycle),
 	}
 
-	sim.serve(customer.Id)
+	return sim.serve(customer.Id)
 }
 
-func Connect(customerId string) {
-	client, conn, err := client(customerId)
+// Connect opens the websocket of the customer
+func Connect(customerId string) error {
+	connected := false
+	err := call(customerId, func(sim *SimulatedCustomer) {
+		response, _ := sim.InitConnection(context.Background(), &pb.InitConnectionRequest{})
+		connected = response.GetSuccess()
+	})
 	if err != nil {
-		log.Printf("Error connecting to customer: %v", err)
-		return
+		return err
 	}
-	defer conn.Close()
-	client.InitConnection(context.Background(), &pb.InitConnectionRequest{})
+	if !connected {
+		return errors.New("the websocket of the customer did not connect")
+	}
+	return nil
 }
 
 func UpdateLocation(customerId string, lat float64, lng float64) {
-	client, conn, err := client(customerId)
+	err := call(customerId, func(sim *SimulatedCustomer) {
+		sim.SetLocation(context.Background(), &pb.SetLocationRequest{Lat: lat, Lng: lng})
+	})
 	if err != nil {
 		log.Printf("Error connecting to customer: %v", err)
-		return
 	}
-	defer conn.Close()
-	client.SetLocation(context.Background(), &pb.SetLocationRequest{Lat: lat, Lng: lng})
 }
 
 func RequestEstimate(customerId string, originLat, originLng, destinationLat, destinationLng float64) {
-	client, conn, err := client(customerId)
+	err := call(customerId, func(sim *SimulatedCustomer) {
+		sim.TripEstimate(context.Background(),
+			&pb.TripEstimateRequest{
+				OriginLng:      originLng,
+				OriginLat:      originLat,
+				DestinationLat: destinationLat,
+				DestinationLng: destinationLng,
+			})
+	})
 	if err != nil {
 		log.Printf("Error connecting to customer: %v", err)
-		return
 	}
-	defer conn.Close()
-	client.TripEstimate(context.Background(),
-		&pb.TripEstimateRequest{
-			OriginLng:      originLng,
-			OriginLat:      originLat,
-			DestinationLat: destinationLat,
-			DestinationLng: destinationLng,
-		})
 }
 
-func ConfirmTrip(customerId string, originLat, originLng, destinationLat, destinationLng float64) {
-	client, conn, err := client(customerId)
+// ConfirmTrip books a trip for the customer, scheduledAt is the pickup time in unix seconds or zero for a trip now
+func ConfirmTrip(customerId string, originLat, originLng, destinationLat, destinationLng float64, scheduledAt int64) {
+	err := call(customerId, func(sim *SimulatedCustomer) {
+		confirmTrip, err := sim.ConfirmTrip(context.Background(),
+			&pb.ConfirmTripRequest{
+				OriginLat:      originLat,
+				OriginLng:      originLng,
+				DestinationLat: destinationLat,
+				DestinationLng: destinationLng,
+				ScheduledAt:    scheduledAt,
+			})
+		if err != nil {
+			return
+		}
+		fmt.Println(confirmTrip)
+	})
 	if err != nil {
 		log.Printf("Error connecting to customer: %v", err)
-		return
-	}
-	defer conn.Close()
-	confirmTrip, err := client.ConfirmTrip(context.Background(),
-		&pb.ConfirmTripRequest{
-			OriginLat:      originLat,
-			OriginLng:      originLng,
-			DestinationLat: destinationLat,
-			DestinationLng: destinationLng,
-		})
-	if err != nil {
-		return
 	}
-	fmt.Println(confirmTrip)
 }
 
 // Server Methods
 
 func (sim *SimulatedCustomer) InitConnection(ctx context.Context, req *pb.InitConnectionRequest) (*pb.InitConnectionResponse, error) {
-	// Get token
-	token := sim.customer.AccessToken
-
-	address := url.URL{Scheme: scheme, Host: host, Path: "/ws/customer"}
-	conn, _, err := websocket.DefaultDialer.Dial(address.String(), http.Header{
-		"Authorization": []string{"Bearer " + token},
-		"MRSOOL-CLIENT": []string{"Simulation"},
-	})
+	sim.started = true
+	if sim.conn != nil && !sim.conn.Closed() {
+		return &pb.InitConnectionResponse{Success: true}, nil // reused actor, already connected
+	}
+	conn, err := socket.DialTarget(sim.actor(), sim.target, "/ws/customer", sim.session, sim.handleMessage, sim.resync)
 	if err != nil {
 		log.Printf("Error connecting to websocket: %v", err)
 		return &pb.InitConnectionResponse{Success: false}, nil
 	}
-	sim.conn = conn
+	sim.setConn(conn)
+	if sim.recovering {
+		sim.resync()
+	}
 
-	go sim.blockingSubscribe(conn)
 	return &pb.InitConnectionResponse{Success: true}, nil
 }
 
+// IsAlive fails once the customer started without a websocket or its websocket was closed for good,
+// the actor can no longer take part in trips
+func (sim *SimulatedCustomer) IsAlive(ctx context.Context, req *pb.IsAliveRequest) (*pb.IsAliveResponse, error) {
+	if sim.started && (sim.conn == nil || sim.conn.Closed()) {
+		return nil, status.Error(codes.Unavailable, "websocket closed")
+	}
+	return &pb.IsAliveResponse{}, nil
+}
+
 func (sim *SimulatedCustomer) SetLocation(ctx context.Context, req *pb.SetLocationRequest) (*pb.SetLocationResponse, error) {
 	sim.lat = req.GetLat()
 	sim.lng = req.GetLng()
@@ -140,6 +204,9 @@ func (sim *SimulatedCustomer) SetLocation(ctx context.Context, req *pb.SetLocati
 }
 
 func (sim *SimulatedCustomer) TripEstimate(ctx context.Context, req *pb.TripEstimateRequest) (*pb.TripEstimateResponse, error) {
+	if err := sim.machine.Transition(StateEstimating); err != nil {
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	}
 	tripRequestPayload := models.TripRequestPayload{
 		Origin: models.LatLong{
 			Latitude:  req.GetOriginLat(),
@@ -150,23 +217,28 @@ func (sim *SimulatedCustomer) TripEstimate(ctx context.Context, req *pb.TripEsti
 			Longitude: req.GetDestinationLng(),
 		},
 	}
-	jsonPayload, _ := json.Marshal(tripRequestPayload)
-
-	message, _ := json.Marshal(models.IncomingMessage{
-		Command: models.RequestEstimate,
-		Payload: jsonPayload,
-	})
-	if !sim.sendMessageToClient(message) {
+	if !sim.sendMessageToClient(models.RequestEstimate, tripRequestPayload) {
+		sim.machine.Transition(StateIdle)
 		return &pb.TripEstimateResponse{Success: false}, nil
 	}
 	return &pb.TripEstimateResponse{Success: true}, nil
 }
 
 func (sim *SimulatedCustomer) ConfirmTrip(ctx context.Context, req *pb.ConfirmTripRequest) (*pb.ConfirmTripResponse, error) {
+	if sim.draining {
+		return nil, status.Error(codes.Unavailable, "shutting down")
+	}
+	if err := sim.machine.Transition(StateSearching); err != nil {
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	}
 	sim.originLat = req.GetOriginLat()
 	sim.originLng = req.GetOriginLng()
 	sim.destinationLat = req.GetDestinationLat()
 	sim.destinationLng = req.GetDestinationLng()
+	sim.leadTime = 0
+	if req.GetScheduledAt() > 0 {
+		sim.leadTime = sim.clock.Until(time.Unix(req.GetScheduledAt(), 0))
+	}
 	tripRequestPayload := models.TripRequestPayload{
 		Origin: models.LatLong{
 			Latitude:  sim.originLat,
@@ -177,26 +249,41 @@ func (sim *SimulatedCustomer) ConfirmTrip(ctx context.Context, req *pb.ConfirmTr
 			Longitude: sim.destinationLng,
 		},
 		VehicleCategoryId: 2, //default selecting the category
+		ScheduledAt:       req.GetScheduledAt(),
 	}
-	jsonPayload, _ := json.Marshal(tripRequestPayload)
-
-	message, _ := json.Marshal(models.IncomingMessage{
-		Command: models.ConfirmTrip,
-		Payload: jsonPayload,
-	})
-
-	if !sim.sendMessageToClient(message) {
+	if !sim.sendMessageToClient(models.ConfirmTrip, tripRequestPayload) {
+		sim.machine.Transition(StateIdle)
 		return &pb.ConfirmTripResponse{Success: false}, nil
 	}
 	return &pb.ConfirmTripResponse{Success: true}, nil
 }
 
+// Terminate closes the websocket once the actor stopped, a restarted customer opens a new one
+func (sim *SimulatedCustomer) Terminate(reason error) {
+	sim.connLock.Lock()
+	defer sim.connLock.Unlock()
+	if sim.conn != nil {
+		sim.conn.Close()
+	}
+}
+
+func (sim *SimulatedCustomer) setConn(conn *socket.Client) {
+	sim.connLock.Lock()
+	sim.conn = conn
+	sim.connLock.Unlock()
+}
+
 // Utility Methods
 
-func (sim *SimulatedCustomer) sendMessageToClient(bytes []byte) bool {
-	sim.writeLock.Lock() // Acquire lock before writing
-	defer sim.writeLock.Unlock()
-	if err := sim.conn.WriteMessage(websocket.TextMessage, bytes); err != nil {
+func (sim *SimulatedCustomer) actor() recorder.Actor {
+	return recorder.Actor{Role: "customer", Id: sim.customer.Id, ScenarioId: sim.scenarioId}
+}
+
+func (sim *SimulatedCustomer) sendMessageToClient(command models.Command, payload interface{}) bool {
+	if sim.conn == nil {
+		return false
+	}
+	if err := sim.conn.SendCommand(command, payload); err != nil {
 		log.Println("Write error:", err)
 		return false
 	}
@@ -204,48 +291,75 @@ func (sim *SimulatedCustomer) sendMessageToClient(bytes []byte) bool {
 	return true
 }
 
-func (sim *SimulatedCustomer) serve(customerId string) {
-	//if checkIfAlreadyServed(customerId) {
-	//	return
-	//}
-	// Register and start a gRPC server for the trip
-	lis, err := registerService(customerId)
+// serve reports whether it spawned the actor of the customer, false when a live one already serves it
+func (sim *SimulatedCustomer) serve(customerId string) bool {
+	if checkIfAlreadyServed(customerId) {
+		log.Printf("customer %s is already running, reusing it", customerId)
+		return false
+	}
+
+	actor := actors.Spawn(actorKind, customerId, sim)
+	sim.self = actor
+	if !actors.ListenersEnabled() {
+		return true
+	}
+
+	// Register and start a gRPC server for tools outside the process
+	lis, err := registerService(actor)
 	if err != nil {
-		return
+		return true
 	}
 
 	go sim.grpcLoop(lis)
+	return true
 }
 
 func (sim *SimulatedCustomer) grpcLoop(lis net.Listener) {
 	defer lis.Close()
 
-	s := grpc.NewServer()
+	s := grpc.NewServer(grpc.UnaryInterceptor(sim.self.UnaryInterceptor()))
 	pb.RegisterSimulatedCustomerServer(s, sim) // Start with initial state
 
+	// the listener goes with the actor, a restarted one opens its own
+	go func() {
+		<-sim.self.Done()
+		s.Stop()
+	}()
 	if err := s.Serve(lis); err != nil {
 		log.Printf("Failed to serve: %v", err)
 	}
 }
 
+// checkIfAlreadyServed reports whether a live actor already simulates the customer, either in this
+// process or in the one that advertised it in the registry. Stale registry entries are removed.
 func checkIfAlreadyServed(customerId string) bool {
-	value, exists := services.CheckAndGetKey(customerId)
+	if actor, ok := actors.Lookup(customerId); ok {
+		return actor.Ping(context.Background()) == nil
+	}
 
+	address, exists := services.CheckAndGetKey(customerId)
 	if !exists {
 		return false
 	}
-	address := value
 	conn, err := grpc.NewClient(address, grpc.WithTransportCredentials(insecure.NewCredentials()))
 	if err != nil {
 		log.Printf("Failed to create new client for %s: %v", customerId, err)
+		return false
 	}
 	defer conn.Close()
 
+	ctx, cancel := context.WithTimeout(context.Background(), actors.PingTimeout)
+	defer cancel()
+	if _, err := pb.NewSimulatedCustomerClient(conn).IsAlive(ctx, &pb.IsAliveRequest{}); err != nil {
+		log.Printf("Removing stale registry entry of %s at %s: %v", customerId, address, err)
+		services.Delete(customerId)
+		return false
+	}
+	log.Printf("%s is served by another process at %s", customerId, address)
 	return true
-
 }
 
-func registerService(customerId string) (lis net.Listener, err error) {
+func registerService(actor *actors.Actor) (lis net.Listener, err error) {
 	lis, err = net.Listen("tcp", ":0") // Let the OS provide the port number
 	if err != nil {
 		log.Printf("Failed to listen: %v", err)
@@ -253,104 +367,241 @@ func registerService(customerId string) (lis net.Listener, err error) {
 	}
 
 	addr := lis.Addr().(*net.TCPAddr)
-	addrString := fmt.Sprintf("%s:%d", "localhost", addr.Port)
+	addrString := fmt.Sprintf("%s:%d", actors.AdvertiseHost(), addr.Port)
 
-	err = services.Set(customerId, []byte(addrString))
+	err = actor.Advertise(addrString)
 	if err != nil {
 		log.Printf("Failed to add server to registry: %v", err)
+		lis.Close()
 		return
 	}
 	return
 }
 
-func client(customerId string) (pb.SimulatedCustomerClient, *grpc.ClientConn, error) {
-
-	value, exists := services.CheckAndGetKey(customerId)
-
-	if !exists {
-		return nil, nil, errors.New("server not found for customer " + customerId)
+// call runs fn in the mailbox of the customer and waits for it
+func call(customerId string, fn func(sim *SimulatedCustomer)) error {
+	actor, ok := actors.Lookup(customerId)
+	if !ok {
+		return fmt.Errorf("customer %s: %w", customerId, actors.ErrNotRunning)
 	}
+	sim, ok := actor.Server.(*SimulatedCustomer)
+	if !ok {
+		return errors.New(customerId + " is not a customer")
+	}
+	return actor.Call(context.Background(), func() { fn(sim) })
+}
 
-	// Connect to GenServer instance
-	genServerConn, err := grpc.NewClient(value, grpc.WithTransportCredentials(insecure.NewCredentials()))
+// handleMessage is called by the websocket read loop for every incoming message, which waits for
+// the mailbox to handle it so messages keep their order
+func (sim *SimulatedCustomer) handleMessage(message []byte) {
+	defer sim.self.Recover()
+	serverMessage, payload, err := models.DecodeServerMessage(message)
 	if err != nil {
-		log.Printf("Failed to connect to GenServer: %v", err)
-		return nil, nil, err
+		log.Printf("decode: %v", err)
+		return
+	}
+	if err := sim.self.Call(context.Background(), func() { sim.dispatch(serverMessage.Command, payload) }); err != nil {
+		log.Printf("customer %s: dropping %s: %v", sim.customer.Id, serverMessage.Command, err)
 	}
+}
 
-	return pb.NewSimulatedCustomerClient(genServerConn), genServerConn, err
+func (sim *SimulatedCustomer) dispatch(command models.Command, payload interface{}) {
+	switch command {
+	case models.RequestEstimate:
+		sim.handleRequestEstimate(payload.(*models.TripEstimateMessage))
+	case models.ConfirmTrip:
+		sim.handleConfirmTrip(payload.(*models.ConfirmTripMessage))
+	case models.ModifyTrip:
+		sim.handleTripModification(payload.(*models.ConfirmTripMessage))
+	case models.Eta:
+		sim.handleEtaPayload(payload.(*models.EtaMessage))
+	case models.DriverLocation:
+		sim.handleDriverLocation(payload.(*models.DriverLocationMessage))
+	case models.AcceptTrip:
+		sim.handleTripUpdate(payload.(*models.TripStatusMessage), StateMatched)
+	case models.StartTrip:
+		sim.handleTripUpdate(payload.(*models.TripStatusMessage), StateOnTrip)
+	case models.CompleteTrip:
+		sim.handleTripCompletion(payload.(*models.TripStatusMessage))
+	case models.CancelTrip, models.NoDriverFound, models.NoDriverAcceptedTrip, models.TripTimedOut:
+		sim.handleTripEnd(payload.(*models.TripStatusMessage))
+	case models.Sync:
+		sim.handleSync(payload.(*models.SyncMessage))
+	}
 }
 
-func (sim *SimulatedCustomer) blockingSubscribe(conn *websocket.Conn) {
-	defer conn.Close()
-	for {
-		_, message, err := conn.ReadMessage()
-		if err != nil {
-			log.Println("read:", err)
-			return
+// resync asks the backend for the customer state after the websocket was re-established
+func (sim *SimulatedCustomer) resync() {
+	sim.self.Cast(func() { sim.sendMessageToClient(models.Sync, struct{}{}) })
+}
+
+func (sim *SimulatedCustomer) handleSync(payload *models.SyncMessage) {
+	if payload.ActiveTrip != nil {
+		sim.tripId = payload.ActiveTrip.Id
+		if sim.confirmTripData != nil && sim.confirmTripData.Id == payload.ActiveTrip.Id {
+			// a modification may have been acknowledged while the websocket was down
+			sim.confirmTripData = &models.ConfirmTripMessage{Trip: *payload.ActiveTrip}
 		}
-		log.Printf("recv: %s", message)
-		var payload map[string]interface{}
-		json.Unmarshal(message, &payload)
-		switch payload["command"] {
-		case string(models.RequestEstimate):
-			sim.handleRequestEstimate(payload)
-		case string(models.ConfirmTrip):
-			sim.handleConfirmTrip(payload)
-		case string(models.Eta):
-			sim.handleEtaPayload(payload)
-		case string(models.DriverLocation):
-			sim.handleDriverLocation(payload)
-		case string(models.CompleteTrip):
-			sim.handleTripCompletion(payload)
+		if state, ok := tripStates[payload.ActiveTrip.Status]; ok {
+			sim.machine.Reset(state)
 		}
+	} else if sim.machine.Is(StateSearching, StateMatched, StateOnTrip) {
+		log.Printf("customer %s: trip %s is no longer active", sim.customer.Id, sim.tripId)
+		sim.machine.Reset(StateIdle)
+		sim.tripId = ""
+	}
+
+	if sim.recovering {
+		sim.recovering = false
+		sim.resume()
 	}
 }
 
-func (sim *SimulatedCustomer) handleRequestEstimate(payload map[string]interface{}) {
+func (sim *SimulatedCustomer) handleRequestEstimate(payload *models.TripEstimateMessage) {
 	sim.requestEstimateData = payload
+	if sim.machine.Is(StateEstimating) {
+		sim.machine.Transition(StateIdle)
+	}
 }
 
-func (sim *SimulatedCustomer) handleConfirmTrip(payload map[string]interface{}) {
+func (sim *SimulatedCustomer) handleConfirmTrip(payload *models.ConfirmTripMessage) {
 	sim.confirmTripData = payload
-	if data, ok := payload["data"].(map[string]interface{}); ok {
-		if id, ok := data["id"].(string); ok {
-			fmt.Println("Parsed ID:", id)
-			sim.tripId = id
-		} else {
-			fmt.Println("ID is not a string or not present")
-		}
-	} else {
-		fmt.Println("Data is not a map or not present")
-		fmt.Printf(payload["message"].(string))
+	fmt.Println("Parsed ID:", payload.Id)
+	sim.tripId = payload.Id
+	if sim.leadTime > 0 && !sim.manual {
+		sim.self.Go(func() { sim.manageBooking(payload.Id) })
+	}
+}
+
+// handleTripModification keeps the booking at the pickup time the backend acknowledged
+func (sim *SimulatedCustomer) handleTripModification(payload *models.ConfirmTripMessage) {
+	if sim.confirmTripData != nil && sim.confirmTripData.Id == payload.Id {
+		sim.confirmTripData = payload
 	}
 }
 
-func (sim *SimulatedCustomer) handleEtaPayload(payload map[string]interface{}) {
+func (sim *SimulatedCustomer) handleEtaPayload(payload *models.EtaMessage) {
 	fmt.Print("Customer getting eta payload after trip acceptance", payload)
 }
 
-func (sim *SimulatedCustomer) handleDriverLocation(payload map[string]interface{}) {
+func (sim *SimulatedCustomer) handleDriverLocation(payload *models.DriverLocationMessage) {
 	fmt.Print("Customer getting driver current location trip acceptance", payload)
 }
 
-func (sim *SimulatedCustomer) handleTripCompletion(_ map[string]interface{}) {
-	sim.RateDriver()
+// handleTripUpdate follows the current trip through the steps the driver takes
+func (sim *SimulatedCustomer) handleTripUpdate(payload *models.TripStatusMessage, state fsm.State) {
+	if payload.TripID() != sim.tripId {
+		return
+	}
+	sim.machine.Transition(state)
+}
+
+// handleTripEnd makes the customer available again once the current trip is cancelled or finds no driver
+func (sim *SimulatedCustomer) handleTripEnd(payload *models.TripStatusMessage) {
+	if payload.TripID() != sim.tripId {
+		return
+	}
+	if err := sim.machine.Transition(StateIdle); err == nil {
+		sim.tripId = ""
+	}
+}
+
+func (sim *SimulatedCustomer) handleTripCompletion(payload *models.TripStatusMessage) {
+	if payload.TripID() != sim.tripId || sim.machine.Transition(StateRating) != nil {
+		return
+	}
+	if sim.manual {
+		return
+	}
+	sim.rateDriver(models.FloatBetweenZeroToOne() * 5)
 	if sim.loop {
-		time.Sleep(sleepBeforeLooping)
-		ConfirmTrip(sim.customer.Id, sim.destinationLat, sim.destinationLng, sim.originLat, sim.originLng)
+		sim.rebook(true)
+	}
+}
+
+// rebook books the next trip of a looping customer a while after its trip, back from the destination
+// of the previous trip when reverse. The booking goes through the mailbox like any other.
+func (sim *SimulatedCustomer) rebook(reverse bool) {
+	originLat, originLng, destinationLat, destinationLng := sim.originLat, sim.originLng, sim.destinationLat, sim.destinationLng
+	if reverse {
+		originLat, originLng, destinationLat, destinationLng = destinationLat, destinationLng, originLat, originLng
+	}
+	leadTime := sim.leadTime
+	sim.self.Go(func() {
+		sim.clock.Sleep(defaultScript.BeforeLooping)
+		ConfirmTrip(sim.customer.Id, originLat, originLng, destinationLat, destinationLng, sim.nextScheduledAt(leadTime))
+	})
+}
+
+// nextScheduledAt keeps the lead time of the previous booking when looping, zero for trips now
+func (sim *SimulatedCustomer) nextScheduledAt(leadTime time.Duration) int64 {
+	if leadTime <= 0 {
+		return 0
+	}
+	return sim.clock.Now().Add(leadTime).Unix()
+}
+
+// manageBooking randomly cancels or moves a scheduled trip a while after it was booked
+func (sim *SimulatedCustomer) manageBooking(tripId string) {
+	sim.clock.Sleep(defaultScript.BeforeBookingChange)
+	if err := sim.self.Call(context.Background(), func() { sim.changeBooking(tripId) }); err != nil {
+		log.Printf("customer %s: not changing trip %s: %v", sim.customer.Id, tripId, err)
+	}
+}
+
+func (sim *SimulatedCustomer) changeBooking(tripId string) {
+	if sim.tripId != tripId {
+		return
+	}
+	switch {
+	case models.FloatBetweenZeroToOne() < sim.cancelRate:
+		sim.CancelTrip(tripId)
+		if sim.loop {
+			sim.rebook(false)
+		}
+	case models.FloatBetweenZeroToOne() < sim.modifyRate:
+		shift := time.Duration((models.FloatBetweenZeroToOne()*2 - 1) * float64(defaultScript.MaxBookingShift))
+		now := sim.clock.Now()
+		scheduledAt := now.Add(sim.leadTime - defaultScript.BeforeBookingChange + shift)
+		if scheduledAt.Before(now.Add(defaultScript.BeforeBookingChange)) {
+			scheduledAt = now.Add(defaultScript.BeforeBookingChange)
+		}
+		sim.ModifyTrip(tripId, scheduledAt.Unix())
+		// the next booking of a looping customer keeps the lead time it changed to
+		sim.leadTime = scheduledAt.Sub(now)
+	}
+}
+
+func (sim *SimulatedCustomer) ModifyTrip(tripId string, scheduledAt int64) {
+	payload := models.ModifyTripPayload{
+		TripId:      tripId,
+		ScheduledAt: scheduledAt,
+	}
+	sim.sendMessageToClient(models.ModifyTrip, payload)
+}
+
+func (sim *SimulatedCustomer) CancelTrip(tripId string) {
+	payload := models.CancelTripPayload{
+		TripId:   tripId,
+		ReasonId: defaultCancellationReasonId,
+	}
+	if sim.sendMessageToClient(models.CancelTrip, payload) && sim.machine.Transition(StateIdle) == nil {
+		sim.tripId = ""
 	}
 }
 
-func (sim *SimulatedCustomer) RateDriver() {
+// rateDriver rates the driver of the completed trip, which makes the customer available again
+func (sim *SimulatedCustomer) rateDriver(rating float64) (bool, error) {
+	if !sim.machine.Is(StateRating) {
+		return false, fmt.Errorf("customer %s has no completed trip to rate", sim.customer.Id)
+	}
 	payload := models.TripRatingPayload{
 		TripId: sim.tripId,
-		Rating: models.FloatBetweenZeroToOne() * 5,
+		Rating: rating,
 	}
-	jsonPayload, _ := json.Marshal(payload)
-	message, _ := json.Marshal(models.IncomingMessage{
-		Command: models.RateDriver,
-		Payload: jsonPayload,
-	})
-	sim.sendMessageToClient(message)
+	if err := sim.machine.Transition(StateIdle); err != nil {
+		return false, err
+	}
+	sim.tripId = ""
+	return sim.sendMessageToClient(models.RateDriver, payload), nil
 }
@@ -0,0 +1,175 @@
+package des
+
+import (
+	"time"
+
+	"sim-server/internal/models"
+	"sim-server/internal/simulation/customers"
+	"sim-server/internal/simulation/fsm"
+)
+
+const defaultCancellationReasonId = 1
+
+// customer follows customers.DefaultScript through customers.Lifecycle, as the live SimulatedCustomer
+// does, and records how its bookings turn out
+type customer struct {
+	run            *run
+	id             string
+	machine        *fsm.Machine
+	originLat      float64
+	originLng      float64
+	destinationLat float64
+	destinationLng float64
+	leadTime       time.Duration // zero for trips now
+	tripId         string
+	bookedAt       time.Time
+}
+
+func newCustomer(r *run, phoneNumber string) *customer {
+	c := &customer{run: r}
+	c.id = r.backend.ConnectCustomer(phoneNumber, r.deliverer("customer", c.dispatch))
+	c.machine = fsm.New("customer "+c.id, customers.StateIdle, customers.Lifecycle)
+	return c
+}
+
+func (c *customer) dispatch(command models.Command, payload interface{}) {
+	switch command {
+	case models.ConfirmTrip:
+		c.handleConfirmTrip(payload.(*models.ConfirmTripMessage))
+	case models.AcceptTrip:
+		if c.handleTripUpdate(payload.(*models.TripStatusMessage), customers.StateMatched) {
+			c.run.count(c.run.engine.Now(), func(b *Bucket) *int { return &b.Matched })
+		}
+	case models.ArrivedForPickup:
+		if payload.(*models.TripStatusMessage).TripID() == c.tripId && c.leadTime == 0 {
+			c.run.pickupWait(c.bookedAt)
+		}
+	case models.StartTrip:
+		c.handleTripUpdate(payload.(*models.TripStatusMessage), customers.StateOnTrip)
+	case models.CompleteTrip:
+		c.handleTripCompletion(payload.(*models.TripStatusMessage))
+	case models.NoDriverFound, models.NoDriverAcceptedTrip:
+		if c.handleTripEnd(payload.(*models.TripStatusMessage)) {
+			c.run.count(c.run.engine.Now(), func(b *Bucket) *int { return &b.NoDriver })
+		}
+	case models.CancelTrip, models.TripTimedOut:
+		c.handleTripEnd(payload.(*models.TripStatusMessage))
+	}
+}
+
+// confirmTrip books a trip, scheduledAt is the pickup time in unix seconds or zero for a trip now
+func (c *customer) confirmTrip(originLat, originLng, destinationLat, destinationLng float64, scheduledAt int64) {
+	if c.machine.Transition(customers.StateSearching) != nil {
+		return
+	}
+	c.originLat, c.originLng = originLat, originLng
+	c.destinationLat, c.destinationLng = destinationLat, destinationLng
+	c.leadTime = 0
+	if scheduledAt > 0 {
+		c.leadTime = time.Unix(scheduledAt, 0).Sub(c.run.engine.Now())
+	}
+	c.bookedAt = c.run.engine.Now()
+	c.run.count(c.bookedAt, func(b *Bucket) *int { return &b.Requested })
+	c.run.send(c.id, models.ConfirmTrip, models.TripRequestPayload{
+		Origin:            models.LatLong{Latitude: originLat, Longitude: originLng},
+		Destination:       models.LatLong{Latitude: destinationLat, Longitude: destinationLng},
+		VehicleCategoryId: 2,
+		ScheduledAt:       scheduledAt,
+	})
+}
+
+func (c *customer) handleConfirmTrip(payload *models.ConfirmTripMessage) {
+	c.tripId = payload.Id
+	if c.leadTime > 0 {
+		tripId := payload.Id
+		c.run.engine.After(c.run.customerScript.BeforeBookingChange, func() { c.changeBooking(tripId) })
+	}
+}
+
+// handleTripUpdate follows the current trip through the steps the driver takes
+func (c *customer) handleTripUpdate(payload *models.TripStatusMessage, state fsm.State) bool {
+	return payload.TripID() == c.tripId && c.machine.Transition(state) == nil
+}
+
+// handleTripEnd makes the customer available again once the current trip is cancelled or finds no driver
+func (c *customer) handleTripEnd(payload *models.TripStatusMessage) bool {
+	if payload.TripID() != c.tripId || c.machine.Transition(customers.StateIdle) != nil {
+		return false
+	}
+	c.tripId = ""
+	return true
+}
+
+func (c *customer) handleTripCompletion(payload *models.TripStatusMessage) {
+	if payload.TripID() != c.tripId || c.machine.Transition(customers.StateRating) != nil {
+		return
+	}
+	c.run.count(c.run.engine.Now(), func(b *Bucket) *int { return &b.Completed })
+	c.rateDriver(c.run.rng.Float64() * 5)
+	if c.run.options.Loop {
+		c.rebook(true)
+	}
+}
+
+func (c *customer) rateDriver(rating float64) {
+	tripId := c.tripId
+	if c.machine.Transition(customers.StateIdle) != nil {
+		return
+	}
+	c.tripId = ""
+	c.run.send(c.id, models.RateDriver, models.TripRatingPayload{TripId: tripId, Rating: rating})
+}
+
+// rebook books the next trip of a looping customer a while after its trip, back from the destination
+// of the previous trip when reverse
+func (c *customer) rebook(reverse bool) {
+	originLat, originLng, destinationLat, destinationLng := c.originLat, c.originLng, c.destinationLat, c.destinationLng
+	if reverse {
+		originLat, originLng, destinationLat, destinationLng = destinationLat, destinationLng, originLat, originLng
+	}
+	leadTime := c.leadTime
+	c.run.engine.After(c.run.customerScript.BeforeLooping, func() {
+		c.confirmTrip(originLat, originLng, destinationLat, destinationLng, c.nextScheduledAt(leadTime))
+	})
+}
+
+// nextScheduledAt keeps the lead time of the previous booking when looping, zero for trips now
+func (c *customer) nextScheduledAt(leadTime time.Duration) int64 {
+	if leadTime <= 0 {
+		return 0
+	}
+	return c.run.engine.Now().Add(leadTime).Unix()
+}
+
+// changeBooking randomly cancels or moves a scheduled trip a while after it was booked
+func (c *customer) changeBooking(tripId string) {
+	if c.tripId != tripId {
+		return
+	}
+	script := c.run.customerScript
+	switch {
+	case c.run.rng.Float64() < c.run.options.CancelRate:
+		c.cancelTrip(tripId)
+		if c.run.options.Loop {
+			c.rebook(false)
+		}
+	case c.run.rng.Float64() < c.run.options.ModifyRate:
+		shift := time.Duration((c.run.rng.Float64()*2 - 1) * float64(script.MaxBookingShift))
+		now := c.run.engine.Now()
+		scheduledAt := now.Add(c.leadTime - script.BeforeBookingChange + shift)
+		if scheduledAt.Before(now.Add(script.BeforeBookingChange)) {
+			scheduledAt = now.Add(script.BeforeBookingChange)
+		}
+		c.run.send(c.id, models.ModifyTrip, models.ModifyTripPayload{TripId: tripId, ScheduledAt: scheduledAt.Unix()})
+		c.leadTime = scheduledAt.Sub(now)
+	}
+}
+
+func (c *customer) cancelTrip(tripId string) {
+	if c.machine.Transition(customers.StateIdle) != nil {
+		return
+	}
+	c.tripId = ""
+	c.run.count(c.run.engine.Now(), func(b *Bucket) *int { return &b.Cancelled })
+	c.run.send(c.id, models.CancelTrip, models.CancelTripPayload{TripId: tripId, ReasonId: defaultCancellationReasonId})
+}
@@ -0,0 +1,286 @@
+// Package des runs the driver and customer scripts against the in-process mock dispatcher on fully
+// virtual time, without websockets, so a simulated day of a zone completes in seconds. It answers
+// capacity questions such as how many drivers a zone needs at 6pm without touching a backend.
+//
+// Drivers and customers go through the same lifecycles and follow the same scripts as the live
+// actors, see drivers.Lifecycle and drivers.DefaultScript. The one difference is that a driver
+// takes a route at the speed the dispatcher estimated for it rather than a point every few
+// seconds, so trips last as long as they would on the road.
+package des
+
+import (
+	"encoding/json"
+	"log"
+	"math"
+	"math/rand"
+	"slices"
+	"strconv"
+	"time"
+
+	"sim-server/internal/mockbackend"
+	"sim-server/internal/models"
+	"sim-server/internal/simulation/customers"
+	"sim-server/internal/simulation/drivers"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+)
+
+const (
+	defaultDuration = 24 * time.Hour
+	maxDuration     = 7 * 24 * time.Hour
+	earthRadius     = 6371000.0 // meters
+)
+
+// Options describe the zone, the fleet and the demand of a run, like a scenario request does
+type Options struct {
+	Start    time.Time     // virtual start of the run, midnight of today by default
+	Duration time.Duration // 24 hours by default
+	Seed     int64         // runs with the same seed and options make the same decisions
+
+	NumDrivers   int
+	NumCustomers int
+	CenterLat    float64
+	CenterLng    float64
+	Radius       float64 // in km, drivers and pickups are spread within it, drop offs within four times it
+
+	AcceptanceRate float64
+	// Looping customers book again once a trip ends, the others book once during the run
+	Loop           bool
+	ScheduledRatio float64
+	LeadTimeMin    time.Duration
+	LeadTimeMax    time.Duration
+	ModifyRate     float64
+	CancelRate     float64
+}
+
+func (o Options) Validate() error {
+	return validation.ValidateStruct(&o,
+		validation.Field(&o.Duration, validation.Min(time.Duration(0)), validation.Max(maxDuration)),
+		validation.Field(&o.NumDrivers, validation.Min(0)),
+		validation.Field(&o.NumCustomers, validation.Min(0)),
+		validation.Field(&o.CenterLat, validation.Min(-90.0), validation.Max(90.0)),
+		validation.Field(&o.CenterLng, validation.Min(-180.0), validation.Max(180.0)),
+		validation.Field(&o.Radius, validation.Min(0.0)),
+		validation.Field(&o.AcceptanceRate, validation.Min(0.0), validation.Max(1.0)),
+		validation.Field(&o.ScheduledRatio, validation.Min(0.0), validation.Max(1.0)),
+		validation.Field(&o.LeadTimeMin, validation.Min(time.Duration(0))),
+		validation.Field(&o.LeadTimeMax, validation.Min(o.LeadTimeMin)),
+		validation.Field(&o.ModifyRate, validation.Min(0.0), validation.Max(1.0)),
+		validation.Field(&o.CancelRate, validation.Min(0.0), validation.Max(1.0)),
+	)
+}
+
+// Report sums up a run, in total and per hour of virtual time
+type Report struct {
+	Start       time.Time `json:"start"`
+	End         time.Time `json:"end"`
+	Events      int       `json:"events"`
+	WallSeconds float64   `json:"wall_seconds"` // how long the run took
+	Total       *Bucket   `json:"total"`
+	Hours       []*Bucket `json:"hours"`
+}
+
+// Bucket counts the trips of a span of virtual time. Bookings count in the span they were made in,
+// their outcome in the span it happened in.
+type Bucket struct {
+	Start     time.Time `json:"start"`
+	Requested int       `json:"requested"`
+	Matched   int       `json:"matched"` // accepted by a driver
+	Completed int       `json:"completed"`
+	NoDriver  int       `json:"no_driver"` // no driver was available or all of them rejected the trip
+	Cancelled int       `json:"cancelled"` // by the customer
+	// from booking a trip now to the driver arriving at the pickup
+	MeanPickupWait float64 `json:"mean_pickup_wait_seconds"`
+	P90PickupWait  float64 `json:"p90_pickup_wait_seconds"`
+	// share of the time of the fleet spent between accepting a trip and rating the customer
+	Utilization float64 `json:"driver_utilization"`
+
+	span  time.Duration
+	waits []float64
+	busy  time.Duration
+}
+
+// run is the state shared by the actors of one run, only touched from the events of its engine
+type run struct {
+	options        Options
+	engine         *Engine
+	backend        *mockbackend.Server
+	rng            *rand.Rand
+	driverScript   drivers.Script
+	customerScript customers.Script
+	total          *Bucket
+	hours          []*Bucket
+}
+
+// Run simulates options.Duration of the zone and reports on it
+func Run(options Options) (*Report, error) {
+	if err := options.Validate(); err != nil {
+		return nil, err
+	}
+	if options.Start.IsZero() {
+		year, month, day := time.Now().Date()
+		options.Start = time.Date(year, month, day, 0, 0, 0, 0, time.Local)
+	}
+	if options.Duration == 0 {
+		options.Duration = defaultDuration
+	}
+	started := time.Now()
+	end := options.Start.Add(options.Duration)
+
+	engine := NewEngine(options.Start)
+	backend := mockbackend.NewServer()
+	backend.UseClock(engine.Now, func(d time.Duration, f func()) mockbackend.Timer { return engine.After(d, f) })
+	r := &run{
+		options:        options,
+		engine:         engine,
+		backend:        backend,
+		rng:            rand.New(rand.NewSource(options.Seed)),
+		driverScript:   drivers.DefaultScript(),
+		customerScript: customers.DefaultScript(),
+		total:          &Bucket{Start: options.Start, span: options.Duration},
+	}
+	for start := options.Start; start.Before(end); start = start.Add(time.Hour) {
+		r.hours = append(r.hours, &Bucket{Start: start, span: min(time.Hour, end.Sub(start))})
+	}
+
+	radius := options.Radius * 1000
+	fleet := make([]*driver, 0, options.NumDrivers)
+	for i := 1; i <= options.NumDrivers; i++ {
+		lat, lng := r.randomPoint(radius)
+		d := newDriver(r, strconv.Itoa(1111100000+i), lat, lng)
+		fleet = append(fleet, d)
+		engine.At(options.Start, d.start)
+	}
+	for i := 1; i <= options.NumCustomers; i++ {
+		c := newCustomer(r, strconv.Itoa(1111100000+i))
+		originLat, originLng := r.randomPoint(radius)
+		destinationLat, destinationLng := r.randomPoint(radius * 4)
+		var leadTime time.Duration
+		if r.rng.Float64() < options.ScheduledRatio {
+			leadTime = options.LeadTimeMin + time.Duration(r.rng.Float64()*float64(options.LeadTimeMax-options.LeadTimeMin))
+		}
+		// first bookings are spread over the run, looping customers keep booking from there
+		at := options.Start.Add(time.Duration(r.rng.Float64() * float64(options.Duration)))
+		engine.At(at, func() {
+			c.confirmTrip(originLat, originLng, destinationLat, destinationLng, c.nextScheduledAt(leadTime))
+		})
+	}
+
+	events := engine.Run(end)
+	for _, d := range fleet {
+		d.stopClock()
+	}
+	return r.report(events, time.Since(started)), nil
+}
+
+func (r *run) report(events int, wall time.Duration) *Report {
+	fleet := time.Duration(r.options.NumDrivers)
+	for _, bucket := range append(r.hours, r.total) {
+		if len(bucket.waits) > 0 {
+			sum := 0.0
+			for _, wait := range bucket.waits {
+				sum += wait
+			}
+			bucket.MeanPickupWait = sum / float64(len(bucket.waits))
+			bucket.P90PickupWait = percentile(bucket.waits, 0.9)
+		}
+		if fleet > 0 && bucket.span > 0 {
+			bucket.Utilization = float64(bucket.busy) / float64(fleet*bucket.span)
+		}
+	}
+	return &Report{
+		Start:       r.options.Start,
+		End:         r.engine.Now(),
+		Events:      events,
+		WallSeconds: wall.Seconds(),
+		Total:       r.total,
+		Hours:       r.hours,
+	}
+}
+
+// count adds one to the counter picked by field of the bucket of at and of the total
+func (r *run) count(at time.Time, field func(bucket *Bucket) *int) {
+	*field(r.total)++
+	if bucket := r.hour(at); bucket != nil {
+		*field(bucket)++
+	}
+}
+
+// pickupWait records the wait for a trip now booked at bookedAt, counted in the hour of the booking
+func (r *run) pickupWait(bookedAt time.Time) {
+	wait := r.engine.Now().Sub(bookedAt).Seconds()
+	r.total.waits = append(r.total.waits, wait)
+	if bucket := r.hour(bookedAt); bucket != nil {
+		bucket.waits = append(bucket.waits, wait)
+	}
+}
+
+// busy adds the time a driver spent on a trip between from and to, split across the hours it spans
+func (r *run) busy(from, to time.Time) {
+	r.total.busy += to.Sub(from)
+	for _, bucket := range r.hours {
+		start, end := from, to
+		if start.Before(bucket.Start) {
+			start = bucket.Start
+		}
+		if spanEnd := bucket.Start.Add(bucket.span); end.After(spanEnd) {
+			end = spanEnd
+		}
+		if overlap := end.Sub(start); overlap > 0 {
+			bucket.busy += overlap
+		}
+	}
+}
+
+func (r *run) hour(at time.Time) *Bucket {
+	i := int(at.Sub(r.options.Start) / time.Hour)
+	if i < 0 || i >= len(r.hours) {
+		return nil
+	}
+	return r.hours[i]
+}
+
+// send hands a command to the backend as the next event, as if it came over the websocket
+func (r *run) send(userId string, command models.Command, payload interface{}) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		log.Printf("des: marshal %s: %v", command, err)
+		return
+	}
+	r.engine.At(r.engine.Now(), func() {
+		r.backend.Receive(userId, models.IncomingMessage{Command: command, Payload: data})
+	})
+}
+
+// deliverer returns the in-process session callback of an actor. The backend calls it with its
+// lock held, so the frame is decoded and handled as the next event rather than right away.
+func (r *run) deliverer(kind string, dispatch func(command models.Command, payload interface{})) func(message []byte) {
+	return func(message []byte) {
+		r.engine.At(r.engine.Now(), func() {
+			serverMessage, payload, err := models.DecodeServerMessage(message)
+			if err != nil {
+				log.Printf("des %s decode: %v", kind, err)
+				return
+			}
+			dispatch(serverMessage.Command, payload)
+		})
+	}
+}
+
+// randomPoint picks a point within radius meters of the center of the zone
+func (r *run) randomPoint(radius float64) (float64, float64) {
+	lat := r.options.CenterLat * math.Pi / 180
+	lng := r.options.CenterLng * math.Pi / 180
+	distance := r.rng.Float64() * radius / earthRadius
+	bearing := r.rng.Float64() * 2 * math.Pi
+
+	newLat := math.Asin(math.Sin(lat)*math.Cos(distance) + math.Cos(lat)*math.Sin(distance)*math.Cos(bearing))
+	newLng := lng + math.Atan2(math.Sin(bearing)*math.Sin(distance)*math.Cos(lat), math.Cos(distance)-math.Sin(lat)*math.Sin(newLat))
+	return newLat * 180 / math.Pi, newLng * 180 / math.Pi
+}
+
+// percentile returns the p-th percentile of values, sorting them in place
+func percentile(values []float64, p float64) float64 {
+	slices.Sort(values)
+	return values[int(math.Ceil(p*float64(len(values))))-1]
+}
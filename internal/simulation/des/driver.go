@@ -0,0 +1,274 @@
+package des
+
+import (
+	"time"
+
+	"sim-server/internal/models"
+	"sim-server/internal/simulation/drivers"
+	"sim-server/internal/simulation/fsm"
+
+	"googlemaps.github.io/maps"
+)
+
+// driver follows drivers.DefaultScript through drivers.Lifecycle, as the live SimulatedDriver does,
+// with the sleeps of the script turned into events
+type driver struct {
+	run       *run
+	id        string
+	machine   *fsm.Machine
+	lat       float64
+	lng       float64
+	tripId    string
+	tripOffer *models.NewTripOfferMessage
+	// scheduled trips accepted ahead, they become the current trip once the driver leaves for the pickup
+	scheduledTrips map[string]*models.NewTripOfferMessage
+	busySince      time.Time // zero while the driver is available
+}
+
+func newDriver(r *run, phoneNumber string, lat, lng float64) *driver {
+	d := &driver{run: r, lat: lat, lng: lng, scheduledTrips: make(map[string]*models.NewTripOfferMessage)}
+	d.id = r.backend.ConnectDriver(phoneNumber, r.deliverer("driver", d.dispatch))
+	d.machine = fsm.New("driver "+d.id, drivers.StateOffline, drivers.Lifecycle)
+	return d
+}
+
+// start puts the driver online and pings its location until the end of the run
+func (d *driver) start() {
+	d.transition(drivers.StateIdle)
+	d.pingLoop()
+}
+
+func (d *driver) pingLoop() {
+	d.pingDriverLocation()
+	d.run.engine.After(d.run.driverScript.PingLocation, d.pingLoop)
+}
+
+func (d *driver) dispatch(command models.Command, payload interface{}) {
+	switch command {
+	case models.NewTripOffer:
+		d.handleNewTripOffer(payload.(*models.NewTripOfferMessage))
+	case models.CompleteTrip:
+		d.handleTripCompletion(payload.(*models.TripStatusMessage))
+	case models.CancelTrip:
+		d.handleTripCancellation(payload.(*models.TripStatusMessage))
+	case models.ModifyTrip:
+		d.handleTripModification(payload.(*models.ConfirmTripMessage))
+	}
+}
+
+// transition moves the state machine and keeps track of the time the driver spends on trips
+func (d *driver) transition(to fsm.State) error {
+	if err := d.machine.Transition(to); err != nil {
+		return err
+	}
+	busy := d.machine.Is(drivers.StateEnRoute, drivers.StateArrived, drivers.StateOnTrip, drivers.StateRating)
+	switch {
+	case busy && d.busySince.IsZero():
+		d.busySince = d.run.engine.Now()
+	case !busy && !d.busySince.IsZero():
+		d.stopClock()
+	}
+	return nil
+}
+
+// stopClock books the time on the current trip, at the end of a trip or of the run
+func (d *driver) stopClock() {
+	if d.busySince.IsZero() {
+		return
+	}
+	d.run.busy(d.busySince, d.run.engine.Now())
+	d.busySince = time.Time{}
+}
+
+func (d *driver) handleNewTripOffer(offer *models.NewTripOfferMessage) {
+	tripId := offer.TripOffer.TripId
+	if scheduledAt := offer.TripOffer.Trip.ScheduledAt; scheduledAt > d.run.engine.Now().Unix() {
+		// pre-assigned offers for scheduled trips are always honoured
+		d.acceptScheduledTrip(offer, time.Unix(scheduledAt, 0))
+		return
+	}
+	if err := d.transition(drivers.StateOffered); err != nil {
+		d.sendTripAction(models.RejectTrip, tripId)
+		return
+	}
+	d.tripOffer = offer
+	d.tripId = tripId
+
+	if d.run.rng.Float64() < d.run.options.AcceptanceRate {
+		d.acceptTrip()
+	} else {
+		d.rejectOffer()
+	}
+}
+
+func (d *driver) acceptTrip() {
+	if d.transition(drivers.StateEnRoute) != nil {
+		return
+	}
+	tripId := d.tripId
+	d.sendTripAction(models.AcceptTrip, tripId)
+	d.inTrip(tripId, d.run.driverScript.BeforeArrival, func() { d.driveTrip(tripId) })
+}
+
+func (d *driver) rejectOffer() {
+	if d.transition(drivers.StateIdle) != nil {
+		return
+	}
+	tripId := d.tripId
+	d.tripOffer = nil
+	d.tripId = ""
+	d.sendTripAction(models.RejectTrip, tripId)
+}
+
+func (d *driver) acceptScheduledTrip(offer *models.NewTripOfferMessage, scheduledAt time.Time) {
+	tripId := offer.TripOffer.TripId
+	d.sendTripAction(models.AcceptTrip, tripId)
+	d.scheduledTrips[tripId] = offer
+	d.scheduleDeparture(tripId, scheduledAt)
+}
+
+func (d *driver) scheduleDeparture(tripId string, scheduledAt time.Time) {
+	d.run.engine.At(scheduledAt.Add(-d.run.driverScript.BeforeArrival), func() { d.leaveForPickup(tripId, scheduledAt) })
+}
+
+// handleTripModification moves the pickup of a scheduled trip to the time the customer changed it to
+func (d *driver) handleTripModification(payload *models.ConfirmTripMessage) {
+	offer, ok := d.scheduledTrips[payload.Id]
+	if !ok || offer.TripOffer.Trip.ScheduledAt == payload.ScheduledAt {
+		return
+	}
+	moved := *offer
+	moved.TripOffer.Trip.ScheduledAt = payload.ScheduledAt
+	d.scheduledTrips[payload.Id] = &moved
+	d.scheduleDeparture(payload.Id, time.Unix(payload.ScheduledAt, 0))
+}
+
+// leaveForPickup makes a scheduled trip the current one when its time comes
+func (d *driver) leaveForPickup(tripId string, scheduledAt time.Time) {
+	offer, ok := d.scheduledTrips[tripId]
+	if ok && offer.TripOffer.Trip.ScheduledAt != scheduledAt.Unix() {
+		// the customer moved the pickup, the departure at the new time takes it from here
+		return
+	}
+	delete(d.scheduledTrips, tripId)
+	if !ok {
+		// cancelled by the customer in the meantime
+		return
+	}
+	if d.transition(drivers.StateEnRoute) != nil {
+		// still busy with another trip
+		return
+	}
+	d.tripOffer = offer
+	d.tripId = tripId
+	d.inTrip(tripId, d.run.driverScript.BeforeArrival, func() { d.driveTrip(tripId) })
+}
+
+// driveTrip drives the current trip from the pickup to the drop off, each step is an event of its
+// own that is dropped once the trip is no longer the current one, e.g. after a cancellation
+func (d *driver) driveTrip(tripId string) {
+	offer := d.tripOffer
+	trip := offer.TripOffer.Trip
+	script := d.run.driverScript
+
+	d.followRoute(tripId, offer.PickupEstimate, func() {
+		if d.transition(drivers.StateArrived) != nil {
+			return
+		}
+		d.moveTo(trip.OriginLat, trip.OriginLng)
+		d.sendTripAction(models.ArrivedForPickup, tripId)
+
+		d.inTrip(tripId, script.BeforeStartTrip, func() {
+			if d.transition(drivers.StateOnTrip) != nil {
+				return
+			}
+			d.sendTripAction(models.StartTrip, tripId)
+
+			d.followRoute(tripId, offer.TripEstimate, func() {
+				d.moveTo(trip.DestinationLat, trip.DestinationLng)
+				d.inTrip(tripId, script.BeforeCompleteTrip, func() {
+					if d.transition(drivers.StateRating) == nil {
+						d.sendTripAction(models.CompleteTrip, tripId)
+					}
+				})
+			})
+		})
+	})
+}
+
+// followRoute moves the driver along a route, pinging every point of it, and runs then once it is
+// through. The points are spread over the duration the dispatcher estimated for the route.
+func (d *driver) followRoute(tripId string, route models.RouteEstimate, then func()) {
+	coordinates, _ := maps.DecodePolyline(route.Route.Polyline.EncodedPolyline)
+	interval := d.run.driverScript.TripPing
+	if len(coordinates) > 0 {
+		interval = max(interval, time.Duration(route.Duration/float64(len(coordinates))*float64(time.Second)))
+	}
+
+	var visit func(i int)
+	visit = func(i int) {
+		if i == len(coordinates) {
+			then()
+			return
+		}
+		d.moveTo(coordinates[i].Lat, coordinates[i].Lng)
+		d.inTrip(tripId, interval, func() { visit(i + 1) })
+	}
+	visit(0)
+}
+
+// inTrip runs step after delay as long as tripId is still the current trip
+func (d *driver) inTrip(tripId string, delay time.Duration, step func()) {
+	d.run.engine.After(delay, func() {
+		if d.tripId == tripId {
+			step()
+		}
+	})
+}
+
+func (d *driver) handleTripCompletion(payload *models.TripStatusMessage) {
+	if !d.machine.Is(drivers.StateRating) || payload.TripID() != d.tripId {
+		return
+	}
+	tripId := d.tripId
+	if d.transition(drivers.StateIdle) != nil {
+		return
+	}
+	d.tripOffer = nil
+	d.tripId = ""
+	d.run.send(d.id, models.RateCustomer, models.TripRatingPayload{TripId: tripId, Rating: d.run.rng.Float64() * 5})
+}
+
+func (d *driver) handleTripCancellation(payload *models.TripStatusMessage) {
+	tripId := payload.TripID()
+	delete(d.scheduledTrips, tripId)
+
+	if tripId == "" || tripId != d.tripId {
+		return
+	}
+	if d.transition(drivers.StateIdle) != nil {
+		return
+	}
+	d.tripOffer = nil
+	d.tripId = ""
+}
+
+func (d *driver) moveTo(lat, lng float64) {
+	d.lat = lat
+	d.lng = lng
+	d.pingDriverLocation()
+}
+
+func (d *driver) pingDriverLocation() {
+	d.run.send(d.id, models.DriverLocation, models.DriverLocationPayload{
+		RawLocation: models.RawLocation{
+			Type:        "Point",
+			Coordinates: models.LatLongCoordinates{Latitude: d.lat, Longitude: d.lng},
+		},
+		VehicleCategoryId: 2,
+	})
+}
+
+func (d *driver) sendTripAction(command models.Command, tripId string) {
+	d.run.send(d.id, command, models.TripActionPayload{TripId: tripId})
+}
@@ -0,0 +1,100 @@
+package des
+
+import (
+	"container/heap"
+	"time"
+)
+
+// Engine runs events in the order of their virtual time. It is single threaded: an event runs to
+// completion before the next one starts and schedules the events that follow from it, so time only
+// moves when the engine picks the next event and a simulated day takes as long as its events do.
+type Engine struct {
+	now   time.Time
+	queue queue
+	seq   uint64 // breaks ties between events at the same time, in the order they were scheduled
+}
+
+// Event is a scheduled call of the engine, it satisfies mockbackend.Timer
+type Event struct {
+	at      time.Time
+	seq     uint64
+	fn      func()
+	stopped bool
+	done    bool
+}
+
+// Stop keeps the event from running, it reports whether the event was still pending
+func (e *Event) Stop() bool {
+	pending := !e.stopped && !e.done
+	e.stopped = true
+	return pending
+}
+
+func NewEngine(start time.Time) *Engine {
+	return &Engine{now: start}
+}
+
+// Now is the virtual time of the event running
+func (e *Engine) Now() time.Time {
+	return e.now
+}
+
+// At schedules fn at t, times in the past run next
+func (e *Engine) At(t time.Time, fn func()) *Event {
+	if t.Before(e.now) {
+		t = e.now
+	}
+	e.seq++
+	event := &Event{at: t, seq: e.seq, fn: fn}
+	heap.Push(&e.queue, event)
+	return event
+}
+
+// After schedules fn d after the current time
+func (e *Engine) After(d time.Duration, fn func()) *Event {
+	return e.At(e.now.Add(d), fn)
+}
+
+// Run runs the events up to until and returns how many ran. The clock is left at until, events
+// scheduled past it stay queued.
+func (e *Engine) Run(until time.Time) int {
+	ran := 0
+	for e.queue.Len() > 0 && !e.queue[0].at.After(until) {
+		event := heap.Pop(&e.queue).(*Event)
+		if event.stopped {
+			continue
+		}
+		e.now = event.at
+		event.done = true
+		event.fn()
+		ran++
+	}
+	if until.After(e.now) {
+		e.now = until
+	}
+	return ran
+}
+
+// queue is a min-heap of events by time
+type queue []*Event
+
+func (q queue) Len() int { return len(q) }
+
+func (q queue) Less(i, j int) bool {
+	if q[i].at.Equal(q[j].at) {
+		return q[i].seq < q[j].seq
+	}
+	return q[i].at.Before(q[j].at)
+}
+
+func (q queue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+
+func (q *queue) Push(x interface{}) { *q = append(*q, x.(*Event)) }
+
+func (q *queue) Pop() interface{} {
+	old := *q
+	event := old[len(old)-1]
+	old[len(old)-1] = nil
+	*q = old[:len(old)-1]
+	return event
+}
@@ -0,0 +1,108 @@
+package des
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestEngineOrder(t *testing.T) {
+	start := time.Date(2024, 1, 1, 8, 0, 0, 0, time.UTC)
+	type event struct {
+		name string
+		at   time.Duration // after start
+		stop bool          // stopped before running
+	}
+	tests := []struct {
+		name   string
+		events []event
+		until  time.Duration
+		ran    []string
+	}{
+		{
+			name:   "by time",
+			events: []event{{name: "c", at: 3 * time.Second}, {name: "a", at: time.Second}, {name: "b", at: 2 * time.Second}},
+			until:  time.Minute,
+			ran:    []string{"a", "b", "c"},
+		},
+		{
+			name:   "ties in the order they were scheduled",
+			events: []event{{name: "first", at: time.Second}, {name: "second", at: time.Second}, {name: "third", at: time.Second}},
+			until:  time.Minute,
+			ran:    []string{"first", "second", "third"},
+		},
+		{
+			name:   "stopped events do not run",
+			events: []event{{name: "a", at: time.Second}, {name: "cancelled", at: 2 * time.Second, stop: true}, {name: "b", at: 3 * time.Second}},
+			until:  time.Minute,
+			ran:    []string{"a", "b"},
+		},
+		{
+			name:   "the past runs first",
+			events: []event{{name: "now", at: 0}, {name: "late", at: -time.Hour}},
+			until:  time.Minute,
+			ran:    []string{"now", "late"},
+		},
+		{
+			name:   "events past until stay queued",
+			events: []event{{name: "a", at: time.Second}, {name: "at until", at: time.Minute}, {name: "later", at: time.Minute + time.Nanosecond}},
+			until:  time.Minute,
+			ran:    []string{"a", "at until"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := NewEngine(start)
+			var ran []string
+			var seen []time.Time
+			for _, e := range tt.events {
+				name := e.name
+				event := engine.At(start.Add(e.at), func() {
+					ran = append(ran, name)
+					seen = append(seen, engine.Now())
+				})
+				if e.stop && !event.Stop() {
+					t.Fatalf("%s was not pending when stopped", name)
+				}
+			}
+
+			if n := engine.Run(start.Add(tt.until)); n != len(tt.ran) {
+				t.Errorf("ran %d events, want %d", n, len(tt.ran))
+			}
+			if !reflect.DeepEqual(ran, tt.ran) {
+				t.Errorf("ran %v, want %v", ran, tt.ran)
+			}
+			for i := 1; i < len(seen); i++ {
+				if seen[i].Before(seen[i-1]) {
+					t.Errorf("time went back from %v to %v", seen[i-1], seen[i])
+				}
+			}
+			if !engine.Now().Equal(start.Add(tt.until)) {
+				t.Errorf("engine at %v after the run, want %v", engine.Now(), start.Add(tt.until))
+			}
+		})
+	}
+}
+
+func TestEngineFollowUps(t *testing.T) {
+	start := time.Date(2024, 1, 1, 8, 0, 0, 0, time.UTC)
+	engine := NewEngine(start)
+	var ran []string
+	engine.After(time.Second, func() {
+		ran = append(ran, "trip")
+		// scheduled while running, at the same time as a queued event they go after it
+		engine.After(0, func() { ran = append(ran, "follow-up") })
+		engine.After(time.Second, func() { ran = append(ran, "next") })
+	})
+	engine.After(time.Second, func() { ran = append(ran, "queued") })
+	done := engine.After(time.Hour, func() { ran = append(ran, "late") })
+
+	engine.Run(start.Add(time.Minute))
+	if want := []string{"trip", "queued", "follow-up", "next"}; !reflect.DeepEqual(ran, want) {
+		t.Errorf("ran %v, want %v", ran, want)
+	}
+	engine.Run(start.Add(2 * time.Hour))
+	if done.Stop() {
+		t.Error("an event that ran was still pending")
+	}
+}
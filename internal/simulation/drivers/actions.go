@@ -0,0 +1,109 @@
+package drivers
+
+import (
+	"context"
+	"log"
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+
+	pb "sim-server/internal/genserver/proto"
+	"sim-server/internal/simulation/actors"
+	"sim-server/internal/simulation/fsm"
+)
+
+// Actions a driver takes through the GenServer service, see actors.RegisterCall and actors.RegisterCast
+func init() {
+	actors.RegisterCall(actorKind, "state", func(sim *SimulatedDriver, _ struct{}) (interface{}, error) {
+		return sim.snapshot(), nil
+	})
+	actors.RegisterCast(actorKind, "set_acceptance_rate", func(sim *SimulatedDriver, payload acceptanceRatePayload) {
+		sim.acceptanceRate = payload.AcceptanceRate
+	})
+	actors.RegisterCast(actorKind, "set_manual", func(sim *SimulatedDriver, payload manualPayload) {
+		sim.manual = payload.Manual
+	})
+	actors.RegisterCast(actorKind, "go_offline", func(sim *SimulatedDriver, _ struct{}) {
+		sim.goOffline()
+	})
+	actors.RegisterCast(actorKind, "go_online", func(sim *SimulatedDriver, _ struct{}) {
+		sim.goOnline()
+	})
+}
+
+// DriverState is a consistent copy of the state of a driver, returned by Snapshot and the state call
+type DriverState struct {
+	Id             string    `json:"id"`
+	ScenarioId     string    `json:"scenario_id,omitempty"`
+	State          fsm.State `json:"state"`
+	StateSince     time.Time `json:"state_since"`
+	Lat            float64   `json:"lat"`
+	Lng            float64   `json:"lng"`
+	TripId         string    `json:"trip_id,omitempty"`
+	ScheduledTrips int       `json:"scheduled_trips"`
+	AcceptanceRate float64   `json:"acceptance_rate"`
+	Manual         bool      `json:"manual"`
+	Offline        bool      `json:"offline"`
+	Connected      bool      `json:"connected"`
+}
+
+type acceptanceRatePayload struct {
+	AcceptanceRate float64 `json:"acceptance_rate"`
+}
+
+func (p acceptanceRatePayload) Validate() error {
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.AcceptanceRate, validation.Min(0.0), validation.Max(1.0)),
+	)
+}
+
+type manualPayload struct {
+	Manual bool `json:"manual"`
+}
+
+// Snapshot copies the state of a running driver in its mailbox, so it is safe to call from anywhere.
+func Snapshot(driverId string) (state DriverState, err error) {
+	err = call(driverId, func(sim *SimulatedDriver) { state = sim.snapshot() })
+	return state, err
+}
+
+func (sim *SimulatedDriver) snapshot() DriverState {
+	scheduledTrips := len(sim.scheduledTrips)
+	state, since := sim.machine.Current()
+	return DriverState{
+		Id:             sim.driver.Id,
+		ScenarioId:     sim.scenarioId,
+		State:          state,
+		StateSince:     since,
+		Lat:            sim.lat,
+		Lng:            sim.lng,
+		TripId:         sim.tripId,
+		ScheduledTrips: scheduledTrips,
+		AcceptanceRate: sim.acceptanceRate,
+		Manual:         sim.manual,
+		Offline:        sim.offline,
+		Connected:      sim.conn != nil && sim.conn.Connected(),
+	}
+}
+
+// goOffline closes the websocket, the driver stays registered and can be told to go online again
+func (sim *SimulatedDriver) goOffline() {
+	if err := sim.machine.Transition(StateOffline); err == nil {
+		// the trip in progress is abandoned
+		sim.tripOffer = nil
+		sim.tripId = ""
+	}
+	sim.offline = true
+	if sim.conn != nil {
+		sim.conn.Close()
+		sim.setConn(nil)
+	}
+	log.Printf("driver %s: forced offline", sim.driver.Id)
+}
+
+func (sim *SimulatedDriver) goOnline() {
+	sim.offline = false
+	ctx := context.Background()
+	sim.GoOnline(ctx, &pb.GoOnlineRequest{})
+	sim.InitConnection(ctx, &pb.InitConnectionRequest{})
+}
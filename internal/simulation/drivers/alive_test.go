@@ -0,0 +1,30 @@
+package drivers
+
+import (
+	"context"
+	"testing"
+
+	pb "sim-server/internal/genserver/proto"
+)
+
+func TestIsAlive(t *testing.T) {
+	tests := []struct {
+		name    string
+		started bool
+		offline bool
+		alive   bool
+	}{
+		{name: "starting", alive: true},
+		{name: "started without a websocket", started: true},
+		{name: "forced offline", started: true, offline: true, alive: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sim := &SimulatedDriver{started: tt.started, offline: tt.offline}
+			_, err := sim.IsAlive(context.Background(), &pb.IsAliveRequest{})
+			if alive := err == nil; alive != tt.alive {
+				t.Errorf("alive %v, want %v: %v", alive, tt.alive, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package drivers
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"sim-server/config"
+	"sim-server/internal/models"
+	"sim-server/internal/simulation/checkpoint"
+)
+
+// Checkpoint returns what it takes to start the driver again after a restart, it runs in the mailbox
+func (sim *SimulatedDriver) Checkpoint() checkpoint.Actor {
+	state, _ := sim.machine.Current()
+	scheduled := make([]*models.NewTripOfferMessage, 0, len(sim.scheduledTrips))
+	for _, offer := range sim.scheduledTrips {
+		scheduled = append(scheduled, offer)
+	}
+	return checkpoint.Actor{
+		Kind:           actorKind,
+		ScenarioId:     sim.scenarioId,
+		Id:             sim.driver.Id,
+		Name:           sim.driver.Name,
+		PhoneNumber:    sim.driver.PhoneNumber,
+		AccessToken:    sim.session.Token(),
+		State:          state,
+		Lat:            sim.lat,
+		Lng:            sim.lng,
+		TripId:         sim.tripId,
+		Manual:         sim.manual,
+		AcceptanceRate: sim.acceptanceRate,
+		TripOffer:      sim.tripOffer,
+		ScheduledTrips: scheduled,
+	}
+}
+
+// Restore starts a driver again from its checkpoint with the token it had. Once it is connected it
+// syncs with the backend and carries on with the trip the backend reports, see resume. A driver
+// that is already running is left as it is, its state is newer than the checkpoint.
+func Restore(saved checkpoint.Actor, target config.Target) error {
+	driver := models.Driver{Id: saved.Id, Name: saved.Name, PhoneNumber: saved.PhoneNumber, AccessToken: saved.AccessToken}
+	if !newSimulatedDriver(driver, target, saved.ScenarioId, saved.Lat, saved.Lng, saved.AcceptanceRate, saved.Manual) {
+		return fmt.Errorf("driver %s is already running, not restoring its checkpoint", saved.Id)
+	}
+	return call(saved.Id, func(sim *SimulatedDriver) {
+		sim.machine.Reset(saved.State)
+		sim.tripId = saved.TripId
+		sim.tripOffer = saved.TripOffer
+		for _, offer := range saved.ScheduledTrips {
+			sim.scheduledTrips[offer.TripOffer.TripId] = offer
+		}
+		sim.recovering = true
+	})
+}
+
+// resume carries on with the scenario after the first sync of a restored driver: the active trip is
+// driven on from the step the backend reports and the scheduled trips are picked up in time
+func (sim *SimulatedDriver) resume(trip *models.Trip) {
+	if trip != nil {
+		if sim.tripOffer == nil || sim.tripOffer.TripOffer.TripId != trip.Id {
+			// the routes of the offer are lost, the driver heads straight for the next stop
+			sim.tripOffer = &models.NewTripOfferMessage{TripOffer: models.TripOffer{TripId: trip.Id, Trip: *trip}}
+		}
+		delete(sim.scheduledTrips, trip.Id)
+	} else if sim.machine.Is(StateOffered, StateRating) {
+		// the offer timed out or the trip was rated while the driver was down
+		sim.machine.Reset(StateIdle)
+		sim.tripOffer = nil
+		sim.tripId = ""
+	}
+	state, _ := sim.machine.Current()
+	log.Printf("driver %s: resuming in %s", sim.driver.Id, state)
+	if sim.manual {
+		return
+	}
+
+	for tripId, offer := range sim.scheduledTrips {
+		scheduledAt := time.Unix(offer.TripOffer.Trip.ScheduledAt, 0)
+		sim.self.Go(func() { sim.awaitScheduledPickup(tripId, scheduledAt) })
+	}
+	if trip == nil {
+		return
+	}
+	tripId := trip.Id
+	switch {
+	case sim.machine.Is(StateEnRoute):
+		sim.self.Go(func() { sim.handleDriverArrival(tripId) })
+	case sim.machine.Is(StateArrived):
+		sim.self.Go(func() { sim.handlePickup(tripId) })
+	case sim.machine.Is(StateOnTrip):
+		sim.self.Go(func() { sim.handleRide(tripId) })
+	case sim.machine.Is(StateRating):
+		sim.rateCustomer(models.FloatBetweenZeroToOne() * 5)
+	}
+}
@@ -0,0 +1,36 @@
+package drivers
+
+import (
+	"testing"
+
+	"sim-server/config"
+	"sim-server/internal/models"
+	"sim-server/internal/simulation/actors"
+	"sim-server/internal/simulation/checkpoint"
+)
+
+func TestRestoreLeavesRunningDriver(t *testing.T) {
+	const driverId = "restored-over"
+	NewSimulatedDriver(models.Driver{Id: driverId}, config.Target{}, "", 28.6139, 77.2090, 1, false)
+	defer func() {
+		if actor, ok := actors.Lookup(driverId); ok {
+			actor.Stop()
+		}
+	}()
+	err := call(driverId, func(sim *SimulatedDriver) {
+		sim.machine.Reset(StateEnRoute)
+		sim.tripId = "live"
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	saved := checkpoint.Actor{Kind: actorKind, Id: driverId, State: StateIdle, TripId: "saved"}
+	if err := Restore(saved, config.Target{}); err == nil {
+		t.Error("restored a checkpoint over a running driver")
+	}
+	state, err := Snapshot(driverId)
+	if err != nil || state.TripId != "live" || state.State != StateEnRoute {
+		t.Errorf("driver on trip %q in %s after the restore, want the live one: %v", state.TripId, state.State, err)
+	}
+}
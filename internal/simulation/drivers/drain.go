@@ -0,0 +1,31 @@
+package drivers
+
+import (
+	"log"
+
+	"sim-server/internal/models"
+)
+
+// Drain lets the trip in progress run to its end, new offers are rejected and the scheduled trips
+// accepted ahead are not picked up, see actors.Shutdown
+func (sim *SimulatedDriver) Drain() {
+	sim.draining = true
+}
+
+// Drained reports whether the driver is done with its trip
+func (sim *SimulatedDriver) Drained() bool {
+	return sim.machine.Is(StateIdle, StateOffline)
+}
+
+// SignOff abandons the trip still in progress and ends the shift of the driver in the backend
+func (sim *SimulatedDriver) SignOff() {
+	sim.draining = true
+	if sim.conn == nil {
+		return
+	}
+	if sim.tripId != "" {
+		log.Printf("driver %s: abandoning trip %s", sim.driver.Id, sim.tripId)
+	}
+	sim.sendMessageToClient(models.GoOffline, struct{}{})
+	sim.goOffline()
+}
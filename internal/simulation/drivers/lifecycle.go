@@ -0,0 +1,35 @@
+package drivers
+
+import "sim-server/internal/simulation/fsm"
+
+// States of a driver, reported by the state call
+const (
+	StateOffline fsm.State = "offline"
+	StateIdle    fsm.State = "idle"
+	StateOffered fsm.State = "offered"  // an offer waits for an answer
+	StateEnRoute fsm.State = "en_route" // driving to the pickup
+	StateArrived fsm.State = "arrived"
+	StateOnTrip  fsm.State = "on_trip"
+	StateRating  fsm.State = "rating" // the trip is complete, the customer is not rated yet
+)
+
+// Lifecycle is the order a driver goes through a trip. A trip can be cancelled up to its start,
+// and the driver can be taken offline at any time. Scheduled trips are accepted from idle and
+// only become the current trip once the driver leaves for the pickup.
+var Lifecycle = fsm.Table{
+	StateOffline: {StateIdle},
+	StateIdle:    {StateOffered, StateEnRoute, StateOffline},
+	StateOffered: {StateEnRoute, StateIdle, StateOffline},
+	StateEnRoute: {StateArrived, StateIdle, StateOffline},
+	StateArrived: {StateOnTrip, StateIdle, StateOffline},
+	StateOnTrip:  {StateRating, StateIdle, StateOffline},
+	StateRating:  {StateIdle, StateOffline},
+}
+
+// tripStates maps the status of the active trip the backend reports on sync to the driver state
+var tripStates = map[string]fsm.State{
+	"accepted":  StateEnRoute,
+	"arrived":   StateArrived,
+	"started":   StateOnTrip,
+	"completed": StateRating,
+}
@@ -0,0 +1,55 @@
+package drivers
+
+import (
+	"testing"
+
+	"sim-server/internal/simulation/fsm"
+)
+
+func TestLifecycle(t *testing.T) {
+	tests := []struct {
+		name  string
+		path  []fsm.State
+		legal bool
+	}{
+		{name: "trip now", path: []fsm.State{StateOffline, StateIdle, StateOffered, StateEnRoute, StateArrived, StateOnTrip, StateRating, StateIdle}, legal: true},
+		{name: "rejected offer", path: []fsm.State{StateIdle, StateOffered, StateIdle}, legal: true},
+		{name: "scheduled trip", path: []fsm.State{StateIdle, StateEnRoute, StateArrived}, legal: true},
+		{name: "cancelled on the way", path: []fsm.State{StateEnRoute, StateIdle}, legal: true},
+		{name: "cancelled on board", path: []fsm.State{StateOnTrip, StateIdle}, legal: true},
+		{name: "offline during a trip", path: []fsm.State{StateArrived, StateOffline}, legal: true},
+		{name: "offer while offline", path: []fsm.State{StateOffline, StateOffered}},
+		{name: "second offer", path: []fsm.State{StateOffered, StateOffered}},
+		{name: "start before arriving", path: []fsm.State{StateEnRoute, StateOnTrip}},
+		{name: "rating again", path: []fsm.State{StateRating, StateRating}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := fsm.New("driver", tt.path[0], Lifecycle)
+			var err error
+			for _, state := range tt.path[1:] {
+				if err = m.Transition(state); err != nil {
+					break
+				}
+			}
+			if legal := err == nil; legal != tt.legal {
+				t.Errorf("path %v legal %v, want %v: %v", tt.path, legal, tt.legal, err)
+			}
+		})
+	}
+}
+
+func TestLifecycleIsClosed(t *testing.T) {
+	for from, targets := range Lifecycle {
+		for _, to := range targets {
+			if _, ok := Lifecycle[to]; !ok {
+				t.Errorf("%s moves to %s, which has no transitions", from, to)
+			}
+		}
+	}
+	for status, state := range tripStates {
+		if _, ok := Lifecycle[state]; !ok {
+			t.Errorf("trip status %s maps to %s, which is not in the lifecycle", status, state)
+		}
+	}
+}
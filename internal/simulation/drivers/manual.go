@@ -0,0 +1,190 @@
+package drivers
+
+import (
+	"context"
+
+	pb "sim-server/internal/genserver/proto"
+	"sim-server/internal/simulation/fsm"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Manual control lets a tool drive a driver started in manual mode through a trip step by step,
+// the requests default to the current trip when they leave the trip id empty.
+
+// Action names a manual control step, as used by the REST endpoints
+type Action string
+
+const (
+	ActionAccept   Action = "accept"
+	ActionReject   Action = "reject"
+	ActionArrive   Action = "arrive"
+	ActionStart    Action = "start"
+	ActionComplete Action = "complete"
+	ActionRate     Action = "rate"
+)
+
+// Control runs a manual control step on a running driver, rating is only used to rate the customer.
+// It reports whether the command was sent to the backend.
+func Control(driverId string, action Action, tripId string, rating float32) (success bool, err error) {
+	callErr := call(driverId, func(sim *SimulatedDriver) {
+		ctx := context.Background()
+		switch action {
+		case ActionAccept:
+			var response *pb.DriverAcceptTripResponse
+			response, err = sim.AcceptTrip(ctx, &pb.DriverAcceptTripRequest{TripId: tripId})
+			success = response.GetSuccess()
+		case ActionReject:
+			var response *pb.DriverRejectTripResponse
+			response, err = sim.RejectTrip(ctx, &pb.DriverRejectTripRequest{TripId: tripId})
+			success = response.GetSuccess()
+		case ActionArrive:
+			var response *pb.DriverArrivalResponse
+			response, err = sim.DriverArrival(ctx, &pb.DriverArrivalRequest{TripId: tripId})
+			success = response.GetSuccess()
+		case ActionStart:
+			var response *pb.DriverStartTripResponse
+			response, err = sim.StartTrip(ctx, &pb.DriverStartTripRequest{TripId: tripId})
+			success = response.GetSuccess()
+		case ActionComplete:
+			var response *pb.DriverCompleteTripResponse
+			response, err = sim.CompleteTrip(ctx, &pb.DriverCompleteTripRequest{TripId: tripId})
+			success = response.GetSuccess()
+		case ActionRate:
+			var response *pb.RatingResponse
+			response, err = sim.RateCustomer(ctx, &pb.RatingRequest{TripId: tripId, Rating: rating})
+			success = response.GetSuccess()
+		default:
+			err = status.Errorf(codes.InvalidArgument, "unknown action %q", action)
+		}
+	})
+	if callErr != nil {
+		return false, callErr
+	}
+	return success, err
+}
+
+func (sim *SimulatedDriver) AcceptTrip(ctx context.Context, req *pb.DriverAcceptTripRequest) (*pb.DriverAcceptTripResponse, error) {
+	if err := sim.pendingOffer(req.GetTripId()); err != nil {
+		return nil, err
+	}
+	if sim.scheduledPickup(sim.tripOffer) > 0 {
+		// the driver stays available and still has to be told to arrive once the pickup time comes
+		offer := sim.tripOffer
+		if err := sim.machine.Transition(StateIdle); err != nil {
+			return nil, status.Error(codes.FailedPrecondition, err.Error())
+		}
+		sim.tripOffer = nil
+		sim.tripId = ""
+		return &pb.DriverAcceptTripResponse{Success: sim.acceptScheduled(offer)}, nil
+	}
+	sent, err := sim.acceptTrip()
+	if err != nil {
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	}
+	return &pb.DriverAcceptTripResponse{Success: sent}, nil
+}
+
+func (sim *SimulatedDriver) RejectTrip(ctx context.Context, req *pb.DriverRejectTripRequest) (*pb.DriverRejectTripResponse, error) {
+	if err := sim.pendingOffer(req.GetTripId()); err != nil {
+		return nil, err
+	}
+	return &pb.DriverRejectTripResponse{Success: sim.rejectOffer()}, nil
+}
+
+// DriverArrival moves the driver to the pickup point of the trip and reports the arrival
+func (sim *SimulatedDriver) DriverArrival(ctx context.Context, req *pb.DriverArrivalRequest) (*pb.DriverArrivalResponse, error) {
+	if err := sim.manualTrip(req.GetTripId()); err != nil {
+		return nil, err
+	}
+	sent, err := sim.driverArrival()
+	if err != nil {
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	}
+	return &pb.DriverArrivalResponse{Success: sent}, nil
+}
+
+func (sim *SimulatedDriver) StartTrip(ctx context.Context, req *pb.DriverStartTripRequest) (*pb.DriverStartTripResponse, error) {
+	if err := sim.manualTrip(req.GetTripId()); err != nil {
+		return nil, err
+	}
+	sent, err := sim.startTrip()
+	if err != nil {
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	}
+	return &pb.DriverStartTripResponse{Success: sent}, nil
+}
+
+// CompleteTrip moves the driver to the destination of the trip and completes it
+func (sim *SimulatedDriver) CompleteTrip(ctx context.Context, req *pb.DriverCompleteTripRequest) (*pb.DriverCompleteTripResponse, error) {
+	if err := sim.manualTrip(req.GetTripId()); err != nil {
+		return nil, err
+	}
+	if !sim.machine.Is(StateOnTrip) {
+		return nil, sim.notIn(StateOnTrip)
+	}
+	if sim.tripOffer != nil && sim.tripOffer.TripOffer.TripId == sim.tripId {
+		sim.moveTo(sim.tripOffer.TripOffer.Trip.DestinationLat, sim.tripOffer.TripOffer.Trip.DestinationLng)
+	}
+	sent, err := sim.completeTrip()
+	if err != nil {
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	}
+	return &pb.DriverCompleteTripResponse{Success: sent}, nil
+}
+
+func (sim *SimulatedDriver) RateCustomer(ctx context.Context, req *pb.RatingRequest) (*pb.RatingResponse, error) {
+	if req.GetRating() < 0 || req.GetRating() > 5 {
+		return nil, status.Error(codes.InvalidArgument, "rating must be between 0 and 5")
+	}
+	if err := sim.manualTrip(req.GetTripId()); err != nil {
+		return nil, err
+	}
+	sent, err := sim.rateCustomer(float64(req.GetRating()))
+	if err != nil {
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	}
+	return &pb.RatingResponse{Success: sent}, nil
+}
+
+// pendingOffer checks an offer waits for an answer, and that it is for tripId unless it is empty
+func (sim *SimulatedDriver) pendingOffer(tripId string) error {
+	if !sim.manual {
+		return status.Error(codes.FailedPrecondition, "driver is not in manual mode")
+	}
+	if !sim.machine.Is(StateOffered) || (tripId != "" && sim.tripId != tripId) {
+		return status.Error(codes.FailedPrecondition, "no pending offer for the trip")
+	}
+	return nil
+}
+
+// manualTrip checks tripId is the current trip of the driver, the current trip is used when it is empty.
+// An idle driver leaves for the pickup of a scheduled trip it accepted when told to act on it.
+func (sim *SimulatedDriver) manualTrip(tripId string) error {
+	if !sim.manual {
+		return status.Error(codes.FailedPrecondition, "driver is not in manual mode")
+	}
+	if tripId != "" && tripId != sim.tripId {
+		offer, ok := sim.scheduledTrips[tripId]
+		if !ok {
+			return status.Errorf(codes.FailedPrecondition, "trip %s is not the current trip", tripId)
+		}
+		if err := sim.machine.Transition(StateEnRoute); err != nil {
+			return status.Error(codes.FailedPrecondition, err.Error())
+		}
+		delete(sim.scheduledTrips, tripId)
+		sim.tripOffer = offer
+		sim.tripId = tripId
+	}
+	if sim.tripId == "" {
+		return status.Error(codes.FailedPrecondition, "driver has no current trip")
+	}
+	return nil
+}
+
+// notIn is the error of a manual step taken outside the state it needs
+func (sim *SimulatedDriver) notIn(state fsm.State) error {
+	current, _ := sim.machine.Current()
+	return status.Errorf(codes.FailedPrecondition, "driver is %s, not %s", current, state)
+}
@@ -0,0 +1,57 @@
+package drivers
+
+import (
+	"testing"
+	"time"
+
+	"sim-server/config"
+	"sim-server/internal/models"
+	"sim-server/internal/simulation/actors"
+	"sim-server/internal/simulation/clock"
+)
+
+func TestModifiedScheduledPickup(t *testing.T) {
+	// an hour of the scenario passes in a tenth of a second
+	const scenarioId = "modified-pickup"
+	scenarioClock := clock.New(36000)
+	clock.Register(scenarioId, scenarioClock)
+
+	driverId := "modified-pickup-driver"
+	NewSimulatedDriver(models.Driver{Id: driverId}, config.Target{}, scenarioId, 28.6139, 77.2090, 1, false)
+	defer func() {
+		if actor, ok := actors.Lookup(driverId); ok {
+			actor.Stop()
+		}
+	}()
+
+	now := scenarioClock.Now()
+	booked, moved := now.Add(time.Hour), now.Add(3*time.Hour)
+	offer := &models.NewTripOfferMessage{TripOffer: models.TripOffer{
+		TripId: "trip",
+		Trip:   models.Trip{Id: "trip", ScheduledAt: booked.Unix()},
+	}}
+	err := call(driverId, func(sim *SimulatedDriver) {
+		sim.machine.Reset(StateIdle)
+		sim.scheduledTrips["trip"] = offer
+		sim.self.Go(func() { sim.awaitScheduledPickup("trip", booked) })
+		modification := models.ConfirmTripMessage{Trip: offer.TripOffer.Trip}
+		modification.ScheduledAt = moved.Unix()
+		sim.handleTripModification(&modification)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the booked pickup time passes, the driver waits for the one it was moved to
+	time.Sleep(scenarioClock.Wall(2 * time.Hour))
+	state, err := Snapshot(driverId)
+	if err != nil || state.TripId != "" || state.ScheduledTrips != 1 {
+		t.Fatalf("left for trip %q with %d scheduled trips before the moved pickup: %v", state.TripId, state.ScheduledTrips, err)
+	}
+
+	time.Sleep(scenarioClock.Wall(scenarioClock.Until(moved) + time.Hour))
+	state, err = Snapshot(driverId)
+	if err != nil || state.TripId != "trip" || state.ScheduledTrips != 0 {
+		t.Fatalf("on trip %q with %d scheduled trips after the moved pickup: %v", state.TripId, state.ScheduledTrips, err)
+	}
+}
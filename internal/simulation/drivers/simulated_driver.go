@@ -2,114 +2,181 @@ package drivers
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"log"
 	"net"
-	"net/http"
-	"net/url"
+	"sim-server/config"
 	"sim-server/internal/services"
 	"sync"
 	"time"
 
-	"github.com/gorilla/websocket"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
 	"googlemaps.github.io/maps"
 	pb "sim-server/internal/genserver/proto"
+	"sim-server/internal/simulation/actors"
+	"sim-server/internal/simulation/clock"
+	"sim-server/internal/simulation/fsm"
+	"sim-server/internal/simulation/recorder"
+	"sim-server/internal/simulation/socket"
 
 	"sim-server/internal/models"
 )
 
-const (
-	scheme                  = "wss"
-	host                    = "rh-core.advantium.in"
-	sleepBeforeArrival      = 10 * time.Second
-	sleepPingLocation       = 50 * time.Second
-	sleepBeforeStartTrip    = 5 * time.Second
-	sleepBeforeCompleteTrip = 5 * time.Second
-	sleepForTripPing        = 2 * time.Second
-)
+const actorKind = "driver"
+
+// Script is the timing of a scripted driver, in durations of the clock of the scenario, see clock.Clock.
+// The discrete-event engine drives its drivers with it as well.
+type Script struct {
+	BeforeArrival      time.Duration // from accepting a trip to leaving for the pickup
+	PingLocation       time.Duration // between two location pings
+	BeforeStartTrip    time.Duration
+	BeforeCompleteTrip time.Duration
+	TripPing           time.Duration // between two points of a route
+}
+
+// defaultScript is followed by every driver, see DefaultScript
+var defaultScript = Script{
+	BeforeArrival:      10 * time.Second,
+	PingLocation:       50 * time.Second,
+	BeforeStartTrip:    5 * time.Second,
+	BeforeCompleteTrip: 5 * time.Second,
+	TripPing:           2 * time.Second,
+}
+
+// DefaultScript returns the timing every driver follows. A scenario speeds it up through its clock.
+func DefaultScript() Script {
+	return defaultScript
+}
+
+// syncTimeout waits for the backend, in wall time
+const syncTimeout = 10 * time.Second
 
+// SimulatedDriver is owned by the mailbox of its actor: the websocket callbacks, the trip goroutines
+// and the gRPC listener hand their work to the mailbox, so the state is only touched from one goroutine.
+// Use Snapshot to read it from outside.
 type SimulatedDriver struct {
 	pb.UnimplementedSimulatedDriverServer
 	driver         models.Driver
+	target         config.Target
+	scenarioId     string
+	session        *services.Session
+	clock          *clock.Clock // of the scenario, the driver sleeps and timestamps through it
 	lat            float64
 	lng            float64
-	conn           *websocket.Conn
-	tripOfferData  map[string]interface{}
+	conn           *socket.Client
+	connLock       sync.Mutex   // guards writes to conn, Terminate closes it from outside the mailbox
+	machine        *fsm.Machine // where the driver is in a trip, see Lifecycle
+	tripOffer      *models.NewTripOfferMessage
 	tripId         string
 	acceptanceRate float64
-	writeLock      sync.Mutex
+	manual         bool // the driver only acts on trips when told to through the manual control RPCs
+	offline        bool // forced offline, the websocket stays closed until the driver is told to go online
+	draining       bool // the process shuts down, the driver finishes its trip and takes no new one, see Drain
+	recovering     bool // restored from a checkpoint, the first sync resumes the trip in progress, see Restore
+	started        bool // InitConnection ran, from then on the driver needs its websocket unless forced offline
+	scheduledTrips map[string]*models.NewTripOfferMessage
+	syncLock       sync.Mutex
+	synced         chan struct{} // closed once the backend answered the last sync
+	self           *actors.Actor // the mailbox owning the driver, it also runs its goroutines so a panic crashes the actor rather than the process
 }
 
 // Client Methods
 
-func NewSimulatedDriver(driver models.Driver, lat, lng, acceptanceRate float64) {
+func NewSimulatedDriver(driver models.Driver, target config.Target, scenarioId string, lat, lng, acceptanceRate float64, manual bool) {
+	newSimulatedDriver(driver, target, scenarioId, lat, lng, acceptanceRate, manual)
+}
 
+// newSimulatedDriver reports whether it started the driver, false when it was already running
+func newSimulatedDriver(driver models.Driver, target config.Target, scenarioId string, lat, lng, acceptanceRate float64, manual bool) bool {
 	sim := &SimulatedDriver{
 		driver:         driver,
+		target:         target,
+		scenarioId:     scenarioId,
+		session:        services.NewSession(target, driver.PhoneNumber, driver.AccessToken, services.DriverAuth(target)),
+		clock:          clock.For(scenarioId),
 		lat:            lat,
 		lng:            lng,
 		acceptanceRate: acceptanceRate,
+		manual:         manual,
+		machine:        fsm.New("driver "+driver.Id, StateOffline, Lifecycle),
+		scheduledTrips: make(map[string]*models.NewTripOfferMessage),
+		synced:         make(chan struct{}),
 	}
+	close(sim.synced)
 
-	sim.serve(driver.Id)
+	return sim.serve(driver.Id)
 }
 
-func CheckAndGoOnline(driverId string) {
-	client, conn, err := client(driverId)
+// CheckAndGoOnline starts a shift for the driver unless it is on one already
+func CheckAndGoOnline(driverId string) error {
+	var err error
+	callErr := call(driverId, func(sim *SimulatedDriver) {
+		var response *pb.GoOnlineResponse
+		if response, err = sim.GoOnline(context.Background(), &pb.GoOnlineRequest{}); err == nil && !response.GetSuccess() {
+			err = errors.New("no shift was started")
+		}
+	})
+	if callErr != nil {
+		return callErr
+	}
 	if err != nil {
-		log.Printf("Error connecting to driver: %v", err)
-		return
+		return fmt.Errorf("going online: %w", err)
 	}
-	defer conn.Close()
-	client.GoOnline(context.Background(), &pb.GoOnlineRequest{})
+	return nil
 }
 
-func Connect(driverId string) {
-	client, conn, err := client(driverId)
+// Connect opens the websocket of the driver
+func Connect(driverId string) error {
+	connected := false
+	err := call(driverId, func(sim *SimulatedDriver) {
+		response, _ := sim.InitConnection(context.Background(), &pb.InitConnectionRequest{})
+		connected = response.GetSuccess()
+	})
 	if err != nil {
-		log.Printf("Error connecting to driver: %v", err)
-		return
+		return err
 	}
-	defer conn.Close()
-	client.InitConnection(context.Background(), &pb.InitConnectionRequest{})
+	if !connected {
+		return errors.New("the websocket of the driver did not connect")
+	}
+	return nil
 }
 
 func UpdateLocation(driverId string, lat, lng float64) (err error) {
-	client, conn, err := client(driverId)
-	if err != nil {
-		log.Printf("Error connecting to driver: %v", err)
-		return
-	}
-	defer conn.Close()
-	_, err = client.SetLocation(context.Background(), &pb.SetLocationRequest{Lat: lat, Lng: lng})
-	if err != nil {
-		return err
+	callErr := call(driverId, func(sim *SimulatedDriver) {
+		_, err = sim.SetLocation(context.Background(), &pb.SetLocationRequest{Lat: lat, Lng: lng})
+	})
+	if callErr != nil {
+		log.Printf("Error connecting to driver: %v", callErr)
+		return callErr
 	}
-	return nil
+	return err
 }
 
 // Server Methods
 
 func (sim *SimulatedDriver) GoOnline(ctx context.Context, req *pb.GoOnlineRequest) (*pb.GoOnlineResponse, error) {
-	// Get token
-	token := sim.driver.AccessToken
-
-	commonResponse, err := services.CheckShiftStatus(token)
+	commonResponse, err := sim.session.Call(ctx, func(token string) (*models.CommonResponse, error) {
+		return services.CheckShiftStatus(ctx, sim.target, token)
+	})
 	if err != nil {
 		return &pb.GoOnlineResponse{Success: false}, err
 	}
-	if commonResponse.Data.(map[string]interface{})["has_active_shift"] == true {
+	shift, _ := commonResponse.Data.(map[string]interface{})
+	if shift["has_active_shift"] == true {
 		return &pb.GoOnlineResponse{Success: true}, nil
 	}
-	commonResponse, err = services.StartNewShift(token)
+	commonResponse, err = sim.session.Call(ctx, func(token string) (*models.CommonResponse, error) {
+		return services.StartNewShift(ctx, sim.target, token)
+	})
 	if err != nil {
 		return &pb.GoOnlineResponse{Success: false}, err
 	}
-	if commonResponse.Data.(map[string]interface{})["new_shift_started"] == true {
+	shift, _ = commonResponse.Data.(map[string]interface{})
+	if shift["new_shift_started"] == true {
 		return &pb.GoOnlineResponse{Success: true}, nil
 	} else {
 		return &pb.GoOnlineResponse{Success: false}, nil
@@ -117,26 +184,37 @@ func (sim *SimulatedDriver) GoOnline(ctx context.Context, req *pb.GoOnlineReques
 }
 
 func (sim *SimulatedDriver) InitConnection(ctx context.Context, req *pb.InitConnectionRequest) (*pb.InitConnectionResponse, error) {
-	// Get token
-	token := sim.driver.AccessToken
-
-	address := url.URL{Scheme: scheme, Host: host, Path: "/ws/driver"}
-	conn, _, err := websocket.DefaultDialer.Dial(address.String(), http.Header{
-		"Authorization": []string{"Bearer " + token},
-		"MRSOOL-CLIENT": []string{"Simulation"},
-	})
+	sim.started = true
+	if sim.conn != nil && !sim.conn.Closed() {
+		return &pb.InitConnectionResponse{Success: true}, nil // reused actor, already connected
+	}
+	conn, err := socket.DialTarget(sim.actor(), sim.target, "/ws/driver", sim.session, sim.handleMessage, sim.resync)
 	if err != nil {
 		log.Printf("Error connecting to websocket: %v", err)
 		return &pb.InitConnectionResponse{Success: false}, nil
 	}
-	sim.conn = conn
+	sim.setConn(conn)
 	log.Print("web socket connected")
+	if sim.machine.Is(StateOffline) {
+		sim.machine.Transition(StateIdle)
+	}
+	if sim.recovering {
+		sim.resync()
+	}
 
-	go sim.blockingSubscribe(conn)
-	go sim.pingDriverLocationLoop() //pinging location to websocket once the connection gets established
+	sim.self.Go(func() { sim.pingDriverLocationLoop(conn) }) //pinging location to websocket once the connection gets established
 	return &pb.InitConnectionResponse{Success: true}, nil
 }
 
+// IsAlive fails once the driver started without a websocket or its websocket was closed for good,
+// the actor can no longer take part in trips. A driver forced offline is alive without one.
+func (sim *SimulatedDriver) IsAlive(ctx context.Context, req *pb.IsAliveRequest) (*pb.IsAliveResponse, error) {
+	if sim.started && !sim.offline && (sim.conn == nil || sim.conn.Closed()) {
+		return nil, status.Error(codes.Unavailable, "websocket closed")
+	}
+	return &pb.IsAliveResponse{}, nil
+}
+
 func (sim *SimulatedDriver) SetLocation(ctx context.Context, req *pb.SetLocationRequest) (*pb.SetLocationResponse, error) {
 	sim.lat = req.GetLat()
 	sim.lng = req.GetLng()
@@ -150,66 +228,108 @@ func (sim *SimulatedDriver) SetLocation(ctx context.Context, req *pb.SetLocation
 			},
 		},
 	}
-	jsonPayload, _ := json.Marshal(payload)
-	message, _ := json.Marshal(models.IncomingMessage{
-		Command: models.DriverLocation,
-		Payload: jsonPayload,
-	})
-	if !sim.sendMessageToClient(message) {
+	if !sim.sendMessageToClient(models.DriverLocation, payload) {
 		return &pb.SetLocationResponse{Success: false}, nil
 	}
 	return &pb.SetLocationResponse{Success: true}, nil
 }
 
-func (sim *SimulatedDriver) serve(driverId string) {
-	//if checkIfAlreadyServed(driverId) {
-	//	return
-	//}
+// serve reports whether it spawned the actor of the driver, false when a live one already serves it
+func (sim *SimulatedDriver) serve(driverId string) bool {
+	if checkIfAlreadyServed(driverId) {
+		log.Printf("driver %s is already running, reusing it", driverId)
+		return false
+	}
+
+	actor := actors.Spawn(actorKind, driverId, sim)
+	sim.self = actor
+	if !actors.ListenersEnabled() {
+		return true
+	}
 
-	// Register and start a gRPC server for the trip
-	lis, err := registerService(driverId)
+	// Register and start a gRPC server for tools outside the process
+	lis, err := registerService(actor)
 	if err != nil {
-		return
+		return true
 	}
 
 	go sim.grpcLoop(lis)
+	return true
 }
 
 func (sim *SimulatedDriver) grpcLoop(lis net.Listener) {
 	defer lis.Close()
 
-	s := grpc.NewServer()
+	s := grpc.NewServer(grpc.UnaryInterceptor(sim.self.UnaryInterceptor()))
 	pb.RegisterSimulatedDriverServer(s, sim) // Start with initial state
 
+	// the listener goes with the actor, a restarted one opens its own
+	go func() {
+		<-sim.self.Done()
+		s.Stop()
+	}()
 	if err := s.Serve(lis); err != nil {
 		log.Printf("Failed to serve: %v", err)
 	}
 }
 
+// checkIfAlreadyServed reports whether a live actor already simulates the driver, either in this
+// process or in the one that advertised it in the registry. Stale registry entries are removed.
 func checkIfAlreadyServed(driverId string) bool {
-	value, exists := services.CheckAndGetKey(driverId)
+	if actor, ok := actors.Lookup(driverId); ok {
+		return actor.Ping(context.Background()) == nil
+	}
 
+	address, exists := services.CheckAndGetKey(driverId)
 	if !exists {
 		return false
 	}
-	address := value
-
 	conn, err := grpc.NewClient(address, grpc.WithTransportCredentials(insecure.NewCredentials()))
 	if err != nil {
 		log.Printf("Failed to create new client for %s: %v", driverId, err)
+		return false
 	}
 	defer conn.Close()
 
+	ctx, cancel := context.WithTimeout(context.Background(), actors.PingTimeout)
+	defer cancel()
+	if _, err := pb.NewSimulatedDriverClient(conn).IsAlive(ctx, &pb.IsAliveRequest{}); err != nil {
+		log.Printf("Removing stale registry entry of %s at %s: %v", driverId, address, err)
+		services.Delete(driverId)
+		return false
+	}
+	log.Printf("%s is served by another process at %s", driverId, address)
 	return true
+}
 
+// Terminate closes the websocket once the actor stopped, a restarted driver opens a new one. It runs
+// outside the mailbox, possibly while a message still does, so it leaves the state machine alone:
+// a restarted driver starts from a new one.
+func (sim *SimulatedDriver) Terminate(reason error) {
+	sim.connLock.Lock()
+	defer sim.connLock.Unlock()
+	if sim.conn != nil {
+		sim.conn.Close()
+	}
+}
+
+func (sim *SimulatedDriver) setConn(conn *socket.Client) {
+	sim.connLock.Lock()
+	sim.conn = conn
+	sim.connLock.Unlock()
 }
 
 // Utility Methods
 
-func (sim *SimulatedDriver) sendMessageToClient(bytes []byte) bool {
-	sim.writeLock.Lock() // Acquire lock before writing
-	defer sim.writeLock.Unlock()
-	if err := sim.conn.WriteMessage(websocket.TextMessage, bytes); err != nil {
+func (sim *SimulatedDriver) actor() recorder.Actor {
+	return recorder.Actor{Role: "driver", Id: sim.driver.Id, ScenarioId: sim.scenarioId}
+}
+
+func (sim *SimulatedDriver) sendMessageToClient(command models.Command, payload interface{}) bool {
+	if sim.conn == nil {
+		return false
+	}
+	if err := sim.conn.SendCommand(command, payload); err != nil {
 		log.Println("Write error:", err)
 		return false
 	}
@@ -217,7 +337,7 @@ func (sim *SimulatedDriver) sendMessageToClient(bytes []byte) bool {
 	return true
 }
 
-func registerService(driverId string) (lis net.Listener, err error) {
+func registerService(actor *actors.Actor) (lis net.Listener, err error) {
 	lis, err = net.Listen("tcp", ":0") // Let the OS provide the port number
 	if err != nil {
 		log.Printf("Failed to listen: %v", err)
@@ -225,61 +345,118 @@ func registerService(driverId string) (lis net.Listener, err error) {
 	}
 
 	addr := lis.Addr().(*net.TCPAddr)
-	addrString := fmt.Sprintf("%s:%d", "localhost", addr.Port)
+	addrString := fmt.Sprintf("%s:%d", actors.AdvertiseHost(), addr.Port)
 
-	err = services.Set(driverId, []byte(addrString))
+	err = actor.Advertise(addrString)
 	if err != nil {
 		log.Printf("Failed to add server to registry: %v", err)
+		lis.Close()
 		return
 	}
 	return
 }
 
-func client(driverId string) (pb.SimulatedDriverClient, *grpc.ClientConn, error) {
-	value, exists := services.CheckAndGetKey(driverId)
-
-	if !exists {
-		return nil, nil, errors.New("server not found for driver " + driverId)
+// call runs fn in the mailbox of the driver and waits for it
+func call(driverId string, fn func(sim *SimulatedDriver)) error {
+	actor, ok := actors.Lookup(driverId)
+	if !ok {
+		return fmt.Errorf("driver %s: %w", driverId, actors.ErrNotRunning)
+	}
+	sim, ok := actor.Server.(*SimulatedDriver)
+	if !ok {
+		return errors.New(driverId + " is not a driver")
 	}
+	return actor.Call(context.Background(), func() { fn(sim) })
+}
 
-	// Connect to GenServer instance
-	genServerConn, err := grpc.NewClient(value, grpc.WithTransportCredentials(insecure.NewCredentials()))
+// handleMessage is called by the websocket read loop for every incoming message, which waits for
+// the mailbox to handle it so messages keep their order
+func (sim *SimulatedDriver) handleMessage(message []byte) {
+	defer sim.self.Recover()
+	serverMessage, payload, err := models.DecodeServerMessage(message)
 	if err != nil {
-		log.Printf("Failed to connect to GenServer: %v", err)
-		return nil, nil, err
+		log.Printf("driver decode: %v", err)
+		return
+	}
+	if err := sim.self.Call(context.Background(), func() { sim.dispatch(serverMessage.Command, payload) }); err != nil {
+		log.Printf("driver %s: dropping %s: %v", sim.driver.Id, serverMessage.Command, err)
 	}
+}
 
-	return pb.NewSimulatedDriverClient(genServerConn), genServerConn, err
+func (sim *SimulatedDriver) dispatch(command models.Command, payload interface{}) {
+	switch command {
+	case models.NewTripOffer:
+		sim.handleNewTripOffer(payload.(*models.NewTripOfferMessage))
+	case models.Eta:
+		sim.handleEtaPayload(payload.(*models.EtaMessage))
+	case models.CompleteTrip:
+		sim.handleTripCompletion(payload.(*models.TripStatusMessage))
+	case models.CancelTrip:
+		sim.handleTripCancellation(payload.(*models.TripStatusMessage))
+	case models.ModifyTrip:
+		sim.handleTripModification(payload.(*models.ConfirmTripMessage))
+	case models.Sync:
+		sim.handleSync(payload.(*models.SyncMessage))
+	}
 }
 
-func (sim *SimulatedDriver) blockingSubscribe(conn *websocket.Conn) {
-	// Loop over incoming websocket messages
-	defer conn.Close()
-	for {
-		_, message, err := conn.ReadMessage()
-		if err != nil {
-			log.Println("driver read:", err)
-			return
-		}
-		log.Printf("driver recv: %s", message)
-		var payload map[string]interface{}
-		json.Unmarshal(message, &payload)
-		switch payload["command"] {
-		case string(models.NewTripOffer):
-			sim.handleNewTripOffer(payload)
-		case string(models.Eta):
-			sim.handleEtaPayload(payload)
-		case string(models.CompleteTrip):
-			sim.handleTripCompletion(payload)
+// resync asks the backend for the driver state after the websocket was re-established,
+// location pings are held back until it answers
+func (sim *SimulatedDriver) resync() {
+	sim.syncLock.Lock()
+	sim.synced = make(chan struct{})
+	sim.syncLock.Unlock()
+
+	sim.self.Cast(func() { sim.sendMessageToClient(models.Sync, struct{}{}) })
+}
+
+func (sim *SimulatedDriver) handleSync(payload *models.SyncMessage) {
+	if payload.ActiveTrip != nil {
+		sim.tripId = payload.ActiveTrip.Id
+		if state, ok := tripStates[payload.ActiveTrip.Status]; ok {
+			sim.machine.Reset(state)
 		}
+	} else if sim.machine.Is(StateEnRoute, StateArrived, StateOnTrip) {
+		log.Printf("driver %s: trip %s is no longer active", sim.driver.Id, sim.tripId)
+		sim.machine.Reset(StateIdle)
+		sim.tripOffer = nil
+		sim.tripId = ""
+	}
+
+	sim.syncLock.Lock()
+	select {
+	case <-sim.synced:
+	default:
+		close(sim.synced)
+	}
+	sim.syncLock.Unlock()
+
+	if sim.recovering {
+		sim.recovering = false
+		sim.resume(payload.ActiveTrip)
 	}
 }
 
-// pinging location to websocket once the connection gets established
-func (sim *SimulatedDriver) pingDriverLocationLoop() {
-	for {
-		sim.pingDriverLocation()
-		time.Sleep(sleepPingLocation)
+// awaitSync blocks while a sync is pending, giving up after syncTimeout
+func (sim *SimulatedDriver) awaitSync() {
+	sim.syncLock.Lock()
+	synced := sim.synced
+	sim.syncLock.Unlock()
+
+	select {
+	case <-synced:
+	case <-time.After(syncTimeout):
+	}
+}
+
+// pinging location to websocket once the connection gets established, until it is closed
+func (sim *SimulatedDriver) pingDriverLocationLoop(conn *socket.Client) {
+	for !conn.Closed() {
+		sim.awaitSync()
+		if err := sim.self.Call(context.Background(), sim.pingDriverLocation); err != nil {
+			return
+		}
+		sim.clock.Sleep(defaultScript.PingLocation)
 	}
 }
 
@@ -294,155 +471,304 @@ func (sim *SimulatedDriver) pingDriverLocation() {
 		},
 		VehicleCategoryId: 2,
 	}
-	jsonPayload, _ := json.Marshal(locationPayload)
-	message, _ := json.Marshal(models.IncomingMessage{
-		Command: models.DriverLocation,
-		Payload: jsonPayload,
-	})
-
-	sim.sendMessageToClient(message)
+	sim.sendMessageToClient(models.DriverLocation, locationPayload)
 }
 
-func (sim *SimulatedDriver) handleNewTripOffer(payload map[string]interface{}) {
-	sim.tripOfferData = payload
-	//tripId := sim.tripOfferData["data"].(map[string]interface{})["trip_offer"].(map[string]interface{})["trip_id"]
-	if data, ok := payload["data"].(map[string]interface{}); ok {
-		if tripOffer, ok := data["trip_offer"].(map[string]interface{}); ok {
-			if id, ok := tripOffer["trip_id"].(string); ok {
-				fmt.Println("Parsed ID:", id)
-				sim.tripId = id
-			} else {
-				fmt.Println("ID is not a string or not present")
-			}
-		}
+// handleNewTripOffer answers an offer for a trip now only while the driver is idle, any other is rejected
+// so it cannot overwrite the trip in progress. Pre-assigned scheduled offers leave the current trip alone.
+func (sim *SimulatedDriver) handleNewTripOffer(offer *models.NewTripOfferMessage) {
+	tripId := offer.TripOffer.TripId
+	fmt.Println("Parsed ID:", tripId)
+
+	if sim.draining {
+		sim.rejectTrip(tripId)
+		return
+	}
+	if scheduledAt := sim.scheduledPickup(offer); scheduledAt > 0 && !sim.manual {
+		// pre-assigned offers for scheduled trips are always honoured
+		sim.AcceptScheduledTrip(offer, scheduledAt)
+		return
+	}
+	if err := sim.machine.Transition(StateOffered); err != nil {
+		sim.rejectTrip(tripId)
+		return
+	}
+	sim.tripOffer = offer
+	sim.tripId = tripId
+
+	if sim.manual {
+		log.Printf("driver %s: offered trip %s, waiting to be accepted or rejected", sim.driver.Id, tripId)
+		return
+	}
+	if shouldAccept(sim.acceptanceRate) {
+		sim.acceptTrip()
 	} else {
-		fmt.Println("Data is not a map or not present")
-		fmt.Printf(payload["message"].(string))
+		sim.rejectOffer()
+	}
+}
+
+// acceptTrip accepts the pending offer, outside manual mode the driver then goes through the trip on its own
+func (sim *SimulatedDriver) acceptTrip() (bool, error) {
+	if err := sim.machine.Transition(StateEnRoute); err != nil {
+		return false, err
+	}
+	tripId := sim.tripId
+	sent := sim.sendTripAction(models.AcceptTrip)
+	if sim.manual {
+		return sent, nil
 	}
+	// the trip runs outside the websocket read loop so connection failures are still noticed
+	sim.self.Go(func() {
+		sim.clock.Sleep(defaultScript.BeforeArrival)
+		sim.handleDriverArrival(tripId)
+	})
+	return sent, nil
+}
 
-	if sim.tripId != "" {
-		if shouldAccept(sim.acceptanceRate) {
-			sim.AcceptTrip(sim.tripId)
-		} else {
-			sim.RejectTrip(sim.tripId)
-		}
+// rejectOffer rejects the pending offer and makes the driver available again
+func (sim *SimulatedDriver) rejectOffer() bool {
+	if err := sim.machine.Transition(StateIdle); err != nil {
+		return false
+	}
+	tripId := sim.tripId
+	sim.tripOffer = nil
+	sim.tripId = ""
+	return sim.rejectTrip(tripId)
+}
+
+// AcceptScheduledTrip accepts a pre-assigned offer and drives to the pickup once the scheduled time comes
+func (sim *SimulatedDriver) AcceptScheduledTrip(offer *models.NewTripOfferMessage, scheduledAt int64) {
+	tripId := offer.TripOffer.TripId
+	if !sim.acceptScheduled(offer) {
+		return
 	}
+	sim.self.Go(func() { sim.awaitScheduledPickup(tripId, time.Unix(scheduledAt, 0)) })
 }
 
-func (sim *SimulatedDriver) AcceptTrip(tripId string) {
+// acceptScheduled accepts a scheduled trip, it only becomes the current trip once the driver leaves for the pickup
+func (sim *SimulatedDriver) acceptScheduled(offer *models.NewTripOfferMessage) bool {
 	payload := models.TripActionPayload{
-		TripId: tripId,
+		TripId: offer.TripOffer.TripId,
 	}
-	jsonPayload, _ := json.Marshal(payload)
-	message, _ := json.Marshal(models.IncomingMessage{
-		Command: models.AcceptTrip,
-		Payload: jsonPayload,
+	if !sim.sendMessageToClient(models.AcceptTrip, payload) {
+		return false
+	}
+	sim.scheduledTrips[offer.TripOffer.TripId] = offer
+	return true
+}
+
+func (sim *SimulatedDriver) awaitScheduledPickup(tripId string, scheduledAt time.Time) {
+	sim.clock.Sleep(sim.clock.Until(scheduledAt.Add(-defaultScript.BeforeArrival)))
+	leaving := false
+	err := sim.self.Call(context.Background(), func() {
+		offer, ok := sim.scheduledTrips[tripId]
+		if ok && offer.TripOffer.Trip.ScheduledAt != scheduledAt.Unix() {
+			// the customer moved the pickup, the timer of the new time takes it from here
+			return
+		}
+		delete(sim.scheduledTrips, tripId)
+		if !ok || sim.draining {
+			// cancelled by the customer in the meantime, or the process shuts down
+			return
+		}
+		if err := sim.machine.Transition(StateEnRoute); err != nil {
+			// still busy with another trip
+			return
+		}
+		sim.tripOffer = offer
+		sim.tripId = tripId
+		leaving = true
 	})
-	sim.sendMessageToClient(message)
-	sim.tripId = tripId
-	// from where to trigger driver arrival
-	time.Sleep(sleepBeforeArrival)
-	sim.handleDriverArrival()
+	if err != nil || !leaving {
+		return
+	}
+	sim.clock.Sleep(defaultScript.BeforeArrival)
+	sim.handleDriverArrival(tripId)
+}
+
+// handleTripModification moves the pickup of a scheduled trip to the time the customer changed it to
+func (sim *SimulatedDriver) handleTripModification(payload *models.ConfirmTripMessage) {
+	offer, ok := sim.scheduledTrips[payload.Id]
+	if !ok || offer.TripOffer.Trip.ScheduledAt == payload.ScheduledAt {
+		return
+	}
+	moved := *offer
+	moved.TripOffer.Trip.ScheduledAt = payload.ScheduledAt
+	sim.scheduledTrips[payload.Id] = &moved
+	if sim.manual {
+		return
+	}
+	tripId, scheduledAt := payload.Id, time.Unix(payload.ScheduledAt, 0)
+	sim.self.Go(func() { sim.awaitScheduledPickup(tripId, scheduledAt) })
+}
+
+func (sim *SimulatedDriver) handleTripCancellation(payload *models.TripStatusMessage) {
+	tripId := payload.TripID()
+	delete(sim.scheduledTrips, tripId)
+
+	if tripId == "" || tripId != sim.tripId {
+		return
+	}
+	if err := sim.machine.Transition(StateIdle); err != nil {
+		return
+	}
+	sim.tripOffer = nil
+	sim.tripId = ""
+}
+
+// scheduledPickup returns the scheduled pickup time of an offer in unix seconds, zero for trips now
+func (sim *SimulatedDriver) scheduledPickup(offer *models.NewTripOfferMessage) int64 {
+	scheduledAt := offer.TripOffer.Trip.ScheduledAt
+	if scheduledAt <= sim.clock.Now().Unix() {
+		return 0
+	}
+	return scheduledAt
 }
 
-func (sim *SimulatedDriver) RejectTrip(tripId string) {
+func (sim *SimulatedDriver) rejectTrip(tripId string) bool {
 	payload := models.TripActionPayload{
 		TripId: tripId,
 	}
-	jsonPayload, _ := json.Marshal(payload)
-	message, _ := json.Marshal(models.IncomingMessage{
-		Command: models.RejectTrip,
-		Payload: jsonPayload,
-	})
-	sim.sendMessageToClient(message)
+	return sim.sendMessageToClient(models.RejectTrip, payload)
 }
 
-func (sim *SimulatedDriver) handleEtaPayload(payload map[string]interface{}) {
+func (sim *SimulatedDriver) handleEtaPayload(payload *models.EtaMessage) {
 	fmt.Print("Driver getting eta payload after trip acceptance", payload)
 }
 
-func (sim *SimulatedDriver) handleDriverArrival() {
-	trip := sim.tripOfferData["data"].(map[string]interface{})["trip_offer"].(map[string]interface{})["trip"].(map[string]interface{})
-	pickUpPolyline := sim.tripOfferData["data"].(map[string]interface{})["pickup_estimate"].(map[string]interface{})["route"].(map[string]interface{})["polyline"].(map[string]interface{})["encodedPolyline"]
-	sim.decodeAndPingOnPolyline(pickUpPolyline.(string))
+// handleDriverArrival drives an accepted trip from the pickup to the drop off, giving up as soon as
+// it is no longer the current trip, e.g. after a cancellation. It runs outside the mailbox and hands
+// every step to it.
+func (sim *SimulatedDriver) handleDriverArrival(tripId string) {
+	offer, ok := sim.currentOffer(tripId)
+	if !ok {
+		return
+	}
+	if !sim.followPolyline(tripId, offer.PickupEstimate.Route.Polyline.EncodedPolyline) {
+		return
+	}
+	if !sim.inTrip(tripId, func() bool { _, err := sim.driverArrival(); return err == nil }) {
+		return
+	}
+	sim.handlePickup(tripId)
+}
 
-	sim.lat = trip["origin_lat"].(float64)
-	sim.lng = trip["origin_lng"].(float64)
-	sim.pingDriverLocation()
-	sim.DriverArrival()
-
-	time.Sleep(sleepBeforeStartTrip)
-	sim.StartTrip()
-	dropPolyline := sim.tripOfferData["data"].(map[string]interface{})["trip_estimate"].(map[string]interface{})["route"].(map[string]interface{})["polyline"].(map[string]interface{})["encodedPolyline"]
-	sim.decodeAndPingOnPolyline(dropPolyline.(string))
-	sim.lat = trip["destination_lat"].(float64)
-	sim.lng = trip["destination_lng"].(float64)
-	sim.pingDriverLocation()
-	time.Sleep(sleepBeforeCompleteTrip)
-	sim.CompleteTrip()
+// handlePickup starts the trip once the customer is on board and drives it to the drop off
+func (sim *SimulatedDriver) handlePickup(tripId string) {
+	sim.clock.Sleep(defaultScript.BeforeStartTrip)
+	if !sim.inTrip(tripId, func() bool { _, err := sim.startTrip(); return err == nil }) {
+		return
+	}
+	sim.handleRide(tripId)
 }
 
-func (sim *SimulatedDriver) decodeAndPingOnPolyline(polyline string) {
+// handleRide drives a started trip to the drop off and completes it
+func (sim *SimulatedDriver) handleRide(tripId string) {
+	offer, ok := sim.currentOffer(tripId)
+	if !ok {
+		return
+	}
+	trip := offer.TripOffer.Trip
+	if !sim.followPolyline(tripId, offer.TripEstimate.Route.Polyline.EncodedPolyline) {
+		return
+	}
+	if !sim.inTrip(tripId, func() bool { sim.moveTo(trip.DestinationLat, trip.DestinationLng); return true }) {
+		return
+	}
+	sim.clock.Sleep(defaultScript.BeforeCompleteTrip)
+	sim.inTrip(tripId, func() bool { _, err := sim.completeTrip(); return err == nil })
+}
+
+// currentOffer returns the offer of tripId as long as it is the current trip
+func (sim *SimulatedDriver) currentOffer(tripId string) (offer *models.NewTripOfferMessage, ok bool) {
+	if !sim.inTrip(tripId, func() bool { offer = sim.tripOffer; return offer != nil }) {
+		log.Printf("driver %s: trip %s is no longer the current trip", sim.driver.Id, tripId)
+		return nil, false
+	}
+	return offer, true
+}
+
+// followPolyline moves the driver along a route, pinging every point of it
+func (sim *SimulatedDriver) followPolyline(tripId, polyline string) bool {
 	coordinates, _ := maps.DecodePolyline(polyline)
 	for _, coordinate := range coordinates {
-		sim.lat = coordinate.Lat
-		sim.lng = coordinate.Lng
-		sim.pingDriverLocation()
-		time.Sleep(sleepForTripPing)
+		if !sim.inTrip(tripId, func() bool { sim.moveTo(coordinate.Lat, coordinate.Lng); return true }) {
+			return false
+		}
+		sim.clock.Sleep(defaultScript.TripPing)
 	}
+	return true
 }
 
-func (sim *SimulatedDriver) DriverArrival() {
-	payload := models.TripActionPayload{
-		TripId: sim.tripId,
-	}
-	jsonPayload, _ := json.Marshal(payload)
-	message, _ := json.Marshal(models.IncomingMessage{
-		Command: models.ArrivedForPickup,
-		Payload: jsonPayload,
+// inTrip runs step in the mailbox as long as tripId is the current trip, reporting whether it ran and succeeded
+func (sim *SimulatedDriver) inTrip(tripId string, step func() bool) bool {
+	ok := false
+	err := sim.self.Call(context.Background(), func() {
+		ok = sim.tripId == tripId && step()
 	})
-	sim.sendMessageToClient(message)
+	return err == nil && ok
 }
 
-func (sim *SimulatedDriver) StartTrip() {
-	payload := models.TripActionPayload{
-		TripId: sim.tripId,
+func (sim *SimulatedDriver) moveTo(lat, lng float64) {
+	sim.lat = lat
+	sim.lng = lng
+	sim.pingDriverLocation()
+}
+
+// driverArrival moves the driver to the pickup point of the current trip and reports the arrival
+func (sim *SimulatedDriver) driverArrival() (bool, error) {
+	if err := sim.machine.Transition(StateArrived); err != nil {
+		return false, err
 	}
-	jsonPayload, _ := json.Marshal(payload)
-	message, _ := json.Marshal(models.IncomingMessage{
-		Command: models.StartTrip,
-		Payload: jsonPayload,
-	})
-	sim.sendMessageToClient(message)
+	if sim.tripOffer != nil && sim.tripOffer.TripOffer.TripId == sim.tripId {
+		sim.moveTo(sim.tripOffer.TripOffer.Trip.OriginLat, sim.tripOffer.TripOffer.Trip.OriginLng)
+	}
+	return sim.sendTripAction(models.ArrivedForPickup), nil
 }
 
-func (sim *SimulatedDriver) CompleteTrip() {
+func (sim *SimulatedDriver) startTrip() (bool, error) {
+	if err := sim.machine.Transition(StateOnTrip); err != nil {
+		return false, err
+	}
+	return sim.sendTripAction(models.StartTrip), nil
+}
+
+func (sim *SimulatedDriver) completeTrip() (bool, error) {
+	if err := sim.machine.Transition(StateRating); err != nil {
+		return false, err
+	}
+	return sim.sendTripAction(models.CompleteTrip), nil
+}
+
+func (sim *SimulatedDriver) sendTripAction(command models.Command) bool {
 	payload := models.TripActionPayload{
 		TripId: sim.tripId,
 	}
-	jsonPayload, _ := json.Marshal(payload)
-	message, _ := json.Marshal(models.IncomingMessage{
-		Command: models.CompleteTrip,
-		Payload: jsonPayload,
-	})
-	sim.sendMessageToClient(message)
+	return sim.sendMessageToClient(command, payload)
 }
 
-func (sim *SimulatedDriver) handleTripCompletion(_ map[string]interface{}) {
-	sim.RateCustomer()
+func (sim *SimulatedDriver) handleTripCompletion(_ *models.TripStatusMessage) {
+	if sim.manual {
+		return
+	}
+	sim.rateCustomer(models.FloatBetweenZeroToOne() * 5)
 }
 
-func (sim *SimulatedDriver) RateCustomer() {
+// rateCustomer rates the customer of the completed trip, which makes the driver available again
+func (sim *SimulatedDriver) rateCustomer(rating float64) (bool, error) {
+	if !sim.machine.Is(StateRating) {
+		return false, fmt.Errorf("driver %s has no completed trip to rate", sim.driver.Id)
+	}
 	payload := models.TripRatingPayload{
 		TripId: sim.tripId,
-		Rating: models.FloatBetweenZeroToOne() * 5,
+		Rating: rating,
 	}
-	jsonPayload, _ := json.Marshal(payload)
-	message, _ := json.Marshal(models.IncomingMessage{
-		Command: models.RateCustomer,
-		Payload: jsonPayload,
-	})
-	sim.sendMessageToClient(message)
+	if err := sim.machine.Transition(StateIdle); err != nil {
+		return false, err
+	}
+	sim.tripOffer = nil
+	sim.tripId = ""
+	return sim.sendMessageToClient(models.RateCustomer, payload), nil
 }
 
 func shouldAccept(probability float64) bool {
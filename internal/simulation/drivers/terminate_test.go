@@ -0,0 +1,44 @@
+package drivers
+
+import (
+	"testing"
+
+	"sim-server/config"
+	"sim-server/internal/models"
+	"sim-server/internal/simulation/actors"
+)
+
+// TestStopWhileTransitioning stops a driver while its mailbox moves the state machine, the race
+// detector catches Terminate touching the machine from outside the mailbox
+func TestStopWhileTransitioning(t *testing.T) {
+	driverId := "stop-while-transitioning"
+	NewSimulatedDriver(models.Driver{Id: driverId}, config.Target{}, "", 28.6139, 77.2090, 1, false)
+	actor, ok := actors.Lookup(driverId)
+	if !ok {
+		t.Fatal("driver did not start")
+	}
+
+	running, release, done := make(chan struct{}), make(chan struct{}), make(chan struct{})
+	err := actor.Cast(func() {
+		defer close(done)
+		sim := actor.Server.(*SimulatedDriver)
+		close(running)
+		for {
+			select {
+			case <-release:
+				return
+			default:
+				sim.machine.Transition(StateIdle)
+				sim.machine.Transition(StateOffline)
+			}
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	<-running
+	actor.Stop()
+	close(release)
+	<-done
+}
@@ -0,0 +1,64 @@
+package fsm
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"time"
+)
+
+var ErrIllegalTransition = errors.New("illegal transition")
+
+// State is one state of an actor, such as "idle" or "on_trip"
+type State string
+
+// Table lists the states each state may move to.
+type Table map[State][]State
+
+// Machine tracks the state of one actor. It is not safe for concurrent use: it belongs to the
+// mailbox of its actor, which runs every transition and read, see actors.Actor.
+type Machine struct {
+	name    string // used in logs, e.g. "driver 42"
+	table   Table
+	current State
+	since   time.Time
+}
+
+func New(name string, initial State, table Table) *Machine {
+	return &Machine{name: name, table: table, current: initial, since: time.Now()}
+}
+
+// Current returns the current state and since when the machine is in it.
+func (m *Machine) Current() (State, time.Time) {
+	return m.current, m.since
+}
+
+// Is reports whether the machine is in one of states.
+func (m *Machine) Is(states ...State) bool {
+	return slices.Contains(states, m.current)
+}
+
+// Transition moves the machine to `to` if the table allows it from the current state. Illegal
+// transitions are logged and leave the state unchanged, staying in a state is only allowed when
+// the table lists it.
+func (m *Machine) Transition(to State) error {
+	if !slices.Contains(m.table[m.current], to) {
+		err := fmt.Errorf("%w from %s to %s", ErrIllegalTransition, m.current, to)
+		log.Printf("%s: %v", m.name, err)
+		return err
+	}
+	m.current = to
+	m.since = time.Now()
+	return nil
+}
+
+// Reset moves the machine to `to` regardless of the table, for when the backend tells the actor
+// its actual state, e.g. after a reconnection.
+func (m *Machine) Reset(to State) {
+	if to != m.current {
+		log.Printf("%s: reset from %s to %s", m.name, m.current, to)
+		m.current = to
+		m.since = time.Now()
+	}
+}
@@ -0,0 +1,75 @@
+package fsm
+
+import (
+	"errors"
+	"testing"
+)
+
+const (
+	idle    State = "idle"
+	offered State = "offered"
+	onTrip  State = "on_trip"
+	polling State = "polling"
+)
+
+var table = Table{
+	idle:    {offered, polling},
+	offered: {onTrip, idle},
+	onTrip:  {idle},
+	polling: {polling, idle},
+}
+
+func TestTransition(t *testing.T) {
+	tests := []struct {
+		name    string
+		from    State
+		to      State
+		allowed bool
+	}{
+		{name: "listed", from: idle, to: offered, allowed: true},
+		{name: "back to the start", from: onTrip, to: idle, allowed: true},
+		{name: "skipping a state", from: idle, to: onTrip},
+		{name: "staying when listed", from: polling, to: polling, allowed: true},
+		{name: "staying when not listed", from: offered, to: offered},
+		{name: "unknown target", from: idle, to: "flying"},
+		{name: "from a state missing in the table", from: "flying", to: idle},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := New("test", tt.from, table)
+			_, before := m.Current()
+
+			err := m.Transition(tt.to)
+
+			state, since := m.Current()
+			switch {
+			case tt.allowed && err != nil:
+				t.Fatalf("transition from %s to %s: %v", tt.from, tt.to, err)
+			case tt.allowed && (state != tt.to || since.Before(before)):
+				t.Errorf("moved to %s since %v, want %s", state, since, tt.to)
+			case !tt.allowed && !errors.Is(err, ErrIllegalTransition):
+				t.Fatalf("transition from %s to %s returned %v, want ErrIllegalTransition", tt.from, tt.to, err)
+			case !tt.allowed && (state != tt.from || since != before):
+				t.Errorf("illegal transition moved the machine to %s", state)
+			}
+		})
+	}
+}
+
+func TestReset(t *testing.T) {
+	m := New("test", idle, table)
+	_, before := m.Current()
+
+	m.Reset(idle)
+	if state, since := m.Current(); state != idle || since != before {
+		t.Errorf("resetting to the current state moved the machine to %s since %v", state, since)
+	}
+	// the backend knows better than the table
+	m.Reset(onTrip)
+	if !m.Is(onTrip) {
+		t.Errorf("reset did not move the machine to %s", onTrip)
+	}
+	if !m.Is(idle, onTrip) || m.Is(idle, offered) {
+		t.Error("Is does not match any of its states")
+	}
+}
@@ -0,0 +1,374 @@
+package recorder
+
+import (
+	"bufio"
+	"bytes"
+	"container/list"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"sim-server/internal/models"
+)
+
+type Direction string
+
+const (
+	Inbound  Direction = "in"
+	Outbound Direction = "out"
+
+	fileExtension = ".ndjson"
+	// maxOpenFiles bounds the file handles kept open, the files written least recently are closed first
+	maxOpenFiles = 256
+)
+
+var ErrDisabled = errors.New("traffic recording is disabled")
+
+// Entry is one websocket frame as written to a transcript, one JSON object per line
+type Entry struct {
+	Time       time.Time       `json:"time"`
+	Direction  Direction       `json:"direction"`
+	Role       string          `json:"role"`
+	ActorId    string          `json:"actor_id"`
+	ScenarioId string          `json:"scenario_id,omitempty"`
+	TripId     string          `json:"trip_id,omitempty"`
+	Command    models.Command  `json:"command"`
+	Frame      json.RawMessage `json:"frame"`
+}
+
+// Actor identifies whose traffic a frame belongs to
+type Actor struct {
+	Role       string
+	Id         string
+	ScenarioId string
+}
+
+// Recorder appends the frames of every actor to its own NDJSON file in dir. A file is
+// rotated once it would grow past maxBytes, keeping at most maxFiles rotated files.
+// The files are only appended to, so the transcripts are read without locking the writers.
+type Recorder struct {
+	dir      string
+	maxBytes int64
+	maxFiles int
+
+	lock   sync.Mutex // guards files and recent
+	files  map[string]*actorFile
+	recent *list.List // of the files in files, the one written last first
+}
+
+// actorFile is the open file of one actor, its lock serialises the writes and rotations of the actor
+type actorFile struct {
+	actorId string
+	element *list.Element // in Recorder.recent
+
+	lock    sync.Mutex
+	file    *os.File // nil until the first write
+	size    int64
+	evicted bool // closed to make room for another actor, the next write opens the file again
+}
+
+var (
+	active     *Recorder
+	activeLock sync.RWMutex
+)
+
+// Enable starts recording the traffic of all actors into dir.
+func Enable(dir string, maxBytes int64, maxFiles int) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	activeLock.Lock()
+	defer activeLock.Unlock()
+	active = &Recorder{
+		dir:      dir,
+		maxBytes: maxBytes,
+		maxFiles: maxFiles,
+		files:    make(map[string]*actorFile),
+		recent:   list.New(),
+	}
+	log.Printf("Recording websocket traffic to %s", dir)
+	return nil
+}
+
+func current() *Recorder {
+	activeLock.RLock()
+	defer activeLock.RUnlock()
+	return active
+}
+
+// Record writes a frame sent or received by actor, it reports false when recording is disabled.
+func Record(actor Actor, direction Direction, frame []byte) bool {
+	rec := current()
+	if rec == nil {
+		return false
+	}
+	var envelope struct {
+		Command models.Command  `json:"command"`
+		Payload json.RawMessage `json:"payload"`
+		Data    json.RawMessage `json:"data"`
+	}
+	json.Unmarshal(frame, &envelope)
+	body := envelope.Payload
+	if direction == Inbound {
+		body = envelope.Data
+	}
+
+	entry := Entry{
+		Time:       time.Now(),
+		Direction:  direction,
+		Role:       actor.Role,
+		ActorId:    actor.Id,
+		ScenarioId: actor.ScenarioId,
+		TripId:     tripIdOf(envelope.Command, body),
+		Command:    envelope.Command,
+		Frame:      frame,
+	}
+	if !json.Valid(frame) {
+		// keep malformed frames readable rather than breaking the line
+		entry.Frame, _ = json.Marshal(string(frame))
+	}
+	if err := rec.write(entry); err != nil {
+		log.Printf("record %s frame of %s: %v", direction, actor.Id, err)
+	}
+	return true
+}
+
+// ActorTranscript returns everything recorded for the actor, oldest first.
+func ActorTranscript(actorId string) ([]Entry, error) {
+	rec := current()
+	if rec == nil {
+		return nil, ErrDisabled
+	}
+
+	var entries []Entry
+	for _, path := range rec.actorFiles(actorId) {
+		fileEntries, err := readEntries(path, nil)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, fileEntries...)
+	}
+	return entries, nil
+}
+
+// TripTranscript returns the frames of every actor that refer to the trip, ordered by time.
+func TripTranscript(tripId string) ([]Entry, error) {
+	rec := current()
+	if rec == nil {
+		return nil, ErrDisabled
+	}
+
+	paths, err := filepath.Glob(filepath.Join(rec.dir, "*"+fileExtension))
+	if err != nil {
+		return nil, err
+	}
+	var entries []Entry
+	for _, path := range paths {
+		fileEntries, err := readEntries(path, func(entry Entry) bool { return entry.TripId == tripId })
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, fileEntries...)
+	}
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].Time.Before(entries[j].Time) })
+	return entries, nil
+}
+
+func (rec *Recorder) write(entry Entry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	for {
+		f := rec.touch(entry.ActorId)
+		f.lock.Lock()
+		if f.evicted {
+			// closed between touch and the lock, the next touch adds the actor again
+			f.lock.Unlock()
+			continue
+		}
+		err := rec.append(f, line)
+		f.lock.Unlock()
+		return err
+	}
+}
+
+// touch returns the file of the actor as the one written last, closing the least recently written
+// files past maxOpenFiles
+func (rec *Recorder) touch(actorId string) *actorFile {
+	rec.lock.Lock()
+	f, ok := rec.files[actorId]
+	if ok {
+		rec.recent.MoveToFront(f.element)
+	} else {
+		f = &actorFile{actorId: actorId}
+		f.element = rec.recent.PushFront(f)
+		rec.files[actorId] = f
+	}
+	var evicted []*actorFile
+	for rec.recent.Len() > maxOpenFiles {
+		oldest := rec.recent.Remove(rec.recent.Back()).(*actorFile)
+		delete(rec.files, oldest.actorId)
+		evicted = append(evicted, oldest)
+	}
+	rec.lock.Unlock()
+
+	for _, oldest := range evicted {
+		oldest.lock.Lock()
+		oldest.evicted = true
+		if oldest.file != nil {
+			oldest.file.Close()
+		}
+		oldest.lock.Unlock()
+	}
+	return f
+}
+
+// append writes a line to the file of the actor, it runs under the lock of f
+func (rec *Recorder) append(f *actorFile, line []byte) error {
+	if f.file == nil {
+		if err := rec.open(f); err != nil {
+			return err
+		}
+	}
+	if f.size > 0 && f.size+int64(len(line)) > rec.maxBytes {
+		if err := rec.rotate(f); err != nil {
+			return err
+		}
+	}
+	n, err := f.file.Write(line)
+	f.size += int64(n)
+	return err
+}
+
+func (rec *Recorder) open(f *actorFile) error {
+	file, err := os.OpenFile(rec.path(f.actorId, 0), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	f.file, f.size = file, info.Size()
+	return nil
+}
+
+// rotate shifts the files of the actor up by one, dropping the oldest, and starts a new one
+func (rec *Recorder) rotate(f *actorFile) error {
+	f.file.Close()
+	f.file = nil
+
+	os.Remove(rec.path(f.actorId, rec.maxFiles))
+	for i := rec.maxFiles - 1; i >= 0; i-- {
+		if err := os.Rename(rec.path(f.actorId, i), rec.path(f.actorId, i+1)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return rec.open(f)
+}
+
+// path is the file of the actor, index 0 is the one being written, higher ones are older
+func (rec *Recorder) path(actorId string, index int) string {
+	name := filepath.Base(actorId)
+	if index > 0 {
+		name = fmt.Sprintf("%s.%d", name, index)
+	}
+	return filepath.Join(rec.dir, name+fileExtension)
+}
+
+// actorFiles lists the existing files of the actor, oldest first
+func (rec *Recorder) actorFiles(actorId string) []string {
+	var paths []string
+	for i := rec.maxFiles; i >= 0; i-- {
+		if _, err := os.Stat(rec.path(actorId, i)); err == nil {
+			paths = append(paths, rec.path(actorId, i))
+		}
+	}
+	return paths
+}
+
+// ReadEntries parses a transcript, such as one downloaded from the transcript API.
+func ReadEntries(r io.Reader) ([]Entry, error) {
+	return decodeEntries(r, nil)
+}
+
+// readEntries reads a recorded file while it may still be written to: a file rotated away in the
+// meantime reads as empty and a line still being written is left out
+func readEntries(path string, keep func(entry Entry) bool) ([]Entry, error) {
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	content = content[:bytes.LastIndexByte(content, '\n')+1]
+
+	entries, err := decodeEntries(bytes.NewReader(content), keep)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return entries, nil
+}
+
+func decodeEntries(r io.Reader, keep func(entry Entry) bool) ([]Entry, error) {
+	var entries []Entry
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		if keep == nil || keep(entry) {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, scanner.Err()
+}
+
+// tripIdOf finds the trip a frame refers to, the payload of outbound frames and the data of inbound ones
+func tripIdOf(command models.Command, body json.RawMessage) string {
+	var fields struct {
+		TripId         string `json:"trip_id"`
+		LocationTripId string `json:"tripId"`
+		Id             string `json:"id"`
+		TripOffer      *struct {
+			TripId string `json:"trip_id"`
+		} `json:"trip_offer"`
+		ActiveTrip *struct {
+			Id string `json:"id"`
+		} `json:"active_trip"`
+	}
+	if json.Unmarshal(body, &fields) != nil {
+		return ""
+	}
+	switch {
+	case fields.TripId != "":
+		return fields.TripId
+	case fields.LocationTripId != "":
+		return fields.LocationTripId
+	case fields.TripOffer != nil:
+		return fields.TripOffer.TripId
+	case fields.ActiveTrip != nil:
+		return fields.ActiveTrip.Id
+	case command == models.ConfirmTrip || command == models.ModifyTrip:
+		return fields.Id
+	}
+	return ""
+}
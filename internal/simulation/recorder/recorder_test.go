@@ -0,0 +1,75 @@
+package recorder
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func frame(tripId string) []byte {
+	return []byte(`{"command":"acceptTrip","message_id":"m","payload":{"trip_id":"` + tripId + `"}}`)
+}
+
+func TestOpenFilesAreBounded(t *testing.T) {
+	if err := Enable(t.TempDir(), 1<<20, 2); err != nil {
+		t.Fatal(err)
+	}
+	rec := current()
+
+	n := maxOpenFiles + 10
+	for i := 0; i < n; i++ {
+		Record(Actor{Role: "driver", Id: fmt.Sprintf("driver-%d", i)}, Outbound, frame("trip"))
+	}
+	rec.lock.Lock()
+	open := rec.recent.Len()
+	rec.lock.Unlock()
+	if open != maxOpenFiles {
+		t.Errorf("%d files open, want %d", open, maxOpenFiles)
+	}
+
+	// the first driver was closed to make room, its next frame goes to the same file
+	Record(Actor{Role: "driver", Id: "driver-0"}, Outbound, frame("trip"))
+	entries, err := ActorTranscript("driver-0")
+	if err != nil || len(entries) != 2 {
+		t.Errorf("driver-0 has %d entries: %v", len(entries), err)
+	}
+	entries, err = TripTranscript("trip")
+	if err != nil || len(entries) != n+1 {
+		t.Errorf("trip has %d entries, want %d: %v", len(entries), n+1, err)
+	}
+}
+
+func TestReadWhileRecording(t *testing.T) {
+	// small files rotate while the transcripts are read
+	if err := Enable(t.TempDir(), 512, 100); err != nil {
+		t.Fatal(err)
+	}
+
+	const drivers, frames = 4, 200
+	var wg sync.WaitGroup
+	for d := 0; d < drivers; d++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < frames; i++ {
+				Record(Actor{Role: "driver", Id: fmt.Sprintf("driver-%d", d)}, Outbound, frame("trip"))
+			}
+		}()
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 20; i++ {
+			if _, err := TripTranscript("trip"); err != nil {
+				t.Errorf("reading while recording: %v", err)
+			}
+		}
+	}()
+	wg.Wait()
+	<-done
+
+	entries, err := TripTranscript("trip")
+	if err != nil || len(entries) != drivers*frames {
+		t.Errorf("trip has %d entries, want %d: %v", len(entries), drivers*frames, err)
+	}
+}
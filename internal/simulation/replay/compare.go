@@ -0,0 +1,137 @@
+package replay
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"sim-server/internal/models"
+)
+
+// kinds of differences
+const (
+	Missing    = "missing"    // recorded but never received during the replay
+	Unexpected = "unexpected" // received during the replay but not recorded
+	Mismatch   = "mismatch"   // received as recorded but a field differs
+)
+
+// Difference is one way the replayed conversation deviated from the recording
+type Difference struct {
+	Role     string         `json:"role"`
+	ActorId  string         `json:"actor_id"` // as recorded
+	Kind     string         `json:"kind"`
+	Command  models.Command `json:"command,omitempty"`
+	Field    string         `json:"field,omitempty"`
+	Recorded interface{}    `json:"recorded,omitempty"`
+	Replayed interface{}    `json:"replayed,omitempty"`
+}
+
+// idFields hold ids the backend generates, they differ between runs and are mapped instead of compared
+var idFields = map[string]bool{
+	"id":         true,
+	"trip_id":    true,
+	"tripId":     true,
+	"driverId":   true,
+	"customerId": true,
+	"message_id": true,
+}
+
+// volatileFields legitimately change between runs and are not compared at all
+var volatileFields = map[string]bool{
+	"encodedPolyline": true,
+}
+
+// compare flags the fields of a replayed frame that differ from the recorded one. Numbers
+// such as distances and timestamps are only checked for presence, the backend computes them.
+func (s *session) compare(a *actor, recorded, replayed models.ServerMessage) {
+	if recorded.Status != replayed.Status {
+		s.mismatch(a, recorded.Command, "status", recorded.Status, replayed.Status)
+	}
+	var recordedData, replayedData interface{}
+	json.Unmarshal(recorded.Data, &recordedData)
+	json.Unmarshal(replayed.Data, &replayedData)
+	s.compareValue(a, recorded.Command, "data", recordedData, replayedData)
+}
+
+func (s *session) compareValue(a *actor, command models.Command, path string, recorded, replayed interface{}) {
+	field := path[strings.LastIndex(path, ".")+1:]
+	if volatileFields[field] {
+		return
+	}
+	switch r := recorded.(type) {
+	case map[string]interface{}:
+		p, ok := replayed.(map[string]interface{})
+		if !ok {
+			s.mismatch(a, command, path, recorded, replayed)
+			return
+		}
+		for key, value := range r {
+			if _, ok := p[key]; !ok {
+				s.mismatch(a, command, path+"."+key, value, nil)
+				continue
+			}
+			s.compareValue(a, command, path+"."+key, value, p[key])
+		}
+		for key, value := range p {
+			if _, ok := r[key]; !ok {
+				s.mismatch(a, command, path+"."+key, nil, value)
+			}
+		}
+	case []interface{}:
+		p, ok := replayed.([]interface{})
+		if !ok || len(p) != len(r) {
+			s.mismatch(a, command, path, recorded, replayed)
+			return
+		}
+		for i := range r {
+			s.compareValue(a, command, path+"["+strconv.Itoa(i)+"]", r[i], p[i])
+		}
+	case string:
+		p, ok := replayed.(string)
+		switch {
+		case !ok:
+			s.mismatch(a, command, path, recorded, replayed)
+		case idFields[field]:
+			s.compareId(a, command, path, r, p)
+		case r != p:
+			s.mismatch(a, command, path, recorded, replayed)
+		}
+	case float64:
+		if _, ok := replayed.(float64); !ok {
+			s.mismatch(a, command, path, recorded, replayed)
+		}
+	default:
+		if recorded != replayed {
+			s.mismatch(a, command, path, recorded, replayed)
+		}
+	}
+}
+
+// compareId learns the replayed counterpart of a recorded id the first time it is seen
+// and flags the frames that use a different one afterwards
+func (s *session) compareId(a *actor, command models.Command, path, recorded, replayed string) {
+	if (recorded == "") != (replayed == "") {
+		s.mismatch(a, command, path, recorded, replayed)
+		return
+	}
+	id, ok := s.ids[recorded]
+	if !ok {
+		s.ids[recorded] = replayed
+		return
+	}
+	if id != replayed {
+		s.mismatch(a, command, path, id, replayed)
+	}
+}
+
+func (s *session) mismatch(a *actor, command models.Command, field string, recorded, replayed interface{}) {
+	s.report.Differences = append(s.report.Differences, Difference{
+		Role:     a.role,
+		ActorId:  a.recordedId,
+		Kind:     Mismatch,
+		Command:  command,
+		Field:    field,
+		Recorded: recorded,
+		Replayed: replayed,
+	})
+}
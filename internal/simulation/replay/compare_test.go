@@ -0,0 +1,116 @@
+package replay
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"testing"
+
+	"sim-server/internal/models"
+)
+
+func TestCompareValue(t *testing.T) {
+	tests := []struct {
+		name     string
+		ids      map[string]string // learnt before the frame
+		recorded string
+		replayed string
+		fields   []string // of the mismatches
+	}{
+		{
+			name:     "same frame",
+			recorded: `{"status":"searching","fare":12.5,"scheduled":false}`,
+			replayed: `{"status":"searching","fare":12.5,"scheduled":false}`,
+		},
+		{
+			name:     "numbers are only checked for presence",
+			recorded: `{"fare":12.5,"eta":300}`,
+			replayed: `{"fare":14,"eta":280}`,
+		},
+		{
+			name:     "number turned into a string",
+			recorded: `{"fare":12.5}`,
+			replayed: `{"fare":"12.5"}`,
+			fields:   []string{"data.fare"},
+		},
+		{
+			name:     "string differs",
+			recorded: `{"status":"searching"}`,
+			replayed: `{"status":"no_driver"}`,
+			fields:   []string{"data.status"},
+		},
+		{
+			name:     "missing and extra keys",
+			recorded: `{"status":"searching","origin":{"lat":1}}`,
+			replayed: `{"origin":{"lat":1,"lng":2},"cancelled_by":"driver"}`,
+			fields:   []string{"data.status", "data.origin.lng", "data.cancelled_by"},
+		},
+		{
+			name:     "array length differs",
+			recorded: `{"stops":[1,2]}`,
+			replayed: `{"stops":[1]}`,
+			fields:   []string{"data.stops"},
+		},
+		{
+			name:     "array items are compared in order",
+			recorded: `{"stops":[{"name":"a"},{"name":"b"}]}`,
+			replayed: `{"stops":[{"name":"a"},{"name":"c"}]}`,
+			fields:   []string{"data.stops[1].name"},
+		},
+		{
+			name:     "volatile fields are skipped",
+			recorded: `{"route":{"encodedPolyline":"abc"}}`,
+			replayed: `{"route":{"encodedPolyline":"xyz"}}`,
+		},
+		{
+			name:     "new id is learnt",
+			recorded: `{"trip_id":"recorded-trip"}`,
+			replayed: `{"trip_id":"replayed-trip"}`,
+		},
+		{
+			name:     "learnt id is kept",
+			ids:      map[string]string{"recorded-trip": "replayed-trip"},
+			recorded: `{"trip_id":"recorded-trip","id":"recorded-trip"}`,
+			replayed: `{"trip_id":"replayed-trip","id":"other-trip"}`,
+			fields:   []string{"data.id"},
+		},
+		{
+			name:     "id went missing",
+			recorded: `{"trip_id":"recorded-trip"}`,
+			replayed: `{"trip_id":""}`,
+			fields:   []string{"data.trip_id"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &session{ids: make(map[string]string), report: &Report{}}
+			for recorded, replayed := range tt.ids {
+				s.ids[recorded] = replayed
+			}
+			a := &actor{role: "customer", recordedId: "customer-1"}
+			var recorded, replayed interface{}
+			if err := json.Unmarshal([]byte(tt.recorded), &recorded); err != nil {
+				t.Fatal(err)
+			}
+			if err := json.Unmarshal([]byte(tt.replayed), &replayed); err != nil {
+				t.Fatal(err)
+			}
+
+			s.compareValue(a, models.ConfirmTrip, "data", recorded, replayed)
+
+			fields := []string{}
+			for _, difference := range s.finish().Differences {
+				if difference.Kind != Mismatch || difference.ActorId != "customer-1" || difference.Command != models.ConfirmTrip {
+					t.Errorf("unexpected difference %+v", difference)
+				}
+				fields = append(fields, difference.Field)
+			}
+			// finish orders the differences of one frame by field
+			want := append([]string{}, tt.fields...)
+			sort.Strings(want)
+			if !reflect.DeepEqual(fields, want) {
+				t.Errorf("mismatched fields %v, want %v", fields, want)
+			}
+		})
+	}
+}
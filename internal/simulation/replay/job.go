@@ -0,0 +1,81 @@
+package replay
+
+import (
+	"sync"
+	"time"
+
+	"sim-server/internal/simulation/recorder"
+)
+
+// jobRetention is how long the outcome of a replay can be fetched once it finished
+const jobRetention = time.Hour
+
+// states of a job
+const (
+	Running = "running"
+	Done    = "done"
+	Failed  = "failed"
+)
+
+// Job is a replay running in the background, it goes by the scenario id its traffic is recorded under
+type Job struct {
+	Id         string     `json:"id"`
+	State      string     `json:"state"`
+	StartedAt  time.Time  `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	Report     *Report    `json:"report,omitempty"`
+	Error      string     `json:"error,omitempty"`
+}
+
+var (
+	jobs     = make(map[string]*Job)
+	jobsLock sync.Mutex
+)
+
+// Start checks the transcript and replays it in the background, see Run. A long transcript replays
+// with its recorded timing, the outcome is fetched with Status.
+func Start(entries []recorder.Entry, options Options) (Job, error) {
+	s, err := newSession(entries, options)
+	if err != nil {
+		return Job{}, err
+	}
+	job := &Job{Id: s.report.ScenarioId, State: Running, StartedAt: time.Now()}
+	jobsLock.Lock()
+	jobs[job.Id] = job
+	started := *job
+	jobsLock.Unlock()
+
+	go func() {
+		report, err := s.run()
+
+		jobsLock.Lock()
+		finishedAt := time.Now()
+		job.FinishedAt = &finishedAt
+		if err != nil {
+			job.State = Failed
+			job.Error = err.Error()
+		} else {
+			job.State = Done
+			job.Report = report
+		}
+		jobsLock.Unlock()
+
+		time.AfterFunc(jobRetention, func() {
+			jobsLock.Lock()
+			delete(jobs, job.Id)
+			jobsLock.Unlock()
+		})
+	}()
+	return started, nil
+}
+
+// Status returns the job of a replay until jobRetention after it finished
+func Status(id string) (Job, bool) {
+	jobsLock.Lock()
+	defer jobsLock.Unlock()
+	job, ok := jobs[id]
+	if !ok {
+		return Job{}, false
+	}
+	return *job, true
+}
@@ -0,0 +1,398 @@
+package replay
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"sim-server/config"
+	"sim-server/internal/models"
+	"sim-server/internal/services"
+	"sim-server/internal/simulation/recorder"
+	"sim-server/internal/simulation/socket"
+
+	"github.com/google/uuid"
+)
+
+const (
+	phoneSeriesBase = 1111100000
+	// how long an outbound frame waits for the responses that preceded it in the recording
+	responseTimeout = 5 * time.Second
+	pollPeriod      = 50 * time.Millisecond
+)
+
+var ErrEmptyTranscript = errors.New("transcript has no outbound frames to replay")
+
+// Options controls how a transcript is replayed
+type Options struct {
+	Target config.Target
+	// Speed scales the recorded timing, 2 replays twice as fast
+	Speed float64
+	// Replayed actors log in with phone numbers counted from these, like scenarios do
+	DriverSeriesStart   int
+	CustomerSeriesStart int
+}
+
+// Report sums up a replay, every frame that did not come back as recorded is a difference
+type Report struct {
+	ScenarioId  string       `json:"scenario_id"` // the replayed traffic is recorded under this id
+	Actors      int          `json:"actors"`
+	Sent        int          `json:"sent"`
+	Received    int          `json:"received"`
+	Matched     int          `json:"matched"`
+	Differences []Difference `json:"differences"`
+}
+
+// session is the state of one replay, the lock guards everything the read loops touch
+type session struct {
+	options  Options
+	entries  []recorder.Entry // by time
+	outbound []recorder.Entry
+	// recorded inbound frames of the actor before each outbound one
+	inboundBefore []int
+
+	lock   sync.Mutex
+	actors map[string]*actor // by recorded actor id
+	ids    map[string]string // recorded id to the one seen during the replay
+	report *Report
+}
+
+type actor struct {
+	role       string
+	recordedId string
+	id         string
+	session    *services.Session
+	conn       *socket.Client
+	expected   []models.ServerMessage // recorded inbound frames, in order
+	matched    []bool
+	received   int
+}
+
+// Run re-sends the outbound frames of the transcript against the target with their original
+// timing and compares what the backend answers with the recorded inbound frames.
+func Run(entries []recorder.Entry, options Options) (*Report, error) {
+	s, err := newSession(entries, options)
+	if err != nil {
+		return nil, err
+	}
+	return s.run()
+}
+
+// newSession reads the transcript, failing when it has nothing to replay
+func newSession(entries []recorder.Entry, options Options) (*session, error) {
+	if options.Speed <= 0 {
+		options.Speed = 1
+	}
+	entries = append([]recorder.Entry(nil), entries...)
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].Time.Before(entries[j].Time) })
+
+	s := &session{
+		options: options,
+		entries: entries,
+		actors:  make(map[string]*actor),
+		ids:     make(map[string]string),
+		report:  &Report{ScenarioId: "replay-" + uuid.NewString()},
+	}
+	for _, entry := range entries {
+		a, ok := s.actors[entry.ActorId]
+		if !ok {
+			a = &actor{role: entry.Role, recordedId: entry.ActorId}
+			s.actors[entry.ActorId] = a
+		}
+		switch entry.Direction {
+		case recorder.Outbound:
+			s.outbound = append(s.outbound, entry)
+			s.inboundBefore = append(s.inboundBefore, len(a.expected))
+		case recorder.Inbound:
+			var message models.ServerMessage
+			if err := json.Unmarshal(entry.Frame, &message); err != nil {
+				return nil, fmt.Errorf("recorded frame of %s at %s: %w", entry.ActorId, entry.Time, err)
+			}
+			a.expected = append(a.expected, message)
+			a.matched = append(a.matched, false)
+		}
+	}
+	if len(s.outbound) == 0 {
+		return nil, ErrEmptyTranscript
+	}
+	s.report.Actors = len(s.actors)
+	return s, nil
+}
+
+func (s *session) run() (*Report, error) {
+	if err := s.connect(s.entries); err != nil {
+		s.close()
+		return nil, err
+	}
+	defer s.close()
+
+	start := time.Now()
+	first := s.entries[0].Time
+	for i, entry := range s.outbound {
+		time.Sleep(time.Until(start.Add(s.scale(entry.Time.Sub(first)))))
+		a := s.actors[entry.ActorId]
+		s.awaitReceived(a, s.inboundBefore[i], time.Now().Add(responseTimeout))
+		s.send(a, entry.Frame)
+	}
+
+	last := s.outbound[len(s.outbound)-1]
+	trailing := s.scale(s.entries[len(s.entries)-1].Time.Sub(last.Time))
+	s.awaitMatched(time.Now().Add(trailing + responseTimeout))
+	return s.finish(), nil
+}
+
+// connect logs in a fresh user for every recorded actor, in order of appearance
+func (s *session) connect(entries []recorder.Entry) error {
+	phones := map[string]int{
+		"driver":   phoneSeriesBase + s.options.DriverSeriesStart,
+		"customer": phoneSeriesBase + s.options.CustomerSeriesStart,
+	}
+	connected := make(map[string]bool)
+	for _, entry := range entries {
+		if connected[entry.ActorId] {
+			continue
+		}
+		connected[entry.ActorId] = true
+		a := s.actors[entry.ActorId]
+		if _, ok := phones[a.role]; !ok {
+			return fmt.Errorf("actor %s has unknown role %q", a.recordedId, a.role)
+		}
+		phones[a.role]++
+		if err := s.login(a, strconv.Itoa(phones[a.role])); err != nil {
+			return fmt.Errorf("logging in %s %s: %w", a.role, a.recordedId, err)
+		}
+		s.lock.Lock()
+		s.ids[a.recordedId] = a.id
+		s.lock.Unlock()
+
+		conn, err := socket.DialTarget(
+			recorder.Actor{Role: a.role, Id: a.id, ScenarioId: s.report.ScenarioId},
+			s.options.Target, "/ws/"+a.role, a.session,
+			func(message []byte) { s.handleMessage(a, message) }, nil)
+		if err != nil {
+			return fmt.Errorf("connecting %s %s: %w", a.role, a.recordedId, err)
+		}
+		a.conn = conn
+	}
+	return nil
+}
+
+func (s *session) login(a *actor, phoneNumber string) error {
+	login := services.CustomerAuth(s.options.Target)
+	if a.role == "driver" {
+		login = services.DriverAuth(s.options.Target)
+	}
+	response, err := login(context.Background(), s.options.Target, phoneNumber)
+	if err != nil {
+		return err
+	}
+	if !response.Status {
+		return errors.New(response.Message)
+	}
+	data, ok := response.Data.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("unexpected login response data: %v", response.Data)
+	}
+	id, _ := data["id"].(string)
+	token, _ := data["access_token"].(string)
+	if id == "" || token == "" {
+		return errors.New("login response has no id or access token")
+	}
+	a.id = id
+	a.session = services.NewSession(s.options.Target, phoneNumber, token, login)
+
+	if a.role == "driver" {
+		// drivers only get offers while on shift
+		shift, err := a.session.Call(context.Background(), func(token string) (*models.CommonResponse, error) {
+			return services.CheckShiftStatus(context.Background(), s.options.Target, token)
+		})
+		if err != nil {
+			return err
+		}
+		status, ok := shift.Data.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("unexpected shift status data: %v", shift.Data)
+		}
+		if status["has_active_shift"] != true {
+			_, err := a.session.Call(context.Background(), func(token string) (*models.CommonResponse, error) {
+				return services.StartNewShift(context.Background(), s.options.Target, token)
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (s *session) close() {
+	for _, a := range s.actors {
+		if a.conn != nil {
+			a.conn.Close()
+		}
+	}
+}
+
+func (s *session) scale(d time.Duration) time.Duration {
+	return time.Duration(float64(d) / s.options.Speed)
+}
+
+// send rewrites the recorded ids in the frame to the ones of this replay before sending it. Every
+// command goes out with a message id of its own: the backend handles a message id only once, and
+// the recorded ones were already handled when the transcript is replayed against where it was recorded.
+func (s *session) send(a *actor, frame []byte) {
+	var message models.IncomingMessage
+	if err := json.Unmarshal(frame, &message); err != nil {
+		log.Printf("replay: skipping malformed frame of %s: %v", a.recordedId, err)
+		return
+	}
+
+	s.lock.Lock()
+	if message.MessageId != "" {
+		// the ack of the command refers to the new id
+		messageId := uuid.NewString()
+		s.ids[message.MessageId] = messageId
+		message.MessageId = messageId
+	}
+	var payload interface{}
+	if json.Unmarshal(message.Payload, &payload) == nil {
+		message.Payload, _ = json.Marshal(s.rewrite(payload))
+	}
+	s.lock.Unlock()
+
+	rewritten, err := json.Marshal(message)
+	if err != nil {
+		log.Printf("replay: %v", err)
+		return
+	}
+	if err := a.conn.Send(rewritten); err != nil {
+		log.Printf("replay: sending %s of %s: %v", message.Command, a.recordedId, err)
+		return
+	}
+	s.lock.Lock()
+	s.report.Sent++
+	s.lock.Unlock()
+}
+
+func (s *session) rewrite(value interface{}) interface{} {
+	switch v := value.(type) {
+	case string:
+		if id, ok := s.ids[v]; ok {
+			return id
+		}
+	case map[string]interface{}:
+		for key, field := range v {
+			v[key] = s.rewrite(field)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = s.rewrite(item)
+		}
+	}
+	return value
+}
+
+// handleMessage matches a frame from the backend with the first recorded one of the same command
+func (s *session) handleMessage(a *actor, frame []byte) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.report.Received++
+	a.received++
+	var message models.ServerMessage
+	if err := json.Unmarshal(frame, &message); err != nil {
+		s.report.Differences = append(s.report.Differences, Difference{
+			Role: a.role, ActorId: a.recordedId, Kind: Unexpected, Replayed: string(frame),
+		})
+		return
+	}
+	for i, recorded := range a.expected {
+		if a.matched[i] || recorded.Command != message.Command {
+			continue
+		}
+		a.matched[i] = true
+		s.report.Matched++
+		s.compare(a, recorded, message)
+		return
+	}
+	s.report.Differences = append(s.report.Differences, Difference{
+		Role: a.role, ActorId: a.recordedId, Kind: Unexpected, Command: message.Command, Replayed: message.Data,
+	})
+}
+
+// awaitReceived waits until the actor got n frames during the replay or the deadline passed
+func (s *session) awaitReceived(a *actor, n int, deadline time.Time) {
+	for time.Now().Before(deadline) {
+		s.lock.Lock()
+		received := a.received
+		s.lock.Unlock()
+		if received >= n {
+			return
+		}
+		time.Sleep(pollPeriod)
+	}
+}
+
+func (s *session) awaitMatched(deadline time.Time) {
+	for time.Now().Before(deadline) {
+		s.lock.Lock()
+		done := s.unmatched() == 0
+		s.lock.Unlock()
+		if done {
+			return
+		}
+		time.Sleep(pollPeriod)
+	}
+}
+
+func (s *session) unmatched() int {
+	n := 0
+	for _, a := range s.actors {
+		for _, matched := range a.matched {
+			if !matched {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+// finish reports every recorded frame that never came back as missing
+func (s *session) finish() *Report {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for _, a := range s.actors {
+		for i, recorded := range a.expected {
+			if a.matched[i] {
+				continue
+			}
+			s.report.Differences = append(s.report.Differences, Difference{
+				Role: a.role, ActorId: a.recordedId, Kind: Missing, Command: recorded.Command, Recorded: recorded.Data,
+			})
+		}
+	}
+	report := *s.report
+	report.Differences = append([]Difference{}, s.report.Differences...)
+	// the actors and the fields of a frame are compared in map order
+	sort.SliceStable(report.Differences, func(i, j int) bool {
+		a, b := report.Differences[i], report.Differences[j]
+		if a.ActorId != b.ActorId {
+			return a.ActorId < b.ActorId
+		}
+		if a.Command != b.Command {
+			return a.Command < b.Command
+		}
+		if a.Field != b.Field {
+			return a.Field < b.Field
+		}
+		return a.Kind < b.Kind
+	})
+	return &report
+}
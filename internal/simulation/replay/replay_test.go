@@ -0,0 +1,66 @@
+package replay
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"sim-server/config"
+	"sim-server/internal/mockbackend"
+	"sim-server/internal/models"
+	"sim-server/internal/simulation/recorder"
+)
+
+// estimateTranscript records a customer asking the mock for an estimate
+func estimateTranscript(t *testing.T) []recorder.Entry {
+	t.Helper()
+	payload, _ := json.Marshal(models.TripRequestPayload{
+		Origin:      models.LatLong{Latitude: 28.62, Longitude: 77.21},
+		Destination: models.LatLong{Latitude: 28.65, Longitude: 77.25},
+	})
+	request := models.IncomingMessage{Command: models.RequestEstimate, MessageId: "recorded-message", Payload: payload}
+	frame, _ := json.Marshal(request)
+
+	start := time.Now()
+	entries := []recorder.Entry{{Time: start, Direction: recorder.Outbound, Role: "customer", ActorId: "recorded-customer", Command: request.Command, Frame: frame}}
+	backend := mockbackend.NewServer()
+	customerId := backend.ConnectCustomer("1111100001", func(message []byte) {
+		entries = append(entries, recorder.Entry{
+			Time:      start.Add(time.Duration(len(entries)) * time.Millisecond),
+			Direction: recorder.Inbound,
+			Role:      "customer",
+			ActorId:   "recorded-customer",
+			Frame:     append(json.RawMessage(nil), message...),
+		})
+	})
+	backend.Receive(customerId, request)
+	if len(entries) != 3 {
+		t.Fatalf("recorded %d frames, want the request, its ack and the estimate", len(entries))
+	}
+	return entries
+}
+
+func TestReplayTwice(t *testing.T) {
+	entries := estimateTranscript(t)
+	backend := mockbackend.NewServer()
+	server := httptest.NewServer(backend.Handler())
+	defer server.Close()
+	options := Options{Target: config.Target{
+		Name:         "mock",
+		BaseURL:      server.URL,
+		WebsocketURL: "ws" + strings.TrimPrefix(server.URL, "http"),
+	}}
+
+	// the second replay logs in the same customer, its commands are still handled
+	for run := 1; run <= 2; run++ {
+		report, err := Run(entries, options)
+		if err != nil {
+			t.Fatalf("replay %d: %v", run, err)
+		}
+		if report.Sent != 1 || report.Matched != 2 || len(report.Differences) != 0 {
+			t.Errorf("replay %d sent %d, matched %d, differences %+v", run, report.Sent, report.Matched, report.Differences)
+		}
+	}
+}
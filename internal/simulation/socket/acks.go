@@ -0,0 +1,203 @@
+package socket
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+
+	"sim-server/internal/models"
+)
+
+const (
+	ackTimeout      = 5 * time.Second
+	ackCheckPeriod  = time.Second
+	maxSendAttempts = 3
+)
+
+// untracked commands are not expected to be acknowledged, location pings are superseded by the next one anyway
+var untracked = map[models.Command]bool{
+	models.DriverLocation: true,
+	models.Sync:           true,
+}
+
+type pendingCommand struct {
+	command     models.Command
+	frame       []byte
+	firstSentAt time.Time
+	lastSentAt  time.Time
+	attempts    int
+}
+
+// ackTracker follows the commands sent on one connection until they are acknowledged,
+// resending them when no ack arrives within ackTimeout until they were sent attempts times
+type ackTracker struct {
+	name     string
+	attempts int
+	lock     sync.Mutex
+	pending  map[string]*pendingCommand
+}
+
+func newAckTracker(name string, attempts int) *ackTracker {
+	return &ackTracker{
+		name:     name,
+		attempts: attempts,
+		pending:  make(map[string]*pendingCommand),
+	}
+}
+
+func (t *ackTracker) track(messageId string, command models.Command, frame []byte) {
+	now := time.Now()
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.pending[messageId] = &pendingCommand{
+		command:     command,
+		frame:       frame,
+		firstSentAt: now,
+		lastSentAt:  now,
+		attempts:    1,
+	}
+}
+
+// forget stops following a command that was never sent
+func (t *ackTracker) forget(messageId string) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	delete(t.pending, messageId)
+}
+
+// acknowledge settles the command the ack refers to. Acks without a message id settle
+// the oldest pending command of the same type.
+func (t *ackTracker) acknowledge(ack models.AckMessage) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	messageId := ack.MessageId
+	if _, ok := t.pending[messageId]; !ok {
+		messageId = ""
+		var oldest time.Time
+		for id, p := range t.pending {
+			if p.command == ack.Command && (messageId == "" || p.firstSentAt.Before(oldest)) {
+				messageId, oldest = id, p.firstSentAt
+			}
+		}
+		if messageId == "" {
+			return
+		}
+	}
+
+	p := t.pending[messageId]
+	delete(t.pending, messageId)
+	stats.record(p.command, time.Since(p.firstSentAt), p.attempts-1)
+}
+
+// expired returns the frames that are due for a resend and drops the ones that ran out of attempts
+func (t *ackTracker) expired() [][]byte {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	var frames [][]byte
+	for id, p := range t.pending {
+		if time.Since(p.lastSentAt) < ackTimeout {
+			continue
+		}
+		if p.attempts >= t.attempts {
+			log.Printf("%s: %s %s was not acknowledged after %d attempts", t.name, p.command, id, p.attempts)
+			delete(t.pending, id)
+			stats.recordTimeout(p.command, p.attempts-1)
+			continue
+		}
+		p.attempts++
+		p.lastSentAt = time.Now()
+		frames = append(frames, p.frame)
+	}
+	return frames
+}
+
+func newMessageId() string {
+	b := make([]byte, 12)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// ackOf returns the ack carried by an incoming frame, if it is one
+func ackOf(message []byte) (models.AckMessage, bool) {
+	var frame struct {
+		Command models.Command    `json:"command"`
+		Data    models.AckMessage `json:"data"`
+	}
+	if err := json.Unmarshal(message, &frame); err != nil || frame.Command != models.Ack {
+		return models.AckMessage{}, false
+	}
+	return frame.Data, true
+}
+
+// CommandStats summarizes the acknowledgements of one command across all actors.
+type CommandStats struct {
+	Sent         int     `json:"sent"`
+	Acknowledged int     `json:"acknowledged"`
+	TimedOut     int     `json:"timed_out"`
+	Retries      int     `json:"retries"`
+	AvgLatencyMs float64 `json:"avg_latency_ms"`
+	MinLatencyMs float64 `json:"min_latency_ms"`
+	MaxLatencyMs float64 `json:"max_latency_ms"`
+}
+
+type ackStats struct {
+	lock     sync.Mutex
+	commands map[models.Command]*CommandStats
+}
+
+var stats = &ackStats{commands: make(map[models.Command]*CommandStats)}
+
+func (s *ackStats) get(command models.Command) *CommandStats {
+	c, ok := s.commands[command]
+	if !ok {
+		c = &CommandStats{}
+		s.commands[command] = c
+	}
+	return c
+}
+
+func (s *ackStats) recordSent(command models.Command) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.get(command).Sent++
+}
+
+func (s *ackStats) record(command models.Command, latency time.Duration, retries int) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	c := s.get(command)
+	ms := float64(latency) / float64(time.Millisecond)
+	if c.Acknowledged == 0 || ms < c.MinLatencyMs {
+		c.MinLatencyMs = ms
+	}
+	if ms > c.MaxLatencyMs {
+		c.MaxLatencyMs = ms
+	}
+	c.AvgLatencyMs = (c.AvgLatencyMs*float64(c.Acknowledged) + ms) / float64(c.Acknowledged+1)
+	c.Acknowledged++
+	c.Retries += retries
+}
+
+func (s *ackStats) recordTimeout(command models.Command, retries int) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	c := s.get(command)
+	c.TimedOut++
+	c.Retries += retries
+}
+
+// AckStats returns a copy of the acknowledgement statistics per command.
+func AckStats() map[models.Command]CommandStats {
+	stats.lock.Lock()
+	defer stats.lock.Unlock()
+	result := make(map[models.Command]CommandStats, len(stats.commands))
+	for command, c := range stats.commands {
+		result[command] = *c
+	}
+	return result
+}
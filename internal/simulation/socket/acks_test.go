@@ -0,0 +1,108 @@
+package socket
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"sim-server/internal/models"
+)
+
+// overdue makes every pending command look like its last attempt went unacknowledged for ackTimeout
+func overdue(tracker *ackTracker) {
+	tracker.lock.Lock()
+	defer tracker.lock.Unlock()
+	for _, p := range tracker.pending {
+		p.lastSentAt = p.lastSentAt.Add(-ackTimeout)
+	}
+}
+
+func frameIds(frames [][]byte) []string {
+	ids := make([]string, len(frames))
+	for i, frame := range frames {
+		ids[i] = string(frame)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func TestAckTracker(t *testing.T) {
+	tests := []struct {
+		name string
+		acks []models.AckMessage
+		// the frames due for a resend after each round without an ack
+		resent [][]string
+	}{
+		{
+			name:   "never acknowledged",
+			resent: [][]string{{"accept", "complete"}, {"accept", "complete"}, {}},
+		},
+		{
+			name:   "acknowledged by message id",
+			acks:   []models.AckMessage{{MessageId: "accept", Command: models.AcceptTrip}},
+			resent: [][]string{{"complete"}, {"complete"}, {}},
+		},
+		{
+			name:   "ack without a message id settles the command of its type",
+			acks:   []models.AckMessage{{Command: models.CompleteTrip}},
+			resent: [][]string{{"accept"}, {"accept"}, {}},
+		},
+		{
+			name:   "ack of an unknown command",
+			acks:   []models.AckMessage{{MessageId: "other", Command: models.StartTrip}},
+			resent: [][]string{{"accept", "complete"}, {"accept", "complete"}, {}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracker := newAckTracker("test", maxSendAttempts)
+			tracker.track("accept", models.AcceptTrip, []byte("accept"))
+			tracker.track("complete", models.CompleteTrip, []byte("complete"))
+			for _, ack := range tt.acks {
+				tracker.acknowledge(ack)
+			}
+
+			if frames := tracker.expired(); len(frames) != 0 {
+				t.Fatalf("resending %v before ackTimeout", frameIds(frames))
+			}
+			for round, want := range tt.resent {
+				overdue(tracker)
+				if got := frameIds(tracker.expired()); !reflect.DeepEqual(got, want) {
+					t.Errorf("round %d resent %v, want %v", round+1, got, want)
+				}
+			}
+			// maxSendAttempts went by, the tracker gave up on everything
+			if n := len(tracker.pending); n != 0 {
+				t.Errorf("%d commands still pending", n)
+			}
+		})
+	}
+}
+
+func TestAckOldestOfType(t *testing.T) {
+	tracker := newAckTracker("test", maxSendAttempts)
+	tracker.track("first", models.RateDriver, []byte("first"))
+	tracker.pending["first"].firstSentAt = time.Now().Add(-time.Second)
+	tracker.track("second", models.RateDriver, []byte("second"))
+
+	tracker.acknowledge(models.AckMessage{Command: models.RateDriver})
+	if _, ok := tracker.pending["first"]; ok {
+		t.Error("ack without a message id did not settle the oldest command")
+	}
+	if _, ok := tracker.pending["second"]; !ok {
+		t.Error("ack without a message id settled the newer command")
+	}
+}
+
+func TestAckWithoutResends(t *testing.T) {
+	tracker := newAckTracker("test", 1)
+	tracker.track("accept", models.AcceptTrip, []byte("accept"))
+	overdue(tracker)
+	if frames := tracker.expired(); len(frames) != 0 {
+		t.Errorf("resent %v to a target that does not deduplicate message ids", frameIds(frames))
+	}
+	if n := len(tracker.pending); n != 0 {
+		t.Errorf("%d commands still pending", n)
+	}
+}
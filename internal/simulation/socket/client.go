@@ -0,0 +1,307 @@
+package socket
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"sim-server/config"
+	"sim-server/internal/models"
+	"sim-server/internal/simulation/recorder"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	initialBackoff = 500 * time.Millisecond
+	maxBackoff     = 30 * time.Second
+)
+
+var ErrNotConnected = errors.New("websocket is not connected")
+
+// authCloseCodes are the close codes the backend uses when it drops a connection over its token
+var authCloseCodes = map[int]bool{
+	websocket.ClosePolicyViolation: true,
+	4001:                           true,
+	4003:                           true,
+	4401:                           true,
+	4403:                           true,
+}
+
+// Credentials supply the bearer token of a connection and renew it once the backend rejects it
+type Credentials interface {
+	Token() string
+	Refresh(ctx context.Context, rejected string) (string, error)
+}
+
+// Client is a websocket connection to the backend that dials again with a jittered
+// exponential backoff whenever reading from it fails, until it is closed.
+// Commands sent through it are resent until the backend acknowledges them.
+type Client struct {
+	name        string
+	actor       recorder.Actor
+	address     string
+	header      http.Header
+	credentials Credentials
+	dialer      websocket.Dialer
+	onMessage   func(message []byte)
+	onReconnect func()
+	acks        *ackTracker
+
+	lock   sync.Mutex // guards conn and closed, and serializes writes
+	conn   *websocket.Conn
+	closed bool
+}
+
+// DialTarget connects actor to path on the websocket URL of target, authenticated with credentials.
+func DialTarget(actor recorder.Actor, target config.Target, path string, credentials Credentials, onMessage func(message []byte), onReconnect func()) (*Client, error) {
+	tlsConfig, err := target.TLSConfig()
+	if err != nil {
+		return nil, err
+	}
+	header := http.Header{}
+	for key, value := range target.Headers {
+		header.Set(key, value)
+	}
+	address := strings.TrimSuffix(target.WebsocketURL, "/") + path
+	return Dial(actor, address, header, tlsConfig, credentials, target.DedupMessageIds, onMessage, onReconnect)
+}
+
+// Dial connects to address and starts delivering every received frame to onMessage.
+// onReconnect is called after the connection was re-established following a failure.
+// The frames in both directions are written to the transcript of actor when recording is on.
+// Every dial is authenticated with the current token of credentials, a rejected token is
+// refreshed before dialing again. resend is only safe when the backend deduplicates message ids,
+// see SendCommand.
+func Dial(actor recorder.Actor, address string, header http.Header, tlsConfig *tls.Config, credentials Credentials, resend bool, onMessage func(message []byte), onReconnect func()) (*Client, error) {
+	attempts := 1
+	if resend {
+		attempts = maxSendAttempts
+	}
+	c := &Client{
+		name:        actor.Role,
+		actor:       actor,
+		address:     address,
+		header:      header,
+		credentials: credentials,
+		dialer:      *websocket.DefaultDialer,
+		onMessage:   onMessage,
+		onReconnect: onReconnect,
+		acks:        newAckTracker(actor.Role, attempts),
+	}
+	c.dialer.TLSClientConfig = tlsConfig
+	conn, err := c.dial()
+	if errors.Is(err, errUnauthorized) {
+		if err = c.refreshToken(); err == nil {
+			conn, err = c.dial()
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	c.conn = conn
+
+	go c.readLoop(conn)
+	go c.retryLoop()
+	return c, nil
+}
+
+// SendCommand sends a command with a fresh message id. When the client resends, commands that
+// expect an ack are accepted for delivery even while disconnected, they are resent until
+// acknowledged or until maxSendAttempts is reached. A resend carries the message id of the first
+// attempt, so the client only resends when the target declares dedup_message_ids: acceptTrip,
+// confirmTrip and completeTrip are not idempotent. Otherwise a command is sent once and a failed
+// send is returned.
+func (c *Client) SendCommand(command models.Command, payload interface{}) error {
+	jsonPayload, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	messageId := newMessageId()
+	frame, err := json.Marshal(models.IncomingMessage{
+		Command:   command,
+		MessageId: messageId,
+		Payload:   jsonPayload,
+	})
+	if err != nil {
+		return err
+	}
+	if untracked[command] {
+		return c.Send(frame)
+	}
+
+	stats.recordSent(command)
+	c.acks.track(messageId, command, frame)
+	if err := c.Send(frame); err != nil {
+		if c.acks.attempts == 1 {
+			c.acks.forget(messageId)
+			return err
+		}
+		log.Printf("%s: %s %s queued for resend: %v", c.name, command, messageId, err)
+	}
+	return nil
+}
+
+// Send writes a text frame, it fails while the connection is being re-established.
+func (c *Client) Send(message []byte) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.conn == nil {
+		return ErrNotConnected
+	}
+	if err := c.conn.WriteMessage(websocket.TextMessage, message); err != nil {
+		return err
+	}
+	recorder.Record(c.actor, recorder.Outbound, message)
+	return nil
+}
+
+// Connected reports whether the connection is currently up.
+func (c *Client) Connected() bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.conn != nil
+}
+
+// Close closes the connection for good, no reconnection is attempted afterwards.
+func (c *Client) Close() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.closed = true
+	if c.conn != nil {
+		c.conn.Close()
+		c.conn = nil
+	}
+}
+
+func (c *Client) readLoop(conn *websocket.Conn) {
+	for {
+		_, message, err := conn.ReadMessage()
+		if err != nil {
+			log.Printf("%s read: %v", c.name, err)
+			conn.Close()
+			var closeErr *websocket.CloseError
+			if errors.As(err, &closeErr) && authCloseCodes[closeErr.Code] {
+				if err := c.refreshToken(); err != nil {
+					log.Printf("%s: %v", c.name, err)
+				}
+			}
+			if conn = c.reconnect(); conn == nil {
+				return
+			}
+			if c.onReconnect != nil {
+				c.onReconnect()
+			}
+			continue
+		}
+		if !recorder.Record(c.actor, recorder.Inbound, message) {
+			log.Printf("%s %s recv: %s", c.name, c.actor.Id, message)
+		}
+		if ack, ok := ackOf(message); ok {
+			c.acks.acknowledge(ack)
+		}
+		c.onMessage(message)
+	}
+}
+
+func (c *Client) retryLoop() {
+	ticker := time.NewTicker(ackCheckPeriod)
+	defer ticker.Stop()
+	for range ticker.C {
+		if c.Closed() {
+			return
+		}
+		if !c.Connected() {
+			continue
+		}
+		for _, frame := range c.acks.expired() {
+			if err := c.Send(frame); err != nil {
+				log.Printf("%s resend: %v", c.name, err)
+			}
+		}
+	}
+}
+
+// reconnect dials until it succeeds or the client is closed, in which case it returns nil.
+func (c *Client) reconnect() *websocket.Conn {
+	c.lock.Lock()
+	c.conn = nil
+	c.lock.Unlock()
+
+	for attempt := 0; ; attempt++ {
+		time.Sleep(backoff(attempt))
+		if c.Closed() {
+			return nil
+		}
+		conn, err := c.dial()
+		if err != nil {
+			log.Printf("%s reconnect attempt %d: %v", c.name, attempt+1, err)
+			if errors.Is(err, errUnauthorized) {
+				if err := c.refreshToken(); err != nil {
+					log.Printf("%s: %v", c.name, err)
+				}
+			}
+			continue
+		}
+
+		c.lock.Lock()
+		if c.closed {
+			c.lock.Unlock()
+			conn.Close()
+			return nil
+		}
+		c.conn = conn
+		c.lock.Unlock()
+		log.Printf("%s reconnected after %d attempts", c.name, attempt+1)
+		return conn
+	}
+}
+
+var errUnauthorized = errors.New("websocket handshake rejected the token")
+
+// dial opens a connection with the current token, errUnauthorized means the token was rejected
+func (c *Client) dial() (*websocket.Conn, error) {
+	header := c.header.Clone()
+	if c.credentials != nil {
+		header.Set("Authorization", "Bearer "+c.credentials.Token())
+	}
+	conn, resp, err := c.dialer.Dial(c.address, header)
+	if err != nil && resp != nil && resp.StatusCode == http.StatusUnauthorized {
+		return nil, fmt.Errorf("%w: %v", errUnauthorized, err)
+	}
+	return conn, err
+}
+
+func (c *Client) refreshToken() error {
+	if c.credentials == nil {
+		return errUnauthorized
+	}
+	if _, err := c.credentials.Refresh(context.Background(), c.credentials.Token()); err != nil {
+		return fmt.Errorf("refreshing token: %w", err)
+	}
+	return nil
+}
+
+// Closed reports whether the client was closed for good.
+func (c *Client) Closed() bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.closed
+}
+
+// backoff doubles the delay on every attempt up to maxBackoff, with half of it randomized
+func backoff(attempt int) time.Duration {
+	delay := maxBackoff
+	if attempt < 16 {
+		delay = min(initialBackoff<<attempt, maxBackoff)
+	}
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)))
+}
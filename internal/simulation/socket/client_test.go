@@ -0,0 +1,30 @@
+package socket
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		attempt int
+		delay   time.Duration // the backoff is within its upper half
+	}{
+		{attempt: 0, delay: initialBackoff},
+		{attempt: 1, delay: 2 * initialBackoff},
+		{attempt: 5, delay: 32 * initialBackoff},
+		{attempt: 6, delay: maxBackoff},
+		{attempt: 15, delay: maxBackoff},
+		// shifting further would overflow
+		{attempt: 64, delay: maxBackoff},
+		{attempt: 1000, delay: maxBackoff},
+	}
+	for _, tt := range tests {
+		for i := 0; i < 100; i++ {
+			if d := backoff(tt.attempt); d < tt.delay/2 || d >= tt.delay {
+				t.Errorf("attempt %d backed off %v, want within [%v, %v)", tt.attempt, d, tt.delay/2, tt.delay)
+				break
+			}
+		}
+	}
+}